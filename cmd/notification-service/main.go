@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	// Quiet hours are read in IANA timezones; the runtime image has no zoneinfo
+	_ "time/tzdata"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/kodra-pay/notification-service/internal/config"
@@ -12,13 +19,39 @@ import (
 func main() {
 	cfg := config.Load("notification-service", "7014")
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	app := fiber.New()
 	app.Use(middleware.RequestID())
 
-	routes.Register(app, cfg.ServiceName)
+	bg, err := routes.Register(app, cfg.ServiceName)
+	if err != nil {
+		log.Fatalf("%s failed to start: %v", cfg.ServiceName, err)
+	}
+
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		bg.Dispatcher.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		bg.Scheduler.Run(ctx)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		if err := app.Shutdown(); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
 
 	log.Printf("%s listening on :%s", cfg.ServiceName, cfg.Port)
 	if err := app.Listen(":" + cfg.Port); err != nil {
 		log.Fatal(err)
 	}
+
+	workers.Wait()
 }
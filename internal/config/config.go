@@ -2,13 +2,248 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
 	ServiceName string
 	Port        string
 	PostgresDSN string
+
+	// EmailProvider selects the email sender: "smtp" or "log" (default).
+	EmailProvider string
+	SMTP          SMTPConfig
+
+	// SMSProvider selects the SMS sender: "twilio", "termii" or "log" (default).
+	SMSProvider string
+	SMSTimeout  time.Duration
+	Twilio      TwilioConfig
+	Termii      TermiiConfig
+
+	// VoiceProvider selects the voice-call sender: "http" (a TTS call API) or "log" (default).
+	VoiceProvider string
+	VoiceTimeout  time.Duration
+	Voice         VoiceConfig
+
+	// PushProvider selects the push sender: "native" (FCM and APNs) or "log" (default).
+	PushProvider string
+	PushTimeout  time.Duration
+	FCM          FCMConfig
+	APNs         APNsConfig
+
+	Dispatcher DispatcherConfig
+	Retry      RetryConfig
+	Scheduler  SchedulerConfig
+
+	OTP OTPConfig
+
+	Unsubscribe UnsubscribeConfig
+
+	// TemplateTestAllowlist restricts template test-sends to internal recipients.
+	// Entries are exact addresses or phone numbers, or "@domain" to allow a whole
+	// email domain. Test-sends are refused when it is empty.
+	TemplateTestAllowlist []string
+}
+
+// OTPConfig holds the settings for issuing and verifying OTPs
+type OTPConfig struct {
+	// HashKeys maps a key ID to its secret pepper. Retired keys stay listed until
+	// the OTPs hashed with them have expired.
+	HashKeys map[string]string
+	// ActiveKeyID selects the key used for newly issued OTPs.
+	ActiveKeyID string
+
+	// Policies maps each OTP purpose to its code format and limits.
+	Policies  map[string]OTPPolicyConfig
+	Limits    OTPLimitsConfig
+	TOTP      TOTPConfig
+	MagicLink MagicLinkConfig
+	Proof     OTPProofConfig
+}
+
+// OTPProofConfig controls the signed tokens returned after a successful
+// verification, which other services validate offline against the JWKS
+type OTPProofConfig struct {
+	// SigningKeys maps a key ID to a base64-encoded 32-byte Ed25519 seed. Retired
+	// keys stay listed, and published, until the tokens they signed have expired.
+	SigningKeys map[string]string
+	// ActiveKeyID selects the key used to sign new tokens.
+	ActiveKeyID string
+	Issuer      string
+	TTL         time.Duration
+}
+
+// MagicLinkConfig controls the signed single-use links that can accompany emailed OTP codes
+type MagicLinkConfig struct {
+	// BaseURL is the public URL of the magic link endpoint, e.g.
+	// https://notifications.kodrapay.com/otp/magic-link. Magic links are refused when it is empty.
+	BaseURL string
+	// RedirectURL receives the user once a link is used, with status, purpose and
+	// reference_id query parameters. Without it the endpoint responds with JSON.
+	RedirectURL string
+}
+
+// UnsubscribeConfig controls the signed one-click unsubscribe links carried by
+// emails in categories recipients may opt out of
+type UnsubscribeConfig struct {
+	// BaseURL is the public URL of the unsubscribe endpoint, e.g.
+	// https://notifications.kodrapay.com/unsubscribe. Emails carry no unsubscribe
+	// link when it is empty.
+	BaseURL string
+	// SigningKeys maps a key ID to an HMAC secret. Retired keys stay listed while
+	// emails carrying links signed with them may still be clicked.
+	SigningKeys map[string]string
+	// ActiveKeyID selects the key used to sign new links.
+	ActiveKeyID string
+}
+
+// OTPPolicyConfig is the code format and limits for one OTP purpose. Requests
+// may ask for a shorter expiry or fewer attempts, never more.
+type OTPPolicyConfig struct {
+	Length int
+	// Alphabet is "numeric" or "alphanumeric" (upper-case letters and digits, without ambiguous characters).
+	Alphabet        string
+	Expiry          time.Duration
+	MaxAttempts     int
+	DeliveryMethods []string
+}
+
+// TOTPConfig controls authenticator app (RFC 6238) enrollment
+type TOTPConfig struct {
+	// Issuer names the account in the user's authenticator app.
+	Issuer string
+	// EncryptionKeys maps a key ID to a base64-encoded AES-256 key used to encrypt
+	// TOTP secrets at rest. Retired keys stay listed while enrollments use them.
+	EncryptionKeys map[string]string
+	// ActiveKeyID selects the key used for new enrollments.
+	ActiveKeyID string
+	// Skew is how many 30-second steps either side of now are accepted, to absorb clock drift.
+	Skew int
+	// RecoveryCodes is how many single-use recovery codes are issued per enrollment.
+	RecoveryCodes int
+}
+
+// OTPLimitsConfig bounds OTP issuance and failed verifications. A zero limit disables that check.
+type OTPLimitsConfig struct {
+	// Window is the fixed window the per-recipient, per-merchant and per-purpose limits count over.
+	Window          time.Duration
+	MaxPerRecipient int
+	MaxPerMerchant  int
+	MaxPerPurpose   int
+	// ResendCooldown is the minimum time between OTPs for the same reference.
+	ResendCooldown time.Duration
+	// LockoutThreshold failed verifications within LockoutWindow lock the recipient out for LockoutDuration.
+	LockoutThreshold int
+	LockoutWindow    time.Duration
+	LockoutDuration  time.Duration
+}
+
+// RetryConfig holds the delivery retry policy for each notification type
+type RetryConfig struct {
+	Email RetryPolicyConfig
+	SMS   RetryPolicyConfig
+	Voice RetryPolicyConfig
+	Push  RetryPolicyConfig
+	// Jitter is the fraction (0-1) of each backoff delay that is randomised.
+	Jitter float64
+}
+
+// RetryPolicyConfig bounds the attempts and exponential backoff for one notification type
+type RetryPolicyConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DispatcherConfig controls the background worker that delivers queued notifications
+type DispatcherConfig struct {
+	Enabled         bool
+	BatchSize       int
+	Concurrency     int
+	PollInterval    time.Duration
+	ClaimTimeout    time.Duration
+	DeliveryTimeout time.Duration
+}
+
+// SchedulerConfig controls the background housekeeping jobs. A zero interval disables that job.
+type SchedulerConfig struct {
+	Enabled bool
+	// Tick is how often the scheduler looks for due jobs.
+	Tick       time.Duration
+	JobTimeout time.Duration
+
+	OTPCleanupInterval time.Duration
+	// OTPRetention is how long expired OTPs are kept before they are deleted.
+	OTPRetention time.Duration
+
+	OTPLimitsCleanupInterval time.Duration
+
+	TOTPPendingCleanupInterval time.Duration
+	// TOTPPendingRetention is how long an unconfirmed enrollment is kept.
+	TOTPPendingRetention time.Duration
+}
+
+// SMTPConfig holds the settings for the SMTP email provider
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// AuthMechanism is "plain", "login" or "none". Defaults to "plain" when a username is set.
+	AuthMechanism string
+	// TLSMode is "starttls", "implicit" or "none".
+	TLSMode string
+	From    string
+	ReplyTo string
+	Timeout time.Duration
+}
+
+// TwilioConfig holds the settings for the Twilio-style SMS provider
+type TwilioConfig struct {
+	BaseURL             string
+	AccountSID          string
+	AuthToken           string
+	From                string
+	MessagingServiceSID string
+}
+
+// TermiiConfig holds the settings for the Termii-style SMS provider
+type TermiiConfig struct {
+	BaseURL  string
+	APIKey   string
+	SenderID string
+	Channel  string
+}
+
+// VoiceConfig holds the settings for the HTTP text-to-speech call provider
+type VoiceConfig struct {
+	BaseURL string
+	APIKey  string
+	From    string
+	// StatusCallbackURL is where the provider reports call outcomes; it must reach
+	// this service's /webhooks/voice/status endpoint for the SMS fallback to work.
+	StatusCallbackURL string
+	// CallbackSecret signs the provider's status callbacks (HMAC-SHA256 of the body).
+	CallbackSecret string
+}
+
+// FCMConfig holds the settings for Firebase Cloud Messaging (HTTP v1), used for fcm and web tokens
+type FCMConfig struct {
+	BaseURL         string
+	TokenURL        string
+	ProjectID       string
+	CredentialsFile string
+}
+
+// APNsConfig holds the token-based authentication settings for Apple Push Notification service
+type APNsConfig struct {
+	BaseURL        string
+	KeyID          string
+	TeamID         string
+	BundleID       string
+	PrivateKeyFile string
 }
 
 func Load(serviceName, defaultPort string) Config {
@@ -21,10 +256,159 @@ func Load(serviceName, defaultPort string) Config {
 		}
 	}
 
+	smtpUsername := getEnv("SMTP_USERNAME", "")
+	smtpAuth := "none"
+	if smtpUsername != "" {
+		smtpAuth = "plain"
+	}
+
 	return Config{
-		ServiceName: serviceName,
-		Port:        getEnv("PORT", defaultPort),
-		PostgresDSN: dsn,
+		ServiceName:   serviceName,
+		Port:          getEnv("PORT", defaultPort),
+		PostgresDSN:   dsn,
+		EmailProvider: strings.ToLower(getEnv("EMAIL_PROVIDER", "log")),
+		SMTP: SMTPConfig{
+			Host:          getEnv("SMTP_HOST", "localhost"),
+			Port:          getEnv("SMTP_PORT", "587"),
+			Username:      smtpUsername,
+			Password:      getEnv("SMTP_PASSWORD", ""),
+			AuthMechanism: strings.ToLower(getEnv("SMTP_AUTH", smtpAuth)),
+			TLSMode:       strings.ToLower(getEnv("SMTP_TLS_MODE", "starttls")),
+			From:          getEnv("SMTP_FROM", "KodraPay <no-reply@kodrapay.com>"),
+			ReplyTo:       getEnv("SMTP_REPLY_TO", ""),
+			Timeout:       getEnvDuration("SMTP_TIMEOUT", 15*time.Second),
+		},
+		SMSProvider: strings.ToLower(getEnv("SMS_PROVIDER", "log")),
+		SMSTimeout:  getEnvDuration("SMS_TIMEOUT", 10*time.Second),
+		Twilio: TwilioConfig{
+			BaseURL:             getEnv("TWILIO_BASE_URL", "https://api.twilio.com"),
+			AccountSID:          getEnv("TWILIO_ACCOUNT_SID", ""),
+			AuthToken:           getEnv("TWILIO_AUTH_TOKEN", ""),
+			From:                getEnv("TWILIO_FROM", ""),
+			MessagingServiceSID: getEnv("TWILIO_MESSAGING_SERVICE_SID", ""),
+		},
+		Termii: TermiiConfig{
+			BaseURL:  getEnv("TERMII_BASE_URL", "https://api.ng.termii.com"),
+			APIKey:   getEnv("TERMII_API_KEY", ""),
+			SenderID: getEnv("TERMII_SENDER_ID", "KodraPay"),
+			Channel:  getEnv("TERMII_CHANNEL", "generic"),
+		},
+		VoiceProvider: strings.ToLower(getEnv("VOICE_PROVIDER", "log")),
+		VoiceTimeout:  getEnvDuration("VOICE_TIMEOUT", 10*time.Second),
+		Voice: VoiceConfig{
+			BaseURL:           getEnv("VOICE_BASE_URL", ""),
+			APIKey:            getEnv("VOICE_API_KEY", ""),
+			From:              getEnv("VOICE_FROM", ""),
+			StatusCallbackURL: getEnv("VOICE_STATUS_CALLBACK_URL", ""),
+			CallbackSecret:    getEnv("VOICE_CALLBACK_SECRET", ""),
+		},
+		PushProvider: strings.ToLower(getEnv("PUSH_PROVIDER", "log")),
+		PushTimeout:  getEnvDuration("PUSH_TIMEOUT", 10*time.Second),
+		FCM: FCMConfig{
+			BaseURL:         getEnv("FCM_BASE_URL", "https://fcm.googleapis.com"),
+			TokenURL:        getEnv("FCM_TOKEN_URL", ""),
+			ProjectID:       getEnv("FCM_PROJECT_ID", ""),
+			CredentialsFile: getEnv("FCM_CREDENTIALS_FILE", ""),
+		},
+		APNs: APNsConfig{
+			BaseURL:        getEnv("APNS_BASE_URL", "https://api.push.apple.com"),
+			KeyID:          getEnv("APNS_KEY_ID", ""),
+			TeamID:         getEnv("APNS_TEAM_ID", ""),
+			BundleID:       getEnv("APNS_BUNDLE_ID", ""),
+			PrivateKeyFile: getEnv("APNS_PRIVATE_KEY_FILE", ""),
+		},
+		Dispatcher: DispatcherConfig{
+			Enabled:         getEnvBool("DISPATCHER_ENABLED", true),
+			BatchSize:       getEnvInt("DISPATCHER_BATCH_SIZE", 50),
+			Concurrency:     getEnvInt("DISPATCHER_CONCURRENCY", 8),
+			PollInterval:    getEnvDuration("DISPATCHER_POLL_INTERVAL", 2*time.Second),
+			ClaimTimeout:    getEnvDuration("DISPATCHER_CLAIM_TIMEOUT", 5*time.Minute),
+			DeliveryTimeout: getEnvDuration("DISPATCHER_DELIVERY_TIMEOUT", 30*time.Second),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:                    getEnvBool("SCHEDULER_ENABLED", true),
+			Tick:                       getEnvDuration("SCHEDULER_TICK", 30*time.Second),
+			JobTimeout:                 getEnvDuration("SCHEDULER_JOB_TIMEOUT", 5*time.Minute),
+			OTPCleanupInterval:         getEnvDuration("SCHEDULER_OTP_CLEANUP_INTERVAL", time.Hour),
+			OTPRetention:               getEnvDuration("OTP_RETENTION", 24*time.Hour),
+			OTPLimitsCleanupInterval:   getEnvDuration("SCHEDULER_OTP_LIMITS_CLEANUP_INTERVAL", 15*time.Minute),
+			TOTPPendingCleanupInterval: getEnvDuration("SCHEDULER_TOTP_PENDING_CLEANUP_INTERVAL", time.Hour),
+			TOTPPendingRetention:       getEnvDuration("TOTP_PENDING_RETENTION", 24*time.Hour),
+		},
+		Retry: RetryConfig{
+			Email:  loadRetryPolicy("EMAIL", 5, 30*time.Second, time.Hour),
+			SMS:    loadRetryPolicy("SMS", 4, 15*time.Second, 15*time.Minute),
+			Voice:  loadRetryPolicy("VOICE", 2, 15*time.Second, 2*time.Minute),
+			Push:   loadRetryPolicy("PUSH", 3, 10*time.Second, 10*time.Minute),
+			Jitter: getEnvFloat("RETRY_JITTER", 0.2),
+		},
+		OTP: OTPConfig{
+			HashKeys:    getEnvMap("OTP_HASH_KEYS"),
+			ActiveKeyID: getEnv("OTP_HASH_ACTIVE_KEY_ID", ""),
+			Policies: map[string]OTPPolicyConfig{
+				"payout":          loadOTPPolicy("payout", 6, "numeric", "email,sms,voice"),
+				"withdrawal":      loadOTPPolicy("withdrawal", 6, "numeric", "email,sms,voice"),
+				"settings_change": loadOTPPolicy("settings_change", 8, "alphanumeric", "email,sms,voice"),
+				"login":           loadOTPPolicy("login", 8, "numeric", "email,sms,voice,totp"),
+				"2fa":             loadOTPPolicy("2fa", 6, "numeric", "email,sms,voice,totp"),
+			},
+			Limits: OTPLimitsConfig{
+				Window:           getEnvDuration("OTP_RATE_WINDOW", 10*time.Minute),
+				MaxPerRecipient:  getEnvInt("OTP_MAX_PER_RECIPIENT", 5),
+				MaxPerMerchant:   getEnvInt("OTP_MAX_PER_MERCHANT", 1000),
+				MaxPerPurpose:    getEnvInt("OTP_MAX_PER_PURPOSE", 500),
+				ResendCooldown:   getEnvDuration("OTP_RESEND_COOLDOWN", time.Minute),
+				LockoutThreshold: getEnvInt("OTP_LOCKOUT_THRESHOLD", 10),
+				LockoutWindow:    getEnvDuration("OTP_LOCKOUT_WINDOW", 15*time.Minute),
+				LockoutDuration:  getEnvDuration("OTP_LOCKOUT_DURATION", 30*time.Minute),
+			},
+			TOTP: TOTPConfig{
+				Issuer:         getEnv("TOTP_ISSUER", "KodraPay"),
+				EncryptionKeys: getEnvMap("TOTP_ENCRYPTION_KEYS"),
+				ActiveKeyID:    getEnv("TOTP_ENCRYPTION_ACTIVE_KEY_ID", ""),
+				Skew:           getEnvInt("TOTP_SKEW", 1),
+				RecoveryCodes:  getEnvInt("TOTP_RECOVERY_CODES", 10),
+			},
+			MagicLink: MagicLinkConfig{
+				BaseURL:     getEnv("OTP_MAGIC_LINK_BASE_URL", ""),
+				RedirectURL: getEnv("OTP_MAGIC_LINK_REDIRECT_URL", ""),
+			},
+			Proof: OTPProofConfig{
+				SigningKeys: getEnvMap("OTP_PROOF_SIGNING_KEYS"),
+				ActiveKeyID: getEnv("OTP_PROOF_ACTIVE_KEY_ID", ""),
+				Issuer:      getEnv("OTP_PROOF_ISSUER", serviceName),
+				TTL:         getEnvDuration("OTP_PROOF_TTL", 5*time.Minute),
+			},
+		},
+		Unsubscribe: UnsubscribeConfig{
+			BaseURL:     getEnv("UNSUBSCRIBE_BASE_URL", ""),
+			SigningKeys: getEnvMap("UNSUBSCRIBE_SIGNING_KEYS"),
+			ActiveKeyID: getEnv("UNSUBSCRIBE_ACTIVE_KEY_ID", ""),
+		},
+		TemplateTestAllowlist: getEnvList("TEMPLATE_TEST_ALLOWLIST", nil),
+	}
+}
+
+// loadOTPPolicy reads OTP_POLICY_<PURPOSE>_LENGTH, _ALPHABET, _EXPIRY, _MAX_ATTEMPTS
+// and _DELIVERY_METHODS (comma-separated)
+func loadOTPPolicy(purpose string, length int, alphabet, deliveryMethods string) OTPPolicyConfig {
+	prefix := "OTP_POLICY_" + strings.ToUpper(purpose) + "_"
+	return OTPPolicyConfig{
+		Length:          getEnvInt(prefix+"LENGTH", length),
+		Alphabet:        strings.ToLower(getEnv(prefix+"ALPHABET", alphabet)),
+		Expiry:          getEnvDuration(prefix+"EXPIRY", 10*time.Minute),
+		MaxAttempts:     getEnvInt(prefix+"MAX_ATTEMPTS", 3),
+		DeliveryMethods: getEnvList(prefix+"DELIVERY_METHODS", strings.Split(deliveryMethods, ",")),
+	}
+}
+
+// loadRetryPolicy reads RETRY_<TYPE>_MAX_ATTEMPTS, RETRY_<TYPE>_BASE_DELAY and RETRY_<TYPE>_MAX_DELAY
+func loadRetryPolicy(notifType string, maxAttempts int, baseDelay, maxDelay time.Duration) RetryPolicyConfig {
+	prefix := "RETRY_" + notifType + "_"
+	return RetryPolicyConfig{
+		MaxAttempts: getEnvInt(prefix+"MAX_ATTEMPTS", maxAttempts),
+		BaseDelay:   getEnvDuration(prefix+"BASE_DELAY", baseDelay),
+		MaxDelay:    getEnvDuration(prefix+"MAX_DELAY", maxDelay),
 	}
 }
 
@@ -34,3 +418,67 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+// getEnvList reads a comma-separated list, dropping empty entries
+func getEnvList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap reads comma-separated key:value pairs, e.g. "k2:secret2,k1:secret1"
+func getEnvMap(key string) map[string]string {
+	m := map[string]string{}
+	for _, item := range getEnvList(key, nil) {
+		k, v, ok := strings.Cut(item, ":")
+		if ok && k != "" && v != "" {
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+func getEnvInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+func getEnvFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+func getEnvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
@@ -0,0 +1,25 @@
+package dto
+
+type RegisterDeviceRequest struct {
+	UserID     string  `json:"user_id"`
+	MerchantID *string `json:"merchant_id,omitempty"`
+	DeviceID   string  `json:"device_id"`
+	Platform   string  `json:"platform"`
+	Token      string  `json:"token"`
+	AppVersion *string `json:"app_version,omitempty"`
+}
+
+type DeviceResponse struct {
+	ID         string  `json:"id"`
+	UserID     string  `json:"user_id"`
+	MerchantID *string `json:"merchant_id,omitempty"`
+	DeviceID   string  `json:"device_id"`
+	Platform   string  `json:"platform"`
+	AppVersion *string `json:"app_version,omitempty"`
+	Active     bool    `json:"active"`
+	LastSeenAt string  `json:"last_seen_at"`
+}
+
+type DeviceListResponse struct {
+	Devices []DeviceResponse `json:"devices"`
+}
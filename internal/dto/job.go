@@ -0,0 +1,23 @@
+package dto
+
+// JobStatusResponse describes a scheduled housekeeping job and its latest run on any replica
+type JobStatusResponse struct {
+	Name     string          `json:"name"`
+	Interval string          `json:"interval"`
+	LastRun  *JobRunResponse `json:"last_run,omitempty"`
+}
+
+type JobRunResponse struct {
+	Status          string  `json:"status"`
+	Affected        int64   `json:"affected"`
+	Error           *string `json:"error,omitempty"`
+	Runner          string  `json:"runner"`
+	StartedAt       string  `json:"started_at"`
+	FinishedAt      string  `json:"finished_at"`
+	LastSucceededAt *string `json:"last_succeeded_at,omitempty"`
+}
+
+type JobStatusListResponse struct {
+	Enabled bool                `json:"enabled"`
+	Jobs    []JobStatusResponse `json:"jobs"`
+}
@@ -1,18 +1,56 @@
 package dto
 
 type NotificationRequest struct {
-	Channel string `json:"channel"`
-	To      string `json:"to"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
+	Type       string  `json:"type"`    // email, sms, voice or push. Default: email
+	Channel    string  `json:"channel"` // event category, e.g. payout. Default: transaction; security and system are reserved
+	MerchantID *string `json:"merchant_id,omitempty"`
+	UserID     *string `json:"user_id,omitempty"`
+	To         string  `json:"to"`
+	Subject    string  `json:"subject"`
+	Body       string  `json:"body"`
+
+	// TemplateName renders Subject and Body from a template; TemplateVersion pins a
+	// managed version instead of the latest published one.
+	TemplateName    *string                `json:"template_name,omitempty"`
+	TemplateVersion *int                   `json:"template_version,omitempty"`
+	TemplateData    map[string]interface{} `json:"template_data,omitempty"`
+
+	// Locale selects the template variant and number/date formats, e.g. "fr-CI".
+	// Defaults to the merchant's preferred locale, then English.
+	Locale *string `json:"locale,omitempty"`
 }
 
 type NotificationResponse struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
-	SentAt string `json:"sent_at,omitempty"`
+	ID              string  `json:"id"`
+	Status          string  `json:"status"`
+	SentAt          string  `json:"sent_at,omitempty"`
+	TemplateName    *string `json:"template_name,omitempty"`
+	TemplateVersion *int    `json:"template_version,omitempty"`
 }
 
 type NotificationListResponse struct {
 	Notifications []NotificationResponse `json:"notifications"`
 }
+
+type DeadLetterResponse struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Channel      string `json:"channel"`
+	Recipient    string `json:"recipient"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	Attempts     int    `json:"attempts"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type DeadLetterListResponse struct {
+	Notifications []DeadLetterResponse `json:"notifications"`
+}
+
+// NotificationStatsResponse counts notifications created since a point in time.
+// Template test-sends are excluded.
+type NotificationStatsResponse struct {
+	Since    string         `json:"since"`
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+	ByType   map[string]int `json:"by_type"`
+}
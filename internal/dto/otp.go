@@ -0,0 +1,83 @@
+package dto
+
+// OTPResponse describes an issued OTP. The code is never returned and the
+// recipient is masked.
+type OTPResponse struct {
+	ID             string  `json:"id"`
+	MerchantID     string  `json:"merchant_id"`
+	Purpose        string  `json:"purpose"`
+	Recipient      string  `json:"recipient"`
+	DeliveryMethod string  `json:"delivery_method"`
+	ExpiresAt      string  `json:"expires_at"`
+	MaxAttempts    int     `json:"max_attempts"`
+	ReferenceID    *string `json:"reference_id,omitempty"`
+}
+
+// VerifyOTPResponse confirms a verification. ProofToken is a short-lived JWS
+// the caller passes to downstream services as evidence of the step-up.
+type VerifyOTPResponse struct {
+	Verified       bool    `json:"verified"`
+	OTPID          string  `json:"otp_id"`
+	Purpose        string  `json:"purpose"`
+	ReferenceID    *string `json:"reference_id,omitempty"`
+	VerifiedAt     string  `json:"verified_at"`
+	ProofToken     string  `json:"proof_token"`
+	ProofExpiresAt string  `json:"proof_expires_at"`
+}
+
+// OTPProofClaims are the claims of a verification proof token
+type OTPProofClaims struct {
+	Issuer      string  `json:"iss"`
+	Subject     string  `json:"sub,omitempty"` // User ID, when the OTP had one
+	IssuedAt    int64   `json:"iat"`
+	ExpiresAt   int64   `json:"exp"`
+	MerchantID  string  `json:"merchant_id"`
+	Purpose     string  `json:"purpose"`
+	ReferenceID *string `json:"reference_id,omitempty"`
+	OTPID       string  `json:"otp_id"`
+	Method      string  `json:"method"` // Delivery method the code was verified through
+}
+
+// VerifyProofRequest checks a proof token. The optional fields, when set, must
+// match the token's claims.
+type VerifyProofRequest struct {
+	Token       string  `json:"token"`
+	MerchantID  string  `json:"merchant_id,omitempty"`
+	Purpose     string  `json:"purpose,omitempty"`
+	ReferenceID *string `json:"reference_id,omitempty"`
+}
+
+type VerifyProofResponse struct {
+	Valid  bool           `json:"valid"`
+	Claims OTPProofClaims `json:"claims"`
+}
+
+// JWK is a public Ed25519 key in JSON Web Key form (RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
+
+// TOTPEnrollmentResponse carries a new authenticator app secret. It is returned
+// once; only the encrypted secret is stored.
+type TOTPEnrollmentResponse struct {
+	EnrollmentID string `json:"enrollment_id"`
+	Secret       string `json:"secret"` // Base32, for manual entry
+	OTPAuthURI   string `json:"otpauth_uri"`
+	QRCodePNG    string `json:"qr_code_png"` // Base64-encoded PNG of OTPAuthURI
+}
+
+// TOTPRecoveryCodesResponse carries newly issued recovery codes, returned once
+type TOTPRecoveryCodesResponse struct {
+	EnrollmentID  string   `json:"enrollment_id"`
+	ConfirmedAt   string   `json:"confirmed_at"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
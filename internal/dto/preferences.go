@@ -0,0 +1,78 @@
+package dto
+
+// PreferenceCells sets (category, delivery type) cells of the preference matrix,
+// e.g. {"payout": {"email": true, "sms": false}}. A null cell is unset, so it
+// falls back to the default or, for a user, to the merchant's setting.
+type PreferenceCells map[string]map[string]*bool
+
+// NotificationPreferencesRequest updates a merchant's notification preferences.
+// PUT replaces the whole matrix and contact details; PATCH changes only the
+// cells and fields present. An empty string clears a field.
+type NotificationPreferencesRequest struct {
+	Preferences  PreferenceCells `json:"preferences,omitempty"`
+	EmailAddress *string         `json:"email_address,omitempty"`
+	PhoneNumber  *string         `json:"phone_number,omitempty"`
+	Locale       *string         `json:"locale,omitempty"`
+	// Timezone is an IANA zone such as Africa/Lagos; quiet hours are read in it
+	Timezone *string `json:"timezone,omitempty"`
+	// QuietHoursStart and QuietHoursEnd are local HH:MM times and must be sent
+	// together. Non-critical notifications are held back until the window ends.
+	QuietHoursStart *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string `json:"quiet_hours_end,omitempty"`
+	// UpdatedAt is the updated_at last read, an alternative to the If-Match header
+	UpdatedAt *string `json:"updated_at,omitempty"`
+}
+
+type NotificationPreferencesResponse struct {
+	MerchantID string `json:"merchant_id"`
+	// Preferences is the effective matrix over every known category and delivery type
+	Preferences map[string]map[string]bool `json:"preferences"`
+	// MandatoryCategories are always delivered and cannot be disabled
+	MandatoryCategories []string `json:"mandatory_categories"`
+	EmailAddress        *string  `json:"email_address,omitempty"`
+	PhoneNumber         *string  `json:"phone_number,omitempty"`
+	Locale              *string  `json:"locale,omitempty"`
+	Timezone            *string  `json:"timezone,omitempty"`
+	QuietHoursStart     *string  `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd       *string  `json:"quiet_hours_end,omitempty"`
+	UpdatedAt           string   `json:"updated_at"`
+	// ETag is also returned in the ETag header; send it back in If-Match to update
+	ETag string `json:"-"`
+}
+
+// UserPreferenceOverrides are a user's overrides of their merchant's preferences.
+// Cells and fields that are absent, null or empty inherit the merchant's setting.
+type UserPreferenceOverrides struct {
+	Preferences     PreferenceCells `json:"preferences,omitempty"`
+	EmailAddress    *string         `json:"email_address,omitempty"`
+	PhoneNumber     *string         `json:"phone_number,omitempty"`
+	Locale          *string         `json:"locale,omitempty"`
+	Timezone        *string         `json:"timezone,omitempty"`
+	QuietHoursStart *string         `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string         `json:"quiet_hours_end,omitempty"`
+}
+
+// UserNotificationPreferencesRequest replaces a user's overrides
+type UserNotificationPreferencesRequest struct {
+	UserPreferenceOverrides
+	// UpdatedAt is the updated_at last read, an alternative to the If-Match header.
+	// Omit both when the user has no overrides yet.
+	UpdatedAt *string `json:"updated_at,omitempty"`
+}
+
+type UserNotificationPreferencesResponse struct {
+	MerchantID string                  `json:"merchant_id"`
+	UserID     string                  `json:"user_id"`
+	Overrides  UserPreferenceOverrides `json:"overrides"`
+	// Effective is the merchant's preferences with the overrides applied
+	Effective NotificationPreferencesResponse `json:"effective"`
+	// UpdatedAt is unset until the user has saved overrides
+	UpdatedAt *string `json:"updated_at,omitempty"`
+	ETag      string  `json:"-"`
+}
+
+// UnsubscribeResponse names the preference an unsubscribe link turns off
+type UnsubscribeResponse struct {
+	Category string `json:"category"`
+	Type     string `json:"type"`
+}
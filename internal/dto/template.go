@@ -0,0 +1,75 @@
+package dto
+
+type TemplateContent struct {
+	Subject      string   `json:"subject"`
+	TextBody     string   `json:"text_body"`
+	HTMLBody     string   `json:"html_body,omitempty"`
+	RequiredVars []string `json:"required_vars"`
+}
+
+type CreateTemplateRequest struct {
+	Name        string  `json:"name"`
+	Locale      string  `json:"locale,omitempty"`
+	Description *string `json:"description,omitempty"`
+	TemplateContent
+}
+
+type UpdateTemplateDraftRequest struct {
+	Description *string `json:"description,omitempty"`
+	TemplateContent
+}
+
+type PublishTemplateRequest struct {
+	Version int `json:"version"`
+}
+
+type TemplateResponse struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	Locale           string          `json:"locale"`
+	Description      *string         `json:"description,omitempty"`
+	Draft            TemplateContent `json:"draft"`
+	PublishedVersion *int            `json:"published_version,omitempty"`
+	CreatedAt        string          `json:"created_at"`
+	UpdatedAt        string          `json:"updated_at"`
+}
+
+type TemplateListResponse struct {
+	Templates []TemplateResponse `json:"templates"`
+}
+
+type TemplateVersionResponse struct {
+	Name      string `json:"name"`
+	Locale    string `json:"locale"`
+	Version   int    `json:"version"`
+	Published bool   `json:"published"`
+	TemplateContent
+	CreatedAt string `json:"created_at"`
+}
+
+type TemplateVersionListResponse struct {
+	Versions []TemplateVersionResponse `json:"versions"`
+}
+
+// PreviewTemplateRequest renders a template with sample data. Version omitted
+// previews the current draft.
+type PreviewTemplateRequest struct {
+	Version      *int                   `json:"version,omitempty"`
+	TemplateData map[string]interface{} `json:"template_data"`
+}
+
+type TemplatePreviewResponse struct {
+	Name     string `json:"name"`
+	Locale   string `json:"locale"`
+	Version  *int   `json:"version,omitempty"`
+	Subject  string `json:"subject"`
+	TextBody string `json:"text_body"`
+	HTMLBody string `json:"html_body,omitempty"`
+}
+
+// TestSendTemplateRequest delivers a rendered template to an allowlisted internal recipient
+type TestSendTemplateRequest struct {
+	PreviewTemplateRequest
+	Type string `json:"type"` // email or sms. Default: email
+	To   string `json:"to"`
+}
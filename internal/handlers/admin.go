@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+type AdminHandler struct {
+	scheduler *services.Scheduler
+}
+
+func NewAdminHandler(scheduler *services.Scheduler) *AdminHandler {
+	return &AdminHandler{scheduler: scheduler}
+}
+
+// Jobs lists the scheduled housekeeping jobs and their last run
+func (h *AdminHandler) Jobs(c *fiber.Ctx) error {
+	resp, err := h.scheduler.Status(c.Context())
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(resp)
+}
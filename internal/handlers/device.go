@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+type DeviceHandler struct {
+	svc *services.DeviceService
+}
+
+func NewDeviceHandler(svc *services.DeviceService) *DeviceHandler {
+	return &DeviceHandler{svc: svc}
+}
+
+func (h *DeviceHandler) Register(c *fiber.Ctx) error {
+	var req dto.RegisterDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Register(c.Context(), req)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+func (h *DeviceHandler) ListByUserID(c *fiber.Ctx) error {
+	userID := c.Params("userID")
+	resp, err := h.svc.ListByUserID(c.Context(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(resp)
+}
+
+func (h *DeviceHandler) Delete(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.svc.Delete(c.Context(), id); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
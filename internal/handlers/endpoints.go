@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/repositories"
 	"github.com/kodra-pay/notification-service/internal/services"
 )
 
@@ -22,9 +26,16 @@ func (h *NotificationHandler) Send(c *fiber.Ctx) error {
 	}
 	resp, err := h.svc.Send(c.Context(), req)
 	if err != nil {
+		var validationErr *services.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrNotificationSuppressed):
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
-	return c.JSON(resp)
+	return c.Status(fiber.StatusAccepted).JSON(resp)
 }
 
 func (h *NotificationHandler) Get(c *fiber.Ctx) error {
@@ -53,3 +64,50 @@ func (h *NotificationHandler) ListByMerchantID(c *fiber.Ctx) error {
 	}
 	return c.JSON(resp)
 }
+
+func (h *NotificationHandler) ListDeadLettered(c *fiber.Ctx) error {
+	resp, err := h.svc.ListDeadLettered(c.Context(), c.QueryInt("limit", 100))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(resp)
+}
+
+func (h *NotificationHandler) Requeue(c *fiber.Ctx) error {
+	id := c.Params("id")
+	resp, err := h.svc.Requeue(c.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrNotificationNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, repositories.ErrNotificationNotDeadLettered):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.Status(fiber.StatusAccepted).JSON(resp)
+}
+
+// Stats counts notifications by status and type. since is RFC 3339 and defaults
+// to the last 24 hours; merchant_id narrows the counts to one merchant.
+func (h *NotificationHandler) Stats(c *fiber.Ctx) error {
+	since := time.Now().Add(-24 * time.Hour)
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "since must be an RFC 3339 timestamp")
+		}
+		since = t
+	}
+
+	var merchantID *string
+	if v := c.Query("merchant_id"); v != "" {
+		merchantID = &v
+	}
+
+	resp, err := h.svc.Stats(c.Context(), merchantID, since)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+type OTPHandler struct {
+	svc *services.OTPService
+	// linkRedirectURL receives users after a magic link; empty responds with JSON
+	linkRedirectURL string
+}
+
+func NewOTPHandler(svc *services.OTPService, linkRedirectURL string) *OTPHandler {
+	return &OTPHandler{svc: svc, linkRedirectURL: linkRedirectURL}
+}
+
+func (h *OTPHandler) Generate(c *fiber.Ctx) error {
+	var req models.CreateOTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Generate(c.Context(), &req)
+	if err != nil {
+		return otpError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+func (h *OTPHandler) Verify(c *fiber.Ctx) error {
+	var req models.VerifyOTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Verify(c.Context(), &req)
+	if err != nil {
+		return otpError(c, err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *OTPHandler) Resend(c *fiber.Ctx) error {
+	var req models.CreateOTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Resend(c.Context(), &req)
+	if err != nil {
+		return otpError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// VerifyProof validates a verification proof token for services that cannot check it offline
+func (h *OTPHandler) VerifyProof(c *fiber.Ctx) error {
+	var req dto.VerifyProofRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.VerifyProof(&req)
+	if err != nil {
+		return otpError(c, err)
+	}
+	return c.JSON(resp)
+}
+
+// JWKS publishes the public keys for validating proof tokens offline
+func (h *OTPHandler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.svc.JWKS())
+}
+
+// magicLinkPage asks the recipient of a magic link to confirm before the link
+// is consumed, since mail scanners and prefetchers follow links too
+var magicLinkPage = template.Must(template.New("magic_link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Confirm it's you</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem; color: #1f2937; }
+button { font: inherit; padding: 0.5rem 1.25rem; border: 0; border-radius: 0.375rem; background: #1f2937; color: #fff; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Confirm it's you</h1>
+<p>Continue to finish verifying with the link we sent you.</p>
+<form method="post">
+<input type="hidden" name="token" value="{{.}}">
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// ConfirmLink shows the confirmation page for a magic link without consuming
+// it. Links that can no longer verify are reported as VerifyLink reports them.
+func (h *OTPHandler) ConfirmLink(c *fiber.Ctx) error {
+	// The token is in the URL; keep it out of caches and Referer headers
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+
+	token := c.Query("token")
+	if err := h.svc.CheckLink(c.Context(), token); err != nil {
+		if h.linkRedirectURL == "" {
+			return otpError(c, err)
+		}
+		return h.redirectLink(c, dto.VerifyOTPResponse{}, err)
+	}
+
+	var buf bytes.Buffer
+	if err := magicLinkPage.Execute(&buf, token); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	c.Type("html", "utf-8")
+	return c.Send(buf.Bytes())
+}
+
+// VerifyLink consumes a magic link, confirmed from ConfirmLink's form, and sends
+// the user on to the configured redirect URL with the outcome
+func (h *OTPHandler) VerifyLink(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+
+	token := c.FormValue("token")
+	if token == "" {
+		token = c.Query("token")
+	}
+
+	resp, err := h.svc.VerifyLink(c.Context(), token)
+	if h.linkRedirectURL == "" {
+		if err != nil {
+			return otpError(c, err)
+		}
+		return c.JSON(resp)
+	}
+	return h.redirectLink(c, resp, err)
+}
+
+// redirectLink sends the user to the redirect URL with a magic link's outcome
+func (h *OTPHandler) redirectLink(c *fiber.Ctx, resp dto.VerifyOTPResponse, err error) error {
+	status := linkStatus(err)
+	if status == "error" {
+		log.Printf("Failed to verify magic link: %v", err)
+	}
+
+	query := url.Values{}
+	query.Set("status", status)
+	if err == nil {
+		query.Set("purpose", resp.Purpose)
+		if resp.ReferenceID != nil {
+			query.Set("reference_id", *resp.ReferenceID)
+		}
+	}
+
+	sep := "?"
+	if strings.Contains(h.linkRedirectURL, "?") {
+		sep = "&"
+	}
+	return c.Redirect(h.linkRedirectURL+sep+query.Encode(), fiber.StatusSeeOther)
+}
+
+// linkStatus summarises a magic link outcome for the redirect target
+func linkStatus(err error) string {
+	var rateLimitErr *services.RateLimitError
+	switch {
+	case err == nil:
+		return "verified"
+	case errors.Is(err, models.ErrOTPInvalidLink):
+		return "invalid"
+	case errors.Is(err, models.ErrOTPExpired):
+		return "expired"
+	case errors.Is(err, models.ErrOTPAlreadyVerified):
+		return "already_used"
+	case errors.Is(err, models.ErrOTPAttemptsExceeded), errors.As(err, &rateLimitErr):
+		return "locked"
+	default:
+		return "error"
+	}
+}
+
+// otpError maps OTP service errors to HTTP errors, setting Retry-After on rate limits
+func otpError(c *fiber.Ctx, err error) error {
+	var validationErr *services.ValidationError
+	var rateLimitErr *services.RateLimitError
+	switch {
+	case errors.As(err, &validationErr):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.As(err, &rateLimitErr):
+		retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	case errors.Is(err, models.ErrOTPInvalidProof):
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	case errors.Is(err, repositories.ErrOTPNotFound), errors.Is(err, repositories.ErrTOTPEnrollmentNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrOTPInvalidCode), errors.Is(err, models.ErrTOTPCodeReused),
+		errors.Is(err, models.ErrOTPInvalidLink):
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, models.ErrTOTPNotEnrolled):
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, models.ErrOTPExpired):
+		return fiber.NewError(fiber.StatusGone, err.Error())
+	case errors.Is(err, models.ErrOTPAttemptsExceeded):
+		return fiber.NewError(fiber.StatusLocked, err.Error())
+	case errors.Is(err, models.ErrOTPAlreadyVerified), errors.Is(err, models.ErrTOTPAlreadyEnrolled):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrNotificationSuppressed):
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+func newMagicLinkApp(redirectURL string) *fiber.App {
+	h := NewOTPHandler(services.NewOTPService(nil, nil, nil, nil, nil, nil, nil, nil), redirectURL)
+	app := fiber.New()
+	app.Get("/otp/magic-link", h.ConfirmLink)
+	app.Post("/otp/magic-link", h.VerifyLink)
+	return app
+}
+
+func TestMagicLinkRejectsInvalidTokens(t *testing.T) {
+	form := url.Values{"token": {"not-a-token"}}.Encode()
+
+	tests := []struct {
+		name        string
+		method      string
+		redirectURL string
+		wantStatus  int
+	}{
+		{name: "confirm without redirect", method: http.MethodGet, wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "verify without redirect", method: http.MethodPost, wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "confirm with redirect", method: http.MethodGet, redirectURL: "https://app.example/done", wantStatus: fiber.StatusSeeOther},
+		{name: "verify with redirect", method: http.MethodPost, redirectURL: "https://app.example/done?from=email", wantStatus: fiber.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/otp/magic-link?token=not-a-token", nil)
+			if tt.method == http.MethodPost {
+				req = httptest.NewRequest(tt.method, "/otp/magic-link", strings.NewReader(form))
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+			}
+
+			resp, err := newMagicLinkApp(tt.redirectURL).Test(req)
+			if err != nil {
+				t.Fatalf("Test: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(fiber.HeaderCacheControl); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			if tt.redirectURL == "" {
+				return
+			}
+			location, err := url.Parse(resp.Header.Get(fiber.HeaderLocation))
+			if err != nil {
+				t.Fatalf("Location: %v", err)
+			}
+			if !strings.HasPrefix(location.String(), tt.redirectURL) || location.Query().Get("status") != "invalid" {
+				t.Errorf("Location = %s, want %s with status=invalid", location, tt.redirectURL)
+			}
+		})
+	}
+}
+
+func TestOTPErrorStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "invalid request", err: &services.ValidationError{Message: "purpose is required"}, wantStatus: fiber.StatusBadRequest},
+		{name: "invalid proof", err: models.ErrOTPInvalidProof, wantStatus: fiber.StatusUnauthorized},
+		{name: "unknown OTP", err: repositories.ErrOTPNotFound, wantStatus: fiber.StatusNotFound},
+		{name: "unknown TOTP enrollment", err: repositories.ErrTOTPEnrollmentNotFound, wantStatus: fiber.StatusNotFound},
+		{name: "already verified", err: models.ErrOTPAlreadyVerified, wantStatus: fiber.StatusConflict},
+		{name: "already enrolled", err: models.ErrTOTPAlreadyEnrolled, wantStatus: fiber.StatusConflict},
+		{name: "expired", err: fmt.Errorf("verify: %w", models.ErrOTPExpired), wantStatus: fiber.StatusGone},
+		{name: "wrong code", err: models.ErrOTPInvalidCode, wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "reused TOTP code", err: models.ErrTOTPCodeReused, wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "invalid link", err: models.ErrOTPInvalidLink, wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "not enrolled", err: models.ErrTOTPNotEnrolled, wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "suppressed", err: services.ErrNotificationSuppressed, wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "attempts exceeded", err: models.ErrOTPAttemptsExceeded, wantStatus: fiber.StatusLocked},
+		{name: "rate limited", err: &services.RateLimitError{Message: "too many OTPs", RetryAfter: 1500 * time.Millisecond},
+			wantStatus: fiber.StatusTooManyRequests, wantRetryAfter: "2"},
+		{name: "rate limited under a second", err: &services.RateLimitError{Message: "too many OTPs", RetryAfter: 10 * time.Millisecond},
+			wantStatus: fiber.StatusTooManyRequests, wantRetryAfter: "1"},
+		{name: "unexpected", err: errors.New("connection refused"), wantStatus: fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post("/otp", func(c *fiber.Ctx) error { return otpError(c, tt.err) })
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/otp", nil))
+			if err != nil {
+				t.Fatalf("Test: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(fiber.HeaderRetryAfter); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
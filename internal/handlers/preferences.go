@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+type PreferencesHandler struct {
+	svc *services.PreferencesService
+}
+
+func NewPreferencesHandler(svc *services.PreferencesService) *PreferencesHandler {
+	return &PreferencesHandler{svc: svc}
+}
+
+func (h *PreferencesHandler) Get(c *fiber.Ctx) error {
+	resp, err := h.svc.Get(c.Context(), c.Params("merchantID"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	c.Set(fiber.HeaderETag, resp.ETag)
+	return c.JSON(resp)
+}
+
+func (h *PreferencesHandler) Replace(c *fiber.Ctx) error {
+	var req dto.NotificationPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Replace(c.Context(), c.Params("merchantID"), req, preferenceUpdate(c))
+	if err != nil {
+		return preferencesError(err)
+	}
+	c.Set(fiber.HeaderETag, resp.ETag)
+	return c.JSON(resp)
+}
+
+func (h *PreferencesHandler) Patch(c *fiber.Ctx) error {
+	var req dto.NotificationPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Patch(c.Context(), c.Params("merchantID"), req, preferenceUpdate(c))
+	if err != nil {
+		return preferencesError(err)
+	}
+	c.Set(fiber.HeaderETag, resp.ETag)
+	return c.JSON(resp)
+}
+
+func (h *PreferencesHandler) GetUser(c *fiber.Ctx) error {
+	resp, err := h.svc.GetUser(c.Context(), c.Params("merchantID"), c.Params("userID"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	if resp.ETag != "" {
+		c.Set(fiber.HeaderETag, resp.ETag)
+	}
+	return c.JSON(resp)
+}
+
+func (h *PreferencesHandler) ReplaceUser(c *fiber.Ctx) error {
+	var req dto.UserNotificationPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.ReplaceUser(c.Context(), c.Params("merchantID"), c.Params("userID"), req, preferenceUpdate(c))
+	if err != nil {
+		return preferencesError(err)
+	}
+	if resp.ETag != "" {
+		c.Set(fiber.HeaderETag, resp.ETag)
+	}
+	return c.JSON(resp)
+}
+
+// preferenceUpdate collects the precondition and audit details from the request.
+// The actor is taken from the client-supplied X-Actor-ID header, so the audit
+// trail records whoever the caller claims to be; the gateway in front of this
+// service must set or strip it.
+func preferenceUpdate(c *fiber.Ctx) services.PreferenceUpdate {
+	update := services.PreferenceUpdate{IfMatch: c.Get(fiber.HeaderIfMatch)}
+	if actor := c.Get("X-Actor-ID"); actor != "" {
+		update.ChangedBy = &actor
+	}
+	if requestID := c.GetRespHeader("X-Request-ID"); requestID != "" {
+		update.RequestID = &requestID
+	}
+	return update
+}
+
+func preferencesError(err error) error {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPreconditionRequired):
+		return fiber.NewError(fiber.StatusPreconditionRequired, err.Error())
+	case errors.Is(err, repositories.ErrPreferencesModified):
+		return fiber.NewError(fiber.StatusPreconditionFailed, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
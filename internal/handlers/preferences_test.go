@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+func TestPreferencesErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"missing If-Match", services.ErrPreconditionRequired, fiber.StatusPreconditionRequired},
+		{"stale If-Match", repositories.ErrPreferencesModified, fiber.StatusPreconditionFailed},
+		{"wrapped stale If-Match", fmt.Errorf("update: %w", repositories.ErrPreferencesModified), fiber.StatusPreconditionFailed},
+		{"invalid request", &services.ValidationError{Message: "bad quiet hours"}, fiber.StatusBadRequest},
+		{"database failure", fmt.Errorf("connection refused"), fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Patch("/preferences", func(c *fiber.Ctx) error { return preferencesError(tt.err) })
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodPatch, "/preferences", nil))
+			if err != nil {
+				t.Fatalf("Test: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPreferenceUpdateFromRequest(t *testing.T) {
+	var got services.PreferenceUpdate
+	app := fiber.New()
+	app.Patch("/preferences", func(c *fiber.Ctx) error {
+		c.Set("X-Request-ID", "req-42")
+		got = preferenceUpdate(c)
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPatch, "/preferences", nil)
+	req.Header.Set(fiber.HeaderIfMatch, `W/"abc"`)
+	req.Header.Set("X-Actor-ID", "admin-7")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+
+	if got.IfMatch != `W/"abc"` {
+		t.Errorf("IfMatch = %q", got.IfMatch)
+	}
+	if got.ChangedBy == nil || *got.ChangedBy != "admin-7" {
+		t.Errorf("ChangedBy = %v, want the X-Actor-ID header", got.ChangedBy)
+	}
+	if got.RequestID == nil || *got.RequestID != "req-42" {
+		t.Errorf("RequestID = %v, want req-42", got.RequestID)
+	}
+}
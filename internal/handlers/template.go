@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+type TemplateHandler struct {
+	svc *services.TemplateService
+}
+
+func NewTemplateHandler(svc *services.TemplateService) *TemplateHandler {
+	return &TemplateHandler{svc: svc}
+}
+
+func (h *TemplateHandler) Create(c *fiber.Ctx) error {
+	var req dto.CreateTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Create(c.Context(), req)
+	if err != nil {
+		return templateError(err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+func (h *TemplateHandler) List(c *fiber.Ctx) error {
+	resp, err := h.svc.List(c.Context())
+	if err != nil {
+		return templateError(err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TemplateHandler) Get(c *fiber.Ctx) error {
+	resp, err := h.svc.Get(c.Context(), c.Params("name"), c.Query("locale"))
+	if err != nil {
+		return templateError(err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TemplateHandler) UpdateDraft(c *fiber.Ctx) error {
+	var req dto.UpdateTemplateDraftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.UpdateDraft(c.Context(), c.Params("name"), c.Query("locale"), req)
+	if err != nil {
+		return templateError(err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TemplateHandler) Delete(c *fiber.Ctx) error {
+	if err := h.svc.Delete(c.Context(), c.Params("name"), c.Query("locale")); err != nil {
+		return templateError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *TemplateHandler) CreateVersion(c *fiber.Ctx) error {
+	resp, err := h.svc.CreateVersion(c.Context(), c.Params("name"), c.Query("locale"))
+	if err != nil {
+		return templateError(err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+func (h *TemplateHandler) ListVersions(c *fiber.Ctx) error {
+	resp, err := h.svc.ListVersions(c.Context(), c.Params("name"), c.Query("locale"))
+	if err != nil {
+		return templateError(err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TemplateHandler) GetVersion(c *fiber.Ctx) error {
+	version, err := c.ParamsInt("version")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid version")
+	}
+	resp, err := h.svc.GetVersion(c.Context(), c.Params("name"), c.Query("locale"), version)
+	if err != nil {
+		return templateError(err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TemplateHandler) Publish(c *fiber.Ctx) error {
+	var req dto.PublishTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Publish(c.Context(), c.Params("name"), c.Query("locale"), req)
+	if err != nil {
+		return templateError(err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TemplateHandler) Preview(c *fiber.Ctx) error {
+	var req dto.PreviewTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Preview(c.Context(), c.Params("name"), c.Query("locale"), req)
+	if err != nil {
+		return templateError(err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TemplateHandler) TestSend(c *fiber.Ctx) error {
+	var req dto.TestSendTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.TestSend(c.Context(), c.Params("name"), c.Query("locale"), req)
+	if err != nil {
+		return templateError(err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(resp)
+}
+
+// templateError maps template service errors to HTTP errors
+func templateError(err error) error {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrTemplateNotFound),
+		errors.Is(err, repositories.ErrTemplateVersionNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, repositories.ErrTemplateExists):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrRecipientNotAllowed):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+type TOTPHandler struct {
+	svc *services.TOTPService
+}
+
+func NewTOTPHandler(svc *services.TOTPService) *TOTPHandler {
+	return &TOTPHandler{svc: svc}
+}
+
+func (h *TOTPHandler) Enroll(c *fiber.Ctx) error {
+	var req models.EnrollTOTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Enroll(c.Context(), &req)
+	if err != nil {
+		return otpError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+func (h *TOTPHandler) Confirm(c *fiber.Ctx) error {
+	var req models.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.Confirm(c.Context(), &req)
+	if err != nil {
+		return otpError(c, err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *TOTPHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req models.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.RegenerateRecoveryCodes(c.Context(), &req)
+	if err != nil {
+		return otpError(c, err)
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+// unsubscribePage is shown for every unsubscribe outcome. The link itself only
+// asks for confirmation, since mail scanners follow links; the opt-out happens
+// on POST, from the form or from a mail client's one-click request.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem; color: #1f2937; }
+button { font: inherit; padding: 0.5rem 1.25rem; border: 0; border-radius: 0.375rem; background: #1f2937; color: #fff; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{- if .Token}}
+<form method="post">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Unsubscribe</button>
+</form>
+{{- end}}
+</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Title   string
+	Message string
+	// Token is set to show the confirmation form
+	Token string
+}
+
+// UnsubscribeHandler serves the public one-click unsubscribe links carried by
+// emails. The signed token is the only credential.
+type UnsubscribeHandler struct {
+	svc *services.PreferencesService
+}
+
+func NewUnsubscribeHandler(svc *services.PreferencesService) *UnsubscribeHandler {
+	return &UnsubscribeHandler{svc: svc}
+}
+
+// Confirm asks the recipient to confirm the opt-out a link names
+func (h *UnsubscribeHandler) Confirm(c *fiber.Ctx) error {
+	token := c.Query("token")
+	resp, err := h.svc.CheckUnsubscribe(token)
+	if err != nil {
+		return unsubscribeError(c, err)
+	}
+	return renderUnsubscribePage(c, fiber.StatusOK, unsubscribePageData{
+		Title:   "Unsubscribe",
+		Message: "Stop receiving " + categoryLabel(resp.Category) + " notifications by " + resp.Type + "?",
+		Token:   token,
+	})
+}
+
+// Unsubscribe applies the opt-out. It accepts the RFC 8058 one-click POST, with
+// the token in the query string, as well as the confirmation form.
+func (h *UnsubscribeHandler) Unsubscribe(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		token = c.FormValue("token")
+	}
+
+	var requestID *string
+	if id := c.GetRespHeader("X-Request-ID"); id != "" {
+		requestID = &id
+	}
+
+	resp, err := h.svc.Unsubscribe(c.Context(), token, requestID)
+	if err != nil {
+		return unsubscribeError(c, err)
+	}
+	return renderUnsubscribePage(c, fiber.StatusOK, unsubscribePageData{
+		Title:   "You have been unsubscribed",
+		Message: "You will no longer receive " + categoryLabel(resp.Category) + " notifications by " + resp.Type + ".",
+	})
+}
+
+func unsubscribeError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrInvalidUnsubscribeToken) {
+		return renderUnsubscribePage(c, fiber.StatusBadRequest, unsubscribePageData{
+			Title:   "Invalid link",
+			Message: "This unsubscribe link is invalid or no longer works. You can change your notifications in your account settings.",
+		})
+	}
+
+	log.Printf("Failed to unsubscribe: %v", err)
+	return renderUnsubscribePage(c, fiber.StatusInternalServerError, unsubscribePageData{
+		Title:   "Something went wrong",
+		Message: "We could not update your preferences. Please try again later.",
+	})
+}
+
+func renderUnsubscribePage(c *fiber.Ctx, status int, data unsubscribePageData) error {
+	// The token is in the URL; keep it out of caches and Referer headers
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+
+	var buf bytes.Buffer
+	if err := unsubscribePage.Execute(&buf, data); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	c.Type("html", "utf-8")
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// categoryLabel turns a category name such as "payout" or "kyc_review" into words
+func categoryLabel(category string) string {
+	return strings.ReplaceAll(category, "_", " ")
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/notification-service/internal/providers"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/services"
+)
+
+// VoiceHandler receives call status callbacks from the voice provider
+type VoiceHandler struct {
+	svc            *services.NotificationServiceV2
+	callbackSecret string
+}
+
+func NewVoiceHandler(svc *services.NotificationServiceV2, callbackSecret string) *VoiceHandler {
+	return &VoiceHandler{svc: svc, callbackSecret: callbackSecret}
+}
+
+// Status records a call outcome, falling back to SMS when the call was not answered
+func (h *VoiceHandler) Status(c *fiber.Ctx) error {
+	status, err := providers.ParseVoiceCallback(h.callbackSecret, c.Body(), c.Get("X-Signature"))
+	if err != nil {
+		if errors.Is(err, providers.ErrInvalidCallbackSignature) {
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		}
+		if errors.Is(err, providers.ErrCallbackNotConfigured) {
+			return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+		}
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.svc.HandleVoiceStatus(c.Context(), status); err != nil {
+		if errors.Is(err, repositories.ErrNotificationNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
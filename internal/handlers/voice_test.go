@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestVoiceStatusRejectsUnauthenticatedCallbacks(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		wantStatus int
+	}{
+		{name: "bad signature", secret: "callback-secret", wantStatus: fiber.StatusUnauthorized},
+		{name: "secret not configured", wantStatus: fiber.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post("/webhooks/voice/status", NewVoiceHandler(nil, tt.secret).Status)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/voice/status",
+				strings.NewReader(`{"call_id":"CA123","reference":"voice-1","status":"busy"}`))
+			req.Header.Set("X-Signature", "deadbeef")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Test: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
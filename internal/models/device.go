@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+type DevicePlatform string
+
+const (
+	PlatformFCM  DevicePlatform = "fcm"
+	PlatformAPNs DevicePlatform = "apns"
+	PlatformWeb  DevicePlatform = "web"
+)
+
+// Device is a push token registered by a user or merchant for one of their devices
+type Device struct {
+	ID         string         `json:"id" db:"id"`
+	UserID     string         `json:"user_id" db:"user_id"`
+	MerchantID *string        `json:"merchant_id,omitempty" db:"merchant_id"`
+	DeviceID   string         `json:"device_id" db:"device_id"`
+	Platform   DevicePlatform `json:"platform" db:"platform"`
+	Token      string         `json:"token" db:"token"`
+	AppVersion *string        `json:"app_version,omitempty" db:"app_version"`
+	Active     bool           `json:"active" db:"active"`
+	LastSeenAt time.Time      `json:"last_seen_at" db:"last_seen_at"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// IsValidPlatform checks if the platform is supported for push delivery
+func IsValidPlatform(p DevicePlatform) bool {
+	switch p {
+	case PlatformFCM, PlatformAPNs, PlatformWeb:
+		return true
+	default:
+		return false
+	}
+}
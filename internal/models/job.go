@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+type JobRunStatus string
+
+const (
+	JobSucceeded JobRunStatus = "succeeded"
+	JobFailed    JobRunStatus = "failed"
+)
+
+// JobRun records the latest run of a scheduled housekeeping job
+type JobRun struct {
+	Name            string       `json:"name" db:"name"`
+	Status          JobRunStatus `json:"status" db:"status"`
+	Affected        int64        `json:"affected" db:"affected"` // Rows cleaned up
+	Error           *string      `json:"error,omitempty" db:"error"`
+	Runner          string       `json:"runner" db:"runner"` // Replica that ran the job
+	StartedAt       time.Time    `json:"started_at" db:"started_at"`
+	FinishedAt      time.Time    `json:"finished_at" db:"finished_at"`
+	LastSucceededAt *time.Time   `json:"last_succeeded_at,omitempty" db:"last_succeeded_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sort"
 	"time"
 )
 
@@ -11,6 +12,7 @@ type NotificationStatus string
 const (
 	TypeEmail NotificationType = "email"
 	TypeSMS   NotificationType = "sms"
+	TypeVoice NotificationType = "voice"
 	TypePush  NotificationType = "push"
 
 	ChannelTransaction NotificationChannel = "transaction"
@@ -18,87 +20,347 @@ const (
 	ChannelSettlement  NotificationChannel = "settlement"
 	ChannelSecurity    NotificationChannel = "security"
 	ChannelSystem      NotificationChannel = "system"
+	ChannelMarketing   NotificationChannel = "marketing"
 
-	StatusPending   NotificationStatus = "pending"
-	StatusSent      NotificationStatus = "sent"
-	StatusFailed    NotificationStatus = "failed"
-	StatusDelivered NotificationStatus = "delivered"
+	StatusPending    NotificationStatus = "pending"
+	StatusProcessing NotificationStatus = "processing"
+	StatusSent       NotificationStatus = "sent"
+	StatusFailed     NotificationStatus = "failed"
+	StatusDelivered  NotificationStatus = "delivered"
+	// StatusDeadLettered is terminal: delivery failed permanently or ran out of attempts
+	StatusDeadLettered NotificationStatus = "dead_lettered"
 )
 
 type Notification struct {
-	ID           string                 `json:"id" db:"id"`
-	MerchantID   *string                `json:"merchant_id,omitempty" db:"merchant_id"`
-	UserID       *string                `json:"user_id,omitempty" db:"user_id"`
-	Type         NotificationType       `json:"type" db:"type"`
-	Channel      NotificationChannel    `json:"channel" db:"channel"`
-	Recipient    string                 `json:"recipient" db:"recipient"`
-	Subject      *string                `json:"subject,omitempty" db:"subject"`
-	Message      string                 `json:"message" db:"message"`
-	TemplateName *string                `json:"template_name,omitempty" db:"template_name"`
-	TemplateData map[string]interface{} `json:"template_data,omitempty" db:"template_data"`
-	Status       NotificationStatus     `json:"status" db:"status"`
-	SentAt       *time.Time             `json:"sent_at,omitempty" db:"sent_at"`
-	DeliveredAt  *time.Time             `json:"delivered_at,omitempty" db:"delivered_at"`
-	ErrorMessage *string                `json:"error_message,omitempty" db:"error_message"`
-	RetryCount   int                    `json:"retry_count" db:"retry_count"`
-	Metadata     map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
-	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+	ID           string              `json:"id" db:"id"`
+	MerchantID   *string             `json:"merchant_id,omitempty" db:"merchant_id"`
+	UserID       *string             `json:"user_id,omitempty" db:"user_id"`
+	Type         NotificationType    `json:"type" db:"type"`
+	Channel      NotificationChannel `json:"channel" db:"channel"`
+	Recipient    string              `json:"recipient" db:"recipient"`
+	Subject      *string             `json:"subject,omitempty" db:"subject"`
+	Message      string              `json:"message" db:"message"`
+	HTMLMessage  *string             `json:"html_message,omitempty" db:"html_message"`
+	TemplateName *string             `json:"template_name,omitempty" db:"template_name"`
+	// TemplateVersion pins a managed template version on send and records the version used once rendered
+	TemplateVersion *int                   `json:"template_version,omitempty" db:"template_version"`
+	TemplateData    map[string]interface{} `json:"template_data,omitempty" db:"template_data"`
+	Locale          *string                `json:"locale,omitempty" db:"locale"`
+	Status          NotificationStatus     `json:"status" db:"status"`
+	SentAt          *time.Time             `json:"sent_at,omitempty" db:"sent_at"`
+	DeliveredAt     *time.Time             `json:"delivered_at,omitempty" db:"delivered_at"`
+	ErrorMessage    *string                `json:"error_message,omitempty" db:"error_message"`
+	RetryCount      int                    `json:"retry_count" db:"retry_count"`
+	NextAttemptAt   *time.Time             `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeadLetteredAt  *time.Time             `json:"dead_lettered_at,omitempty" db:"dead_lettered_at"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
+	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
 }
 
-type NotificationPreferences struct {
-	ID                       string     `json:"id" db:"id"`
-	MerchantID               string     `json:"merchant_id" db:"merchant_id"`
-	EmailEnabled             bool       `json:"email_enabled" db:"email_enabled"`
-	SMSEnabled               bool       `json:"sms_enabled" db:"sms_enabled"`
-	PushEnabled              bool       `json:"push_enabled" db:"push_enabled"`
-	TransactionNotifications bool       `json:"transaction_notifications" db:"transaction_notifications"`
-	PayoutNotifications      bool       `json:"payout_notifications" db:"payout_notifications"`
-	SettlementNotifications  bool       `json:"settlement_notifications" db:"settlement_notifications"`
-	SecurityNotifications    bool       `json:"security_notifications" db:"security_notifications"`
-	MarketingNotifications   bool       `json:"marketing_notifications" db:"marketing_notifications"`
-	EmailAddress             *string    `json:"email_address,omitempty" db:"email_address"`
-	PhoneNumber              *string    `json:"phone_number,omitempty" db:"phone_number"`
-	CreatedAt                time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt                time.Time  `json:"updated_at" db:"updated_at"`
-}
-
-// ShouldSend determines if a notification should be sent based on preferences
-func (np *NotificationPreferences) ShouldSend(notifType NotificationType, channel NotificationChannel) bool {
-	// Security notifications are always sent
-	if channel == ChannelSecurity && np.SecurityNotifications {
-		return true
+// NotificationCount is the number of notifications of one type in one status
+type NotificationCount struct {
+	Type   NotificationType
+	Status NotificationStatus
+	Count  int
+}
+
+// Delivery types a preference can be set for
+var NotificationTypes = []NotificationType{TypeEmail, TypeSMS, TypeVoice, TypePush}
+
+// PreferenceCategories are the well-known event categories preferences are shown
+// for. Any other category name can be stored too; it uses the type defaults.
+var PreferenceCategories = []NotificationChannel{
+	ChannelTransaction, ChannelPayout, ChannelSettlement, ChannelSecurity, ChannelSystem, ChannelMarketing,
+}
+
+// MandatoryCategories are always delivered and cannot be disabled by merchants or users
+var MandatoryCategories = []NotificationChannel{ChannelSecurity, ChannelSystem}
+
+// IsMandatoryCategory reports whether notifications in a category are always sent
+func IsMandatoryCategory(category NotificationChannel) bool {
+	for _, c := range MandatoryCategories {
+		if c == category {
+			return true
+		}
 	}
+	return false
+}
 
-	// Check channel-specific preference
-	var channelEnabled bool
-	switch channel {
-	case ChannelTransaction:
-		channelEnabled = np.TransactionNotifications
-	case ChannelPayout:
-		channelEnabled = np.PayoutNotifications
-	case ChannelSettlement:
-		channelEnabled = np.SettlementNotifications
-	case ChannelSecurity:
-		channelEnabled = np.SecurityNotifications
-	case ChannelSystem:
-		channelEnabled = true // System notifications are always sent
-	default:
-		return false
+// IsValidNotificationType reports whether t is a supported delivery type
+func IsValidNotificationType(t NotificationType) bool {
+	for _, nt := range NotificationTypes {
+		if nt == t {
+			return true
+		}
 	}
+	return false
+}
 
-	if !channelEnabled {
+// DefaultPreference is whether a category is delivered by a type when neither
+// the merchant nor the user has set it. Marketing is opt-in.
+func DefaultPreference(category NotificationChannel, notifType NotificationType) bool {
+	if category == ChannelMarketing {
 		return false
 	}
-
-	// Check notification type preference
 	switch notifType {
-	case TypeEmail:
-		return np.EmailEnabled
-	case TypeSMS:
-		return np.SMSEnabled
-	case TypePush:
-		return np.PushEnabled
+	case TypeEmail, TypePush:
+		return true
 	default:
 		return false
 	}
 }
+
+// PreferenceMatrix holds explicit (category, delivery type) settings. Cells that
+// are not present fall back to the layer below.
+type PreferenceMatrix map[NotificationChannel]map[NotificationType]bool
+
+// Get returns a cell and whether it is set
+func (m PreferenceMatrix) Get(category NotificationChannel, notifType NotificationType) (bool, bool) {
+	enabled, ok := m[category][notifType]
+	return enabled, ok
+}
+
+// Set stores a cell
+func (m PreferenceMatrix) Set(category NotificationChannel, notifType NotificationType, enabled bool) {
+	if m[category] == nil {
+		m[category] = map[NotificationType]bool{}
+	}
+	m[category][notifType] = enabled
+}
+
+// Unset removes a cell so it falls back again
+func (m PreferenceMatrix) Unset(category NotificationChannel, notifType NotificationType) {
+	delete(m[category], notifType)
+	if len(m[category]) == 0 {
+		delete(m, category)
+	}
+}
+
+// Clone returns a deep copy of the matrix
+func (m PreferenceMatrix) Clone() PreferenceMatrix {
+	clone := make(PreferenceMatrix, len(m))
+	for category, cells := range m {
+		for notifType, enabled := range cells {
+			clone.Set(category, notifType, enabled)
+		}
+	}
+	return clone
+}
+
+// Categories lists the well-known categories followed by any others set in the matrix
+func (m PreferenceMatrix) Categories() []NotificationChannel {
+	categories := append([]NotificationChannel{}, PreferenceCategories...)
+	seen := make(map[NotificationChannel]bool, len(categories))
+	for _, c := range categories {
+		seen[c] = true
+	}
+	var custom []string
+	for c := range m {
+		if !seen[c] {
+			custom = append(custom, string(c))
+		}
+	}
+	sort.Strings(custom)
+	for _, c := range custom {
+		categories = append(categories, NotificationChannel(c))
+	}
+	return categories
+}
+
+// NotificationPreferences are a merchant's contact details and the (category,
+// delivery type) matrix of which notifications they receive
+type NotificationPreferences struct {
+	ID           string           `json:"id" db:"id"`
+	MerchantID   string           `json:"merchant_id" db:"merchant_id"`
+	Matrix       PreferenceMatrix `json:"preferences"`
+	EmailAddress *string          `json:"email_address,omitempty" db:"email_address"`
+	PhoneNumber  *string          `json:"phone_number,omitempty" db:"phone_number"`
+	Locale       *string          `json:"locale,omitempty" db:"locale"`
+	// Timezone is the IANA zone quiet hours are read in; UTC when unset
+	Timezone *string `json:"timezone,omitempty" db:"timezone"`
+	// QuietHoursStart and QuietHoursEnd are local HH:MM times, set together
+	QuietHoursStart *string   `json:"quiet_hours_start,omitempty" db:"quiet_hours_start"`
+	QuietHoursEnd   *string   `json:"quiet_hours_end,omitempty" db:"quiet_hours_end"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UserNotificationPreferences are one team member's overrides of their merchant's
+// preferences. Cells missing from Matrix and nil fields inherit the merchant's
+// settings.
+type UserNotificationPreferences struct {
+	ID              string           `json:"id" db:"id"`
+	MerchantID      string           `json:"merchant_id" db:"merchant_id"`
+	UserID          string           `json:"user_id" db:"user_id"`
+	Matrix          PreferenceMatrix `json:"preferences"`
+	EmailAddress    *string          `json:"email_address,omitempty" db:"email_address"`
+	PhoneNumber     *string          `json:"phone_number,omitempty" db:"phone_number"`
+	Locale          *string          `json:"locale,omitempty" db:"locale"`
+	Timezone        *string          `json:"timezone,omitempty" db:"timezone"`
+	QuietHoursStart *string          `json:"quiet_hours_start,omitempty" db:"quiet_hours_start"`
+	QuietHoursEnd   *string          `json:"quiet_hours_end,omitempty" db:"quiet_hours_end"`
+	CreatedAt       time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// WithUserOverrides returns the effective preferences for a user: the merchant's
+// preferences with the user's overrides applied. A nil user inherits everything.
+func (np *NotificationPreferences) WithUserOverrides(user *UserNotificationPreferences) *NotificationPreferences {
+	effective := *np
+	effective.Matrix = np.Matrix.Clone()
+	if user == nil {
+		return &effective
+	}
+
+	for category, types := range user.Matrix {
+		for notifType, enabled := range types {
+			effective.Matrix.Set(category, notifType, enabled)
+		}
+	}
+	if user.EmailAddress != nil {
+		effective.EmailAddress = user.EmailAddress
+	}
+	if user.PhoneNumber != nil {
+		effective.PhoneNumber = user.PhoneNumber
+	}
+	if user.Locale != nil {
+		effective.Locale = user.Locale
+	}
+	if user.Timezone != nil {
+		effective.Timezone = user.Timezone
+	}
+	if user.QuietHoursStart != nil && user.QuietHoursEnd != nil {
+		effective.QuietHoursStart = user.QuietHoursStart
+		effective.QuietHoursEnd = user.QuietHoursEnd
+	}
+
+	return &effective
+}
+
+// PreferenceFieldChange is the before and after value of one changed preference
+type PreferenceFieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// NotificationPreferenceChange is an audit record of one update to a merchant's
+// preferences, or to a user's overrides when UserID is set
+type NotificationPreferenceChange struct {
+	ID         string                           `json:"id" db:"id"`
+	MerchantID string                           `json:"merchant_id" db:"merchant_id"`
+	UserID     *string                          `json:"user_id,omitempty" db:"user_id"`
+	Changes    map[string]PreferenceFieldChange `json:"changes" db:"changes"`
+	ChangedBy  *string                          `json:"changed_by,omitempty" db:"changed_by"`
+	RequestID  *string                          `json:"request_id,omitempty" db:"request_id"`
+	CreatedAt  time.Time                        `json:"created_at" db:"created_at"`
+}
+
+// ShouldSend determines if a notification should be sent based on preferences.
+// Mandatory categories are always sent; otherwise the matrix cell applies, or
+// the default when it is not set. For a user's notifications, call it on the
+// effective preferences from WithUserOverrides so both layers are taken into
+// account.
+func (np *NotificationPreferences) ShouldSend(notifType NotificationType, channel NotificationChannel) bool {
+	if IsMandatoryCategory(channel) {
+		return true
+	}
+	if !IsValidNotificationType(notifType) {
+		return false
+	}
+	if enabled, ok := np.Matrix.Get(channel, notifType); ok {
+		return enabled
+	}
+	return DefaultPreference(channel, notifType)
+}
+
+// IsCritical reports whether a notification must go out immediately, ignoring
+// quiet hours: security notifications, which include OTPs, and anything marked
+// as an OTP in its metadata
+func (n *Notification) IsCritical() bool {
+	if n.Channel == ChannelSecurity {
+		return true
+	}
+	_, isOTP := n.Metadata["otp_purpose"]
+	return isOTP
+}
+
+// ParseClock parses a local HH:MM time into minutes after midnight
+func ParseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// QuietUntil reports whether now falls within the quiet hours and, if so, when
+// they end. Windows whose end is not after their start span midnight.
+func (np *NotificationPreferences) QuietUntil(now time.Time) (time.Time, bool) {
+	if np.QuietHoursStart == nil || np.QuietHoursEnd == nil {
+		return time.Time{}, false
+	}
+	start, ok := ParseClock(*np.QuietHoursStart)
+	if !ok {
+		return time.Time{}, false
+	}
+	end, ok := ParseClock(*np.QuietHoursEnd)
+	if !ok || start == end {
+		return time.Time{}, false
+	}
+
+	loc := time.UTC
+	if np.Timezone != nil {
+		if l, err := time.LoadLocation(*np.Timezone); err == nil {
+			loc = l
+		}
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	var quiet bool
+	if start < end {
+		quiet = minute >= start && minute < end
+	} else {
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	for day := 0; ; day++ {
+		until := wallClock(local.Year(), local.Month(), local.Day()+day, end, loc)
+		if until.After(local) {
+			return until, true
+		}
+		// When the clock falls back it reads the end time twice; the second
+		// reading may still be ahead
+		if _, zoneEnd := until.ZoneBounds(); !zoneEnd.IsZero() {
+			_, before := until.Zone()
+			_, after := zoneEnd.Zone()
+			repeat := until.Add(time.Duration(before-after) * time.Second)
+			if !repeat.Before(zoneEnd) && repeat.After(local) {
+				return repeat, true
+			}
+		}
+	}
+}
+
+// wallClock returns the instant the clock in loc reads minute (after midnight)
+// on the given day. If the clock skips over it when springing forward, it
+// returns the moment of the skip, the first instant at or past that reading.
+func wallClock(year int, month time.Month, day, minute int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, minute/60, minute%60, 0, 0, loc)
+	if t.Hour()*60+t.Minute() == minute {
+		return t
+	}
+
+	// time.Date resolved the missing reading in one of the two zones around the
+	// gap; the gap is where they meet
+	start, end := t.ZoneBounds()
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	want := time.Date(year, month, day, minute/60, minute%60, 0, 0, time.UTC)
+	if got.Before(want) {
+		return end
+	}
+	return start
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"testing"
+	"time"
+	// The zones below must load without system zoneinfo
+	_ "time/tzdata"
+)
+
+func quietPrefs(tz, start, end string) *NotificationPreferences {
+	prefs := &NotificationPreferences{QuietHoursStart: &start, QuietHoursEnd: &end}
+	if tz != "" {
+		prefs.Timezone = &tz
+	}
+	return prefs
+}
+
+func TestQuietUntil(t *testing.T) {
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("parse %q: %v", s, err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name  string
+		prefs *NotificationPreferences
+		now   string
+		want  string // empty when not quiet
+	}{
+		{name: "daytime window", prefs: quietPrefs("", "09:00", "17:00"), now: "2026-06-10T12:00:00Z", want: "2026-06-10T17:00:00Z"},
+		{name: "outside daytime window", prefs: quietPrefs("", "09:00", "17:00"), now: "2026-06-10T18:00:00Z"},
+		{name: "before midnight", prefs: quietPrefs("", "22:00", "07:00"), now: "2026-06-10T23:00:00Z", want: "2026-06-11T07:00:00Z"},
+		{name: "after midnight", prefs: quietPrefs("", "22:00", "07:00"), now: "2026-06-11T03:00:00Z", want: "2026-06-11T07:00:00Z"},
+		{name: "start is inclusive", prefs: quietPrefs("", "22:00", "07:00"), now: "2026-06-10T22:00:00Z", want: "2026-06-11T07:00:00Z"},
+		{name: "end is exclusive", prefs: quietPrefs("", "22:00", "07:00"), now: "2026-06-11T07:00:00Z"},
+		{name: "midday outside overnight window", prefs: quietPrefs("", "22:00", "07:00"), now: "2026-06-11T12:00:00Z"},
+		{name: "empty window", prefs: quietPrefs("", "22:00", "22:00"), now: "2026-06-10T22:30:00Z"},
+		{name: "unknown zone reads UTC", prefs: quietPrefs("Mars/Olympus_Mons", "22:00", "07:00"), now: "2026-06-10T23:00:00Z", want: "2026-06-11T07:00:00Z"},
+		// 23:30 in Lagos (UTC+1)
+		{name: "recipient zone", prefs: quietPrefs("Africa/Lagos", "22:00", "07:00"), now: "2026-06-10T22:30:00Z", want: "2026-06-11T06:00:00Z"},
+
+		// New York springs forward from 02:00 EST to 03:00 EDT on 2026-03-08
+		{name: "spring forward across window", prefs: quietPrefs("America/New_York", "01:00", "06:00"), now: "2026-03-08T06:30:00Z", want: "2026-03-08T10:00:00Z"},
+		{name: "spring forward skips end", prefs: quietPrefs("America/New_York", "22:00", "02:30"), now: "2026-03-08T06:50:00Z", want: "2026-03-08T07:00:00Z"},
+		{name: "after skipped end", prefs: quietPrefs("America/New_York", "22:00", "02:30"), now: "2026-03-08T07:10:00Z"},
+		// and falls back from 02:00 EDT to 01:00 EST on 2026-11-01
+		{name: "fall back across window", prefs: quietPrefs("America/New_York", "00:00", "06:00"), now: "2026-11-01T04:30:00Z", want: "2026-11-01T11:00:00Z"},
+		{name: "fall back first reading of end", prefs: quietPrefs("America/New_York", "00:00", "01:30"), now: "2026-11-01T05:10:00Z", want: "2026-11-01T05:30:00Z"},
+		{name: "fall back second reading of end", prefs: quietPrefs("America/New_York", "00:00", "01:30"), now: "2026-11-01T06:10:00Z", want: "2026-11-01T06:30:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := tt.prefs.QuietUntil(utc(tt.now))
+			if tt.want == "" {
+				if quiet {
+					t.Fatalf("quiet until %v, want not quiet", until)
+				}
+				return
+			}
+			if !quiet {
+				t.Fatal("not quiet, want quiet")
+			}
+			if !until.Equal(utc(tt.want)) {
+				t.Errorf("until = %v, want %v", until.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietUntilUserTimezoneOverride(t *testing.T) {
+	merchant := quietPrefs("UTC", "22:00", "07:00")
+	tokyo := "Asia/Tokyo"
+	// 23:00 in Tokyo, mid-afternoon in UTC
+	now := time.Date(2026, 6, 10, 14, 0, 0, 0, time.UTC)
+
+	if _, quiet := merchant.QuietUntil(now); quiet {
+		t.Fatal("merchant window is quiet in UTC afternoon")
+	}
+
+	// A user who only sets a timezone has the merchant's window read in it
+	effective := merchant.WithUserOverrides(&UserNotificationPreferences{Timezone: &tokyo})
+	until, quiet := effective.QuietUntil(now)
+	if !quiet {
+		t.Fatal("not quiet at 23:00 in the user's zone")
+	}
+	if want := time.Date(2026, 6, 10, 22, 0, 0, 0, time.UTC); !until.Equal(want) {
+		t.Errorf("until = %v, want %v", until.UTC(), want)
+	}
+
+	// A user window replaces the merchant's and is read in the user's zone too
+	start, end := "08:00", "09:00"
+	effective = merchant.WithUserOverrides(&UserNotificationPreferences{Timezone: &tokyo, QuietHoursStart: &start, QuietHoursEnd: &end})
+	if _, quiet := effective.QuietUntil(now); quiet {
+		t.Error("user window 08:00-09:00 is quiet at 23:00")
+	}
+}
+
+func TestShouldSendLayering(t *testing.T) {
+	merchant := &NotificationPreferences{Matrix: PreferenceMatrix{}}
+	merchant.Matrix.Set(ChannelTransaction, TypeSMS, true)
+	merchant.Matrix.Set(ChannelPayout, TypeEmail, false)
+	merchant.Matrix.Set(ChannelSecurity, TypeEmail, false)
+
+	user := &UserNotificationPreferences{Matrix: PreferenceMatrix{}}
+	user.Matrix.Set(ChannelTransaction, TypeSMS, false)
+	user.Matrix.Set(ChannelMarketing, TypeEmail, true)
+	user.Matrix.Set(ChannelSystem, TypePush, false)
+
+	effective := merchant.WithUserOverrides(user)
+
+	tests := []struct {
+		name      string
+		channel   NotificationChannel
+		notifType NotificationType
+		want      bool
+	}{
+		{"user override beats merchant rule", ChannelTransaction, TypeSMS, false},
+		{"user override beats default", ChannelMarketing, TypeEmail, true},
+		{"unset user rule inherits merchant rule", ChannelPayout, TypeEmail, false},
+		{"unset in both layers uses default", ChannelSettlement, TypeEmail, true},
+		{"default off for sms", ChannelSettlement, TypeSMS, false},
+		{"mandatory category ignores merchant", ChannelSecurity, TypeEmail, true},
+		{"mandatory category ignores user", ChannelSystem, TypePush, true},
+		{"unknown delivery type", ChannelTransaction, NotificationType("fax"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := effective.ShouldSend(tt.notifType, tt.channel); got != tt.want {
+				t.Errorf("ShouldSend(%s, %s) = %v, want %v", tt.notifType, tt.channel, got, tt.want)
+			}
+		})
+	}
+
+	// Layering works on a copy; the merchant's own preferences are unchanged
+	if !merchant.ShouldSend(TypeSMS, ChannelTransaction) {
+		t.Error("user override leaked into the merchant's matrix")
+	}
+	if merchant.WithUserOverrides(nil).ShouldSend(TypeEmail, ChannelPayout) {
+		t.Error("no user overrides: merchant rule not applied")
+	}
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -19,6 +20,28 @@ const (
 
 	DeliveryEmail OTPDeliveryMethod = "email"
 	DeliverySMS   OTPDeliveryMethod = "sms"
+	DeliveryVoice OTPDeliveryMethod = "voice" // Code is read out in a phone call, falling back to SMS
+	DeliveryTOTP  OTPDeliveryMethod = "totp" // Code comes from the user's authenticator app
+)
+
+// Code alphabets. The alphanumeric one leaves out characters that are easily
+// confused when read or typed: 0/O and 1/I/L.
+const (
+	AlphabetNumeric      = "0123456789"
+	AlphabetAlphanumeric = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+)
+
+// Purposes lists every supported OTP purpose
+var Purposes = []OTPPurpose{PurposePayout, PurposeWithdrawal, PurposeSettingsChange, PurposeLogin, Purpose2FA}
+
+// Verification failures, distinguished so callers can tell the user what went wrong
+var (
+	ErrOTPAlreadyVerified  = errors.New("OTP already verified")
+	ErrOTPExpired          = errors.New("OTP has expired")
+	ErrOTPAttemptsExceeded = errors.New("maximum verification attempts exceeded")
+	ErrOTPInvalidCode      = errors.New("invalid OTP code")
+	ErrOTPInvalidLink      = errors.New("invalid verification link")
+	ErrOTPInvalidProof     = errors.New("invalid or expired verification proof")
 )
 
 type OTP struct {
@@ -26,35 +49,57 @@ type OTP struct {
 	MerchantID     string                 `json:"merchant_id" db:"merchant_id"`
 	UserID         *string                `json:"user_id,omitempty" db:"user_id"`
 	Purpose        OTPPurpose             `json:"purpose" db:"purpose"`
-	Code           string                 `json:"code" db:"code"`
+	Code           string                 `json:"-" db:"code"`   // HMAC digest; plaintext on rows without a KeyID
+	KeyID          *string                `json:"-" db:"key_id"` // Pepper used to hash Code
 	Recipient      string                 `json:"recipient" db:"recipient"`
 	DeliveryMethod OTPDeliveryMethod      `json:"delivery_method" db:"delivery_method"`
 	ExpiresAt      time.Time              `json:"expires_at" db:"expires_at"`
 	VerifiedAt     *time.Time             `json:"verified_at,omitempty" db:"verified_at"`
+	LockedAt       *time.Time             `json:"locked_at,omitempty" db:"locked_at"`
 	Attempts       int                    `json:"attempts" db:"attempts"`
 	MaxAttempts    int                    `json:"max_attempts" db:"max_attempts"`
 	ReferenceID    *string                `json:"reference_id,omitempty" db:"reference_id"`
+	Locale         *string                `json:"locale,omitempty" db:"locale"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
 	CreatedAt      time.Time              `json:"created_at" db:"created_at"`
 }
 
-// GenerateCode generates a random 6-digit OTP code
+// IsValidPurpose reports whether p is a supported OTP purpose
+func IsValidPurpose(p OTPPurpose) bool {
+	for _, purpose := range Purposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidDeliveryMethod reports whether m is a supported OTP delivery method
+func IsValidDeliveryMethod(m OTPDeliveryMethod) bool {
+	return m == DeliveryEmail || m == DeliverySMS || m == DeliveryVoice || m == DeliveryTOTP
+}
+
+// GenerateCode generates a random numeric OTP code, 6 digits by default
 func GenerateCode(length int) (string, error) {
+	return GenerateCodeFrom(AlphabetNumeric, length)
+}
+
+// GenerateCodeFrom generates a random OTP code of the given length from alphabet
+func GenerateCodeFrom(alphabet string, length int) (string, error) {
 	if length <= 0 {
 		length = 6
 	}
 
-	// Generate random digits
-	digits := ""
-	for i := 0; i < length; i++ {
-		n, err := rand.Int(rand.Reader, big.NewInt(10))
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
 		if err != nil {
 			return "", fmt.Errorf("failed to generate random number: %w", err)
 		}
-		digits += fmt.Sprintf("%d", n)
+		code[i] = alphabet[n.Int64()]
 	}
 
-	return digits, nil
+	return string(code), nil
 }
 
 // IsExpired checks if the OTP has expired
@@ -67,33 +112,30 @@ func (o *OTP) IsVerified() bool {
 	return o.VerifiedAt != nil
 }
 
+// IsLocked checks if the OTP was locked after exhausting its attempts
+func (o *OTP) IsLocked() bool {
+	return o.LockedAt != nil
+}
+
 // CanAttempt checks if more verification attempts are allowed
 func (o *OTP) CanAttempt() bool {
-	return o.Attempts < o.MaxAttempts
+	return !o.IsLocked() && o.Attempts < o.MaxAttempts
 }
 
-// Verify validates the provided code against the OTP
-func (o *OTP) Verify(code string) error {
+// CheckVerifiable returns why the OTP can no longer be verified, or nil if it can
+func (o *OTP) CheckVerifiable() error {
 	if o.IsVerified() {
-		return fmt.Errorf("OTP already verified")
-	}
-
-	if o.IsExpired() {
-		return fmt.Errorf("OTP has expired")
+		return ErrOTPAlreadyVerified
 	}
 
 	if !o.CanAttempt() {
-		return fmt.Errorf("maximum verification attempts exceeded")
+		return ErrOTPAttemptsExceeded
 	}
 
-	o.Attempts++
-
-	if o.Code != code {
-		return fmt.Errorf("invalid OTP code")
+	if o.IsExpired() {
+		return ErrOTPExpired
 	}
 
-	now := time.Now()
-	o.VerifiedAt = &now
 	return nil
 }
 
@@ -104,16 +146,20 @@ type CreateOTPRequest struct {
 	Purpose         OTPPurpose             `json:"purpose"`
 	Recipient       string                 `json:"recipient"`
 	DeliveryMethod  OTPDeliveryMethod      `json:"delivery_method"`
-	ExpiryMinutes   int                    `json:"expiry_minutes"` // Default: 10 minutes
-	MaxAttempts     int                    `json:"max_attempts"`   // Default: 3
+	ExpiryMinutes   int                    `json:"expiry_minutes"` // Default and maximum: the purpose's policy
+	MaxAttempts     int                    `json:"max_attempts"`   // Default and maximum: the purpose's policy
 	ReferenceID     *string                `json:"reference_id,omitempty"`
+	Locale          *string                `json:"locale,omitempty"` // Template locale, e.g. fr-CI
+	MagicLink       bool                   `json:"magic_link"`       // Also email a single-use verification link
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// VerifyOTPRequest represents a request to verify an OTP
+// VerifyOTPRequest represents a request to verify an OTP, identified by the OTP ID
+// returned from Generate or by its reference ID
 type VerifyOTPRequest struct {
 	MerchantID  string     `json:"merchant_id"`
 	Purpose     OTPPurpose `json:"purpose"`
 	Code        string     `json:"code"`
+	OTPID       *string    `json:"otp_id,omitempty"`
 	ReferenceID *string    `json:"reference_id,omitempty"`
 }
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationTemplate is a managed template variant for one locale, with an editable draft and a published version pointer
+type NotificationTemplate struct {
+	ID                string    `json:"id" db:"id"`
+	Name              string    `json:"name" db:"name"`
+	Locale            string    `json:"locale" db:"locale"`
+	Description       *string   `json:"description,omitempty" db:"description"`
+	DraftSubject      string    `json:"draft_subject" db:"draft_subject"`
+	DraftText         string    `json:"draft_text" db:"draft_text"`
+	DraftHTML         string    `json:"draft_html" db:"draft_html"`
+	DraftRequiredVars []string  `json:"draft_required_vars" db:"draft_required_vars"`
+	PublishedVersion  *int      `json:"published_version,omitempty" db:"published_version"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TemplateVersion is an immutable snapshot of a template variant's draft
+type TemplateVersion struct {
+	ID           string    `json:"id" db:"id"`
+	TemplateID   string    `json:"template_id" db:"template_id"`
+	Name         string    `json:"name" db:"name"`
+	Locale       string    `json:"locale" db:"locale"`
+	Version      int       `json:"version" db:"version"`
+	Subject      string    `json:"subject" db:"subject"`
+	TextBody     string    `json:"text_body" db:"text_body"`
+	HTMLBody     string    `json:"html_body" db:"html_body"`
+	RequiredVars []string  `json:"required_vars" db:"required_vars"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
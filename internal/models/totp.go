@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// TOTP enrollment failures
+var (
+	ErrTOTPNotEnrolled     = errors.New("no confirmed authenticator app enrollment")
+	ErrTOTPAlreadyEnrolled = errors.New("authenticator app already enrolled")
+	ErrTOTPCodeReused      = errors.New("authenticator code has already been used")
+)
+
+// TOTPEnrollment is a user's authenticator app registration. It only accepts
+// codes for verification once confirmed.
+type TOTPEnrollment struct {
+	ID           string     `json:"id" db:"id"`
+	MerchantID   string     `json:"merchant_id" db:"merchant_id"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	AccountName  string     `json:"account_name" db:"account_name"`
+	Secret       []byte     `json:"-" db:"secret"`         // AES-GCM ciphertext of the shared secret
+	KeyID        string     `json:"-" db:"key_id"`         // Key used to encrypt Secret
+	LastUsedStep int64      `json:"-" db:"last_used_step"` // Latest accepted time step, for replay protection
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsConfirmed checks if the user has proven possession of the secret
+func (e *TOTPEnrollment) IsConfirmed() bool {
+	return e.ConfirmedAt != nil
+}
+
+// EnrollTOTPRequest starts an authenticator app enrollment
+type EnrollTOTPRequest struct {
+	MerchantID  string  `json:"merchant_id"`
+	UserID      string  `json:"user_id"`
+	AccountName *string `json:"account_name,omitempty"` // Label shown in the app. Default: user_id
+}
+
+// TOTPCodeRequest carries a code from the user's authenticator app, used to
+// confirm an enrollment or regenerate recovery codes
+type TOTPCodeRequest struct {
+	MerchantID string `json:"merchant_id"`
+	UserID     string `json:"user_id"`
+	Code       string `json:"code"`
+}
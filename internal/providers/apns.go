@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// apnsUnregisteredReasons are APNs rejection reasons that mean the token should be pruned
+var apnsUnregisteredReasons = map[string]bool{
+	"BadDeviceToken":         true,
+	"DeviceTokenNotForTopic": true,
+	"Unregistered":           true,
+}
+
+// APNsSender speaks the Apple Push Notification service HTTP/2 API using token-based authentication
+type APNsSender struct {
+	cfg    config.APNsConfig
+	client *http.Client
+	key    crypto.Signer
+
+	mu        sync.Mutex
+	authToken string
+	issuedAt  time.Time
+}
+
+func NewAPNsSender(cfg config.APNsConfig, client *http.Client) (*APNsSender, error) {
+	if cfg.TeamID == "" || cfg.BundleID == "" || cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("apns team id, bundle id and private key file are required")
+	}
+
+	raw, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read apns private key: %w", err)
+	}
+
+	key, err := parsePrivateKeyPEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse apns private key: %w", err)
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	return &APNsSender{cfg: cfg, client: client, key: key}, nil
+}
+
+// SendPush sends an alert to one device token and returns the apns-id
+func (s *APNsSender) SendPush(ctx context.Context, msg *PushMessage) (string, error) {
+	authToken, err := s.getAuthToken()
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		if k != "aps" {
+			body[k] = v
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("encode apns request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/3/device/%s", s.cfg.BaseURL, url.PathEscape(msg.Token))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("build apns request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+authToken)
+	req.Header.Set("apns-topic", s.cfg.BundleID)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("apns request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp struct {
+			Reason string `json:"reason"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+
+		provErr := &ProviderError{
+			Provider:   "apns",
+			StatusCode: resp.StatusCode,
+			Code:       errResp.Reason,
+			Message:    http.StatusText(resp.StatusCode),
+		}
+		if resp.StatusCode == http.StatusGone || apnsUnregisteredReasons[errResp.Reason] {
+			provErr.Err = ErrUnregisteredToken
+		}
+		return "", provErr
+	}
+
+	return resp.Header.Get("apns-id"), nil
+}
+
+// getAuthToken returns the provider JWT, refreshing it before APNs' one hour limit
+func (s *APNsSender) getAuthToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.authToken != "" && time.Since(s.issuedAt) < 50*time.Minute {
+		return s.authToken, nil
+	}
+
+	now := time.Now()
+	token, err := signJWT(
+		map[string]interface{}{"alg": "ES256", "kid": s.cfg.KeyID},
+		map[string]interface{}{"iss": s.cfg.TeamID, "iat": now.Unix()},
+		s.key,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	s.authToken = token
+	s.issuedAt = now
+	return token, nil
+}
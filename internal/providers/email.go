@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// EmailMessage is a provider-agnostic outbound email
+type EmailMessage struct {
+	From     string
+	ReplyTo  string
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+	Headers  map[string]string
+}
+
+// EmailSender delivers email messages and returns the provider message ID
+type EmailSender interface {
+	SendEmail(ctx context.Context, msg *EmailMessage) (string, error)
+}
+
+// NewEmailSender builds the email sender selected by cfg.EmailProvider
+func NewEmailSender(cfg config.Config) (EmailSender, error) {
+	switch cfg.EmailProvider {
+	case "smtp":
+		return NewSMTPSender(cfg.SMTP)
+	case "log", "":
+		return &LogEmailSender{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported email provider: %s", cfg.EmailProvider)
+	}
+}
+
+// LogEmailSender writes emails to the service log instead of delivering them
+type LogEmailSender struct{}
+
+func (s *LogEmailSender) SendEmail(ctx context.Context, msg *EmailMessage) (string, error) {
+	log.Printf("[EMAIL] To: %v", msg.To)
+	log.Printf("[EMAIL] Subject: %s", msg.Subject)
+	log.Printf("[EMAIL] Message: %s", msg.TextBody)
+	return "", nil
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+)
+
+// ErrUnregisteredToken indicates the push provider no longer accepts a device token
+var ErrUnregisteredToken = errors.New("device token is no longer registered")
+
+// ErrInvalidCallbackSignature indicates a provider callback failed authentication
+var ErrInvalidCallbackSignature = errors.New("invalid callback signature")
+
+// ErrCallbackNotConfigured indicates callbacks arrived for a provider without a shared secret
+var ErrCallbackNotConfigured = errors.New("callback secret is not configured")
+
+// ProviderError is a failure reported by an upstream delivery provider
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Code       string
+	Message    string
+	// Err optionally classifies the failure, e.g. ErrUnregisteredToken
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s error %s: %s", e.Provider, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s error (HTTP %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError marks a failure that will not succeed on retry, such as an invalid recipient
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so that IsRetryable reports false
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsRetryable reports whether a delivery error is transient. Unknown errors are
+// treated as transient so that a flaky dependency does not lose notifications.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var permErr *PermanentError
+	if errors.As(err, &permErr) {
+		return false
+	}
+
+	if errors.Is(err, ErrUnregisteredToken) {
+		return false
+	}
+
+	var provErr *ProviderError
+	if errors.As(err, &provErr) {
+		switch {
+		case provErr.StatusCode == http.StatusTooManyRequests,
+			provErr.StatusCode == http.StatusRequestTimeout,
+			provErr.StatusCode >= 500:
+			return true
+		case provErr.StatusCode >= 400:
+			return false
+		}
+		return true
+	}
+
+	// SMTP replies: 4xx are transient, 5xx are permanent
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code < 500
+	}
+
+	// Network failures, timeouts and anything unclassified
+	return true
+}
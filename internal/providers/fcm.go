@@ -0,0 +1,225 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMSender speaks the Firebase Cloud Messaging HTTP v1 API using a service account
+type FCMSender struct {
+	cfg         config.FCMConfig
+	client      *http.Client
+	clientEmail string
+	tokenURL    string
+	key         crypto.Signer
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+type fcmServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+func NewFCMSender(cfg config.FCMConfig, client *http.Client) (*FCMSender, error) {
+	if cfg.CredentialsFile == "" {
+		return nil, fmt.Errorf("fcm credentials file is required")
+	}
+
+	raw, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read fcm credentials: %w", err)
+	}
+
+	var account fcmServiceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("parse fcm credentials: %w", err)
+	}
+
+	key, err := parsePrivateKeyPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse fcm private key: %w", err)
+	}
+
+	tokenURL := cfg.TokenURL
+	if tokenURL == "" {
+		tokenURL = account.TokenURI
+	}
+	if tokenURL == "" {
+		tokenURL = "https://oauth2.googleapis.com/token"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	return &FCMSender{
+		cfg:         cfg,
+		client:      client,
+		clientEmail: account.ClientEmail,
+		tokenURL:    tokenURL,
+		key:         key,
+	}, nil
+}
+
+type fcmSendRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body"`
+}
+
+type fcmErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type      string `json:"@type"`
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// SendPush sends a message to one registration token and returns the FCM message name
+func (s *FCMSender) SendPush(ctx context.Context, msg *PushMessage) (string, error) {
+	accessToken, err := s.getAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(fcmSendRequest{Message: fcmMessage{
+		Token:        msg.Token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	}})
+	if err != nil {
+		return "", fmt.Errorf("encode fcm request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", s.cfg.BaseURL, url.PathEscape(s.cfg.ProjectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("build fcm request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp fcmErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+
+		provErr := &ProviderError{
+			Provider:   "fcm",
+			StatusCode: resp.StatusCode,
+			Code:       errResp.Error.Status,
+			Message:    errResp.Error.Message,
+		}
+		for _, d := range errResp.Error.Details {
+			if d.ErrorCode != "" {
+				provErr.Code = d.ErrorCode
+			}
+		}
+		if provErr.Code == "UNREGISTERED" || (provErr.Code == "INVALID_ARGUMENT" && strings.Contains(provErr.Message, "registration token")) {
+			provErr.Err = ErrUnregisteredToken
+		}
+		if provErr.Message == "" {
+			provErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return "", provErr
+	}
+
+	var sendResp struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sendResp); err != nil {
+		return "", fmt.Errorf("decode fcm response: %w", err)
+	}
+
+	return sendResp.Name, nil
+}
+
+// getAccessToken exchanges a signed service account assertion for an OAuth2 access token, caching it until shortly before expiry
+func (s *FCMSender) getAccessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]interface{}{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{
+			"iss":   s.clientEmail,
+			"scope": fcmScope,
+			"aud":   s.tokenURL,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		s.key,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("build fcm token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", &ProviderError{Provider: "fcm", StatusCode: resp.StatusCode, Message: "failed to obtain access token"}
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("decode fcm token response: %w", err)
+	}
+
+	s.accessToken = tokenResp.AccessToken
+	s.expiresAt = now.Add(time.Duration(tokenResp.ExpiresIn)*time.Second - time.Minute)
+	return s.accessToken, nil
+}
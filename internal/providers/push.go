@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// PushMessage is a provider-agnostic push notification for a single device token
+type PushMessage struct {
+	Platform models.DevicePlatform
+	Token    string
+	Title    string
+	Body     string
+	Data     map[string]string
+}
+
+// PushSender delivers push notifications and returns the provider message ID.
+// Tokens the provider rejects as stale are reported with ErrUnregisteredToken.
+type PushSender interface {
+	SendPush(ctx context.Context, msg *PushMessage) (string, error)
+}
+
+// NewPushSender builds the push sender selected by cfg.PushProvider
+func NewPushSender(cfg config.Config) (PushSender, error) {
+	switch cfg.PushProvider {
+	case "native":
+		client := &http.Client{Timeout: cfg.PushTimeout}
+		router := &PlatformPushRouter{senders: map[models.DevicePlatform]PushSender{}}
+
+		if cfg.FCM.ProjectID != "" {
+			fcm, err := NewFCMSender(cfg.FCM, client)
+			if err != nil {
+				return nil, err
+			}
+			// Web tokens are issued by the Firebase JS SDK and delivered through FCM
+			router.senders[models.PlatformFCM] = fcm
+			router.senders[models.PlatformWeb] = fcm
+		}
+
+		if cfg.APNs.KeyID != "" {
+			apns, err := NewAPNsSender(cfg.APNs, client)
+			if err != nil {
+				return nil, err
+			}
+			router.senders[models.PlatformAPNs] = apns
+		}
+
+		if len(router.senders) == 0 {
+			return nil, fmt.Errorf("native push provider requires FCM or APNs configuration")
+		}
+		return router, nil
+	case "log", "":
+		return &LogPushSender{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported push provider: %s", cfg.PushProvider)
+	}
+}
+
+// PlatformPushRouter dispatches each message to the sender for its device platform
+type PlatformPushRouter struct {
+	senders map[models.DevicePlatform]PushSender
+}
+
+func (r *PlatformPushRouter) SendPush(ctx context.Context, msg *PushMessage) (string, error) {
+	sender, ok := r.senders[msg.Platform]
+	if !ok {
+		return "", fmt.Errorf("no push provider configured for platform: %s", msg.Platform)
+	}
+	return sender.SendPush(ctx, msg)
+}
+
+// LogPushSender writes push notifications to the service log instead of delivering them
+type LogPushSender struct{}
+
+func (s *LogPushSender) SendPush(ctx context.Context, msg *PushMessage) (string, error) {
+	log.Printf("[PUSH] To: %s device token %s", msg.Platform, msg.Token)
+	log.Printf("[PUSH] Title: %s", msg.Title)
+	log.Printf("[PUSH] Message: %s", msg.Body)
+	return "", nil
+}
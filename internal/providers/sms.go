@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// SMSMessage is a provider-agnostic outbound text message
+type SMSMessage struct {
+	To   string
+	Body string
+}
+
+// SMSSender delivers text messages and returns the provider message ID
+type SMSSender interface {
+	SendSMS(ctx context.Context, msg *SMSMessage) (string, error)
+}
+
+// NewSMSSender builds the SMS sender selected by cfg.SMSProvider
+func NewSMSSender(cfg config.Config) (SMSSender, error) {
+	client := &http.Client{Timeout: cfg.SMSTimeout}
+
+	switch cfg.SMSProvider {
+	case "twilio":
+		return NewTwilioSender(cfg.Twilio, client)
+	case "termii":
+		return NewTermiiSender(cfg.Termii, client)
+	case "log", "":
+		return &LogSMSSender{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported sms provider: %s", cfg.SMSProvider)
+	}
+}
+
+// LogSMSSender writes text messages to the service log instead of delivering them
+type LogSMSSender struct{}
+
+func (s *LogSMSSender) SendSMS(ctx context.Context, msg *SMSMessage) (string, error) {
+	log.Printf("[SMS] To: %s", msg.To)
+	log.Printf("[SMS] Message: %s", msg.Body)
+	return "", nil
+}
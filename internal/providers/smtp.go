@@ -0,0 +1,327 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// SMTPSender delivers email through an SMTP relay
+type SMTPSender struct {
+	cfg  config.SMTPConfig
+	from *mail.Address
+	// rootCAs verifies the relay's certificate; nil uses the system roots
+	rootCAs *x509.CertPool
+}
+
+func NewSMTPSender(cfg config.SMTPConfig) (*SMTPSender, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+
+	switch cfg.TLSMode {
+	case "starttls", "implicit", "none":
+	default:
+		return nil, fmt.Errorf("unsupported smtp tls mode: %s", cfg.TLSMode)
+	}
+
+	switch cfg.AuthMechanism {
+	case "plain", "login", "none":
+	default:
+		return nil, fmt.Errorf("unsupported smtp auth mechanism: %s", cfg.AuthMechanism)
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp from address: %w", err)
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+
+	return &SMTPSender{cfg: cfg, from: from}, nil
+}
+
+// SendEmail delivers the message and returns its Message-ID
+func (s *SMTPSender) SendEmail(ctx context.Context, msg *EmailMessage) (string, error) {
+	if len(msg.To) == 0 {
+		return "", Permanent(fmt.Errorf("email has no recipients"))
+	}
+
+	from := s.from
+	if msg.From != "" {
+		addr, err := mail.ParseAddress(msg.From)
+		if err != nil {
+			return "", Permanent(fmt.Errorf("invalid from address: %w", err))
+		}
+		from = addr
+	}
+
+	messageID := newMessageID(from.Address)
+	body, err := s.buildMessage(from, msg, messageID)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	if err := s.authenticate(client); err != nil {
+		return "", err
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return "", fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return "", Permanent(fmt.Errorf("invalid recipient %q: %w", to, err))
+		}
+		if err := client.Rcpt(addr.Address); err != nil {
+			return "", fmt.Errorf("smtp RCPT TO: %w", err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return "", fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return "", fmt.Errorf("smtp write body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp end of data: %w", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		return "", fmt.Errorf("smtp QUIT: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// dial connects to the relay and negotiates TLS according to the configured mode
+func (s *SMTPSender) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12, RootCAs: s.rootCAs}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.TLSMode == "implicit" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smtp dial %s: %w", addr, err)
+	}
+
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp handshake: %w", err)
+	}
+
+	if s.cfg.TLSMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp STARTTLS: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+func (s *SMTPSender) authenticate(client *smtp.Client) error {
+	if s.cfg.AuthMechanism == "none" || s.cfg.Username == "" {
+		return nil
+	}
+
+	if ok, _ := client.Extension("AUTH"); !ok {
+		return fmt.Errorf("smtp server does not support AUTH")
+	}
+
+	var auth smtp.Auth
+	switch s.cfg.AuthMechanism {
+	case "login":
+		auth = &loginAuth{username: s.cfg.Username, password: s.cfg.Password, host: s.cfg.Host}
+	default:
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp AUTH: %w", err)
+	}
+	return nil
+}
+
+// buildMessage renders the RFC 5322 message, using multipart/alternative when an HTML body is present
+func (s *SMTPSender) buildMessage(from *mail.Address, msg *EmailMessage, messageID string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	headers := map[string]string{
+		"From":         from.String(),
+		"To":           strings.Join(msg.To, ", "),
+		"Subject":      mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   messageID,
+		"MIME-Version": "1.0",
+	}
+
+	replyTo := s.cfg.ReplyTo
+	if msg.ReplyTo != "" {
+		replyTo = msg.ReplyTo
+	}
+	if replyTo != "" {
+		headers["Reply-To"] = replyTo
+	}
+
+	for k, v := range msg.Headers {
+		headers[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+
+	if msg.HTMLBody == "" {
+		headers["Content-Type"] = "text/plain; charset=utf-8"
+		headers["Content-Transfer-Encoding"] = "quoted-printable"
+		writeHeaders(&buf, headers)
+		if err := writeQuotedPrintable(&buf, msg.TextBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	headers["Content-Type"] = "multipart/alternative; boundary=" + mw.Boundary()
+	writeHeaders(&buf, headers)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.TextBody},
+		{"text/html; charset=utf-8", msg.HTMLBody},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create mime part: %w", err)
+		}
+		if err := writeQuotedPrintable(pw, p.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("close multipart: %w", err)
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeHeaders(buf *bytes.Buffer, headers map[string]string) {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s: %s\r\n", k, headers[k])
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return fmt.Errorf("encode body: %w", err)
+	}
+	return qp.Close()
+}
+
+func newMessageID(fromAddress string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 {
+		domain = fromAddress[at+1:]
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
+
+// loginAuth implements the non-standard but widely deployed AUTH LOGIN mechanism
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Match smtp.PlainAuth: never send credentials in the clear except to localhost
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	prompt := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected AUTH LOGIN challenge: %s", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,406 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// fakeSMTPServer is a minimal in-process SMTP relay that records what it receives
+type fakeSMTPServer struct {
+	listener net.Listener
+	tls      *tls.Config
+	starttls bool
+
+	mu       sync.Mutex
+	authMech string
+	username string
+	password string
+	sawTLS   bool
+	from     string
+	rcpts    []string
+	data     string
+}
+
+// newFakeSMTPServer starts a relay on a local port. tlsMode is "implicit",
+// "starttls" or "none"; the returned pool trusts the relay's certificate.
+func newFakeSMTPServer(t *testing.T, tlsMode string) (*fakeSMTPServer, *x509.CertPool) {
+	t.Helper()
+
+	cert, pool := selfSignedCert(t)
+	srv := &fakeSMTPServer{
+		tls:      &tls.Config{Certificates: []tls.Certificate{cert}},
+		starttls: tlsMode == "starttls",
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	if tlsMode == "implicit" {
+		l = tls.NewListener(l, srv.tls)
+	}
+	srv.listener = l
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+
+	return srv, pool
+}
+
+func (s *fakeSMTPServer) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	_, secure := conn.(*tls.Conn)
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake.test ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"250-fake.test"}
+			if s.starttls && !secure {
+				lines = append(lines, "250-STARTTLS")
+			}
+			if secure || !s.starttls {
+				lines = append(lines, "250-AUTH PLAIN LOGIN")
+			}
+			lines = append(lines, "250 8BITMIME")
+			for _, l := range lines {
+				tp.PrintfLine("%s", l)
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			if !s.auth(tp, arg) {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			s.mu.Lock()
+			s.sawTLS = secure
+			s.mu.Unlock()
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			s.mu.Lock()
+			// Drop ESMTP parameters such as BODY=8BITMIME
+			path, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			s.from = strings.Trim(path, "<>")
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = string(data)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// auth handles AUTH PLAIN and AUTH LOGIN, recording the credentials presented
+func (s *fakeSMTPServer) auth(tp *textproto.Conn, arg string) bool {
+	mech, initial, _ := strings.Cut(arg, " ")
+	decode := func(v string) string {
+		b, _ := base64.StdEncoding.DecodeString(v)
+		return string(b)
+	}
+
+	var username, password string
+	switch strings.ToUpper(mech) {
+	case "PLAIN":
+		parts := strings.Split(decode(initial), "\x00")
+		if len(parts) != 3 {
+			return false
+		}
+		username, password = parts[1], parts[2]
+	case "LOGIN":
+		tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Username:")))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return false
+		}
+		username = decode(line)
+		tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Password:")))
+		if line, err = tp.ReadLine(); err != nil {
+			return false
+		}
+		password = decode(line)
+	default:
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authMech, s.username, s.password = strings.ToUpper(mech), username, password
+	return true
+}
+
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake.test"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func newTestSMTPSender(t *testing.T, srv *fakeSMTPServer, pool *x509.CertPool, tlsMode, authMech string) *SMTPSender {
+	t.Helper()
+
+	sender, err := NewSMTPSender(config.SMTPConfig{
+		Host:          "127.0.0.1",
+		Port:          srv.port(),
+		Username:      "mailer",
+		Password:      "s3cret",
+		AuthMechanism: authMech,
+		TLSMode:       tlsMode,
+		From:          "KodraPay <no-reply@kodrapay.test>",
+		ReplyTo:       "support@kodrapay.test",
+		Timeout:       5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+	sender.rootCAs = pool
+	return sender
+}
+
+func TestSMTPSenderTLSAndAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		tlsMode  string
+		authMech string
+		wantMech string
+	}{
+		{"starttls with plain", "starttls", "plain", "PLAIN"},
+		{"starttls with login", "starttls", "login", "LOGIN"},
+		{"implicit tls with plain", "implicit", "plain", "PLAIN"},
+		{"implicit tls with login", "implicit", "login", "LOGIN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, pool := newFakeSMTPServer(t, tt.tlsMode)
+			sender := newTestSMTPSender(t, srv, pool, tt.tlsMode, tt.authMech)
+
+			messageID, err := sender.SendEmail(context.Background(), &EmailMessage{
+				To:       []string{"merchant@example.com"},
+				Subject:  "Payout sent",
+				TextBody: "Your payout is on its way.",
+			})
+			if err != nil {
+				t.Fatalf("SendEmail: %v", err)
+			}
+			if !strings.HasSuffix(messageID, "@kodrapay.test>") {
+				t.Errorf("message ID = %q, want one at the sender's domain", messageID)
+			}
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if srv.authMech != tt.wantMech {
+				t.Errorf("auth mechanism = %q, want %q", srv.authMech, tt.wantMech)
+			}
+			if srv.username != "mailer" || srv.password != "s3cret" {
+				t.Errorf("credentials = %q/%q, want mailer/s3cret", srv.username, srv.password)
+			}
+			if !srv.sawTLS {
+				t.Error("credentials were sent before TLS was negotiated")
+			}
+			if srv.from != "no-reply@kodrapay.test" {
+				t.Errorf("MAIL FROM = %q", srv.from)
+			}
+			if len(srv.rcpts) != 1 || srv.rcpts[0] != "merchant@example.com" {
+				t.Errorf("RCPT TO = %v", srv.rcpts)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderRejectsUntrustedCertificate(t *testing.T) {
+	srv, _ := newFakeSMTPServer(t, "implicit")
+	sender := newTestSMTPSender(t, srv, x509.NewCertPool(), "implicit", "plain")
+
+	_, err := sender.SendEmail(context.Background(), &EmailMessage{
+		To:       []string{"merchant@example.com"},
+		TextBody: "hello",
+	})
+	if err == nil {
+		t.Fatal("SendEmail succeeded against an untrusted certificate")
+	}
+}
+
+func TestSMTPSenderMultipartBodyAndReplyTo(t *testing.T) {
+	srv, pool := newFakeSMTPServer(t, "starttls")
+	sender := newTestSMTPSender(t, srv, pool, "starttls", "plain")
+
+	_, err := sender.SendEmail(context.Background(), &EmailMessage{
+		To:       []string{"merchant@example.com"},
+		ReplyTo:  "payouts@kodrapay.test",
+		Subject:  "Règlement effectué",
+		TextBody: "Plain body with a long line that the quoted-printable encoder must wrap because it goes on and on.",
+		HTMLBody: "<p>HTML body</p>",
+		Headers:  map[string]string{"list-unsubscribe": "<https://example.com/u>"},
+	})
+	if err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+
+	srv.mu.Lock()
+	data := srv.data
+	srv.mu.Unlock()
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if got := msg.Header.Get("Reply-To"); got != "payouts@kodrapay.test" {
+		t.Errorf("Reply-To = %q, want the message's own reply-to", got)
+	}
+	if got := msg.Header.Get("List-Unsubscribe"); got != "<https://example.com/u>" {
+		t.Errorf("List-Unsubscribe = %q, want the extra header canonicalised", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Règlement effectué" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Plain body with a long line that the quoted-printable encoder must wrap because it goes on and on."},
+		{"text/html; charset=utf-8", "<p>HTML body</p>"},
+	}
+	for i, w := range want {
+		part, err := mr.NextRawPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("part %d Content-Type = %q, want %q", i, got, w.contentType)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if string(body) != w.body {
+			t.Errorf("part %d body = %q, want %q", i, body, w.body)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly two parts, got %v", err)
+	}
+}
+
+func TestSMTPSenderDefaultReplyTo(t *testing.T) {
+	srv, pool := newFakeSMTPServer(t, "implicit")
+	sender := newTestSMTPSender(t, srv, pool, "implicit", "plain")
+
+	if _, err := sender.SendEmail(context.Background(), &EmailMessage{
+		To:       []string{"merchant@example.com"},
+		TextBody: "plain only",
+	}); err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+
+	srv.mu.Lock()
+	data := srv.data
+	srv.mu.Unlock()
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if got := msg.Header.Get("Reply-To"); got != "support@kodrapay.test" {
+		t.Errorf("Reply-To = %q, want the configured default", got)
+	}
+	if got := msg.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want a single text part", got)
+	}
+}
@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// termiiErrorMessages maps Termii error messages to stable codes and readable descriptions
+var termiiErrorMessages = map[string]struct {
+	code    string
+	message string
+}{
+	"invalid api key":               {"invalid_api_key", "authentication failed"},
+	"insufficient balance":          {"insufficient_balance", "insufficient SMS balance"},
+	"invalid phone number":          {"invalid_number", "invalid recipient phone number"},
+	"sender id not found":           {"invalid_sender", "sender ID is not registered"},
+	"applicationsenderid not found": {"invalid_sender", "sender ID is not registered"},
+	"country inactive":              {"country_inactive", "sending to this country is not enabled"},
+}
+
+// TermiiSender speaks the Termii messaging REST API
+type TermiiSender struct {
+	cfg    config.TermiiConfig
+	client *http.Client
+}
+
+func NewTermiiSender(cfg config.TermiiConfig, client *http.Client) (*TermiiSender, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("termii api key is required")
+	}
+	if cfg.SenderID == "" {
+		return nil, fmt.Errorf("termii sender id is required")
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = "generic"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	return &TermiiSender{cfg: cfg, client: client}, nil
+}
+
+type termiiSendRequest struct {
+	To      string `json:"to"`
+	From    string `json:"from"`
+	SMS     string `json:"sms"`
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	APIKey  string `json:"api_key"`
+}
+
+type termiiSendResponse struct {
+	Code      string `json:"code"`
+	MessageID string `json:"message_id"`
+	Message   string `json:"message"`
+}
+
+// SendSMS sends a plain message and returns the Termii message ID
+func (s *TermiiSender) SendSMS(ctx context.Context, msg *SMSMessage) (string, error) {
+	payload, err := json.Marshal(termiiSendRequest{
+		// Termii expects international format without the leading plus
+		To:      strings.TrimPrefix(msg.To, "+"),
+		From:    s.cfg.SenderID,
+		SMS:     msg.Body,
+		Type:    "plain",
+		Channel: s.cfg.Channel,
+		APIKey:  s.cfg.APIKey,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode termii request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/api/sms/send", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("build termii request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("termii request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var sendResp termiiSendResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&sendResp)
+
+	if resp.StatusCode >= 300 {
+		return "", termiiError(resp.StatusCode, sendResp.Message)
+	}
+	// A rejection in a successful response is final, except for a low balance,
+	// which clears once the account is topped up
+	if sendResp.Code != "" && sendResp.Code != "ok" {
+		provErr := termiiError(resp.StatusCode, sendResp.Message)
+		if provErr.Code == "insufficient_balance" {
+			return "", provErr
+		}
+		return "", Permanent(provErr)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("decode termii response: %w", decodeErr)
+	}
+	if sendResp.MessageID == "" {
+		return "", termiiError(resp.StatusCode, sendResp.Message)
+	}
+
+	return sendResp.MessageID, nil
+}
+
+func termiiError(statusCode int, message string) *ProviderError {
+	provErr := &ProviderError{Provider: "termii", StatusCode: statusCode, Message: message}
+	if known, ok := termiiErrorMessages[strings.ToLower(strings.TrimSpace(message))]; ok {
+		provErr.Code = known.code
+		provErr.Message = known.message
+	}
+	if provErr.Message == "" {
+		provErr.Message = http.StatusText(statusCode)
+	}
+	return provErr
+}
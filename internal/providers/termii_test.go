@@ -0,0 +1,125 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+func newTestTermiiSender(t *testing.T, handler http.HandlerFunc) *TermiiSender {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	sender, err := NewTermiiSender(config.TermiiConfig{
+		BaseURL:  srv.URL + "/",
+		APIKey:   "key",
+		SenderID: "KodraPay",
+	}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewTermiiSender: %v", err)
+	}
+	return sender
+}
+
+func TestTermiiSenderSuccess(t *testing.T) {
+	sender := newTestTermiiSender(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/sms/send" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		var req termiiSendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		want := termiiSendRequest{
+			To: "2348012345678", From: "KodraPay", SMS: "hello", Type: "plain", Channel: "generic", APIKey: "key",
+		}
+		if req != want {
+			t.Errorf("request = %+v, want %+v", req, want)
+		}
+		w.Write([]byte(`{"code":"ok","message_id":"3017544054459","message":"Successfully Sent","balance":9,"user":"Kodra"}`))
+	})
+
+	id, err := sender.SendSMS(context.Background(), &SMSMessage{To: "+2348012345678", Body: "hello"})
+	if err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	if id != "3017544054459" {
+		t.Errorf("message ID = %q", id)
+	}
+}
+
+func TestTermiiSenderErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantCode    string
+		wantMessage string
+		retryable   bool
+	}{
+		{
+			name:        "invalid number",
+			status:      http.StatusBadRequest,
+			body:        `{"message":"Invalid phone number"}`,
+			wantCode:    "invalid_number",
+			wantMessage: "invalid recipient phone number",
+		},
+		{
+			name:        "bad api key",
+			status:      http.StatusUnauthorized,
+			body:        `{"message":"Invalid API key"}`,
+			wantCode:    "invalid_api_key",
+			wantMessage: "authentication failed",
+		},
+		{
+			name:        "server error",
+			status:      http.StatusBadGateway,
+			body:        `<html>bad gateway</html>`,
+			wantMessage: "Bad Gateway",
+			retryable:   true,
+		},
+		{
+			name:        "rejected in a successful response",
+			status:      http.StatusOK,
+			body:        `{"code":"error","message":"Sender ID not found"}`,
+			wantCode:    "invalid_sender",
+			wantMessage: "sender ID is not registered",
+		},
+		{
+			name:        "low balance in a successful response",
+			status:      http.StatusOK,
+			body:        `{"code":"error","message":"Insufficient balance"}`,
+			wantCode:    "insufficient_balance",
+			wantMessage: "insufficient SMS balance",
+			retryable:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := newTestTermiiSender(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := sender.SendSMS(context.Background(), &SMSMessage{To: "+2348012345678", Body: "hi"})
+			var provErr *ProviderError
+			if !errors.As(err, &provErr) {
+				t.Fatalf("err = %v, want a ProviderError", err)
+			}
+			if provErr.Provider != "termii" || provErr.Code != tt.wantCode || provErr.Message != tt.wantMessage {
+				t.Errorf("error = %+v, want code %q message %q", provErr, tt.wantCode, tt.wantMessage)
+			}
+			if got := IsRetryable(err); got != tt.retryable {
+				t.Errorf("IsRetryable = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// HTTPVoiceSender speaks a generic text-to-speech call API: POST /v1/calls with
+// the script, authenticated with a bearer API key
+type HTTPVoiceSender struct {
+	cfg    config.VoiceConfig
+	client *http.Client
+}
+
+func NewHTTPVoiceSender(cfg config.VoiceConfig, client *http.Client) (*HTTPVoiceSender, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("voice provider base url is required")
+	}
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("voice provider api key is required")
+	}
+	if cfg.From == "" {
+		return nil, fmt.Errorf("voice caller id is required")
+	}
+	// Without the secret every status callback is refused and the SMS fallback never runs
+	if cfg.CallbackSecret == "" {
+		return nil, fmt.Errorf("voice callback secret is required (VOICE_CALLBACK_SECRET)")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	return &HTTPVoiceSender{cfg: cfg, client: client}, nil
+}
+
+type ttsCallRequest struct {
+	To                string `json:"to"`
+	From              string `json:"from"`
+	Text              string `json:"text"`
+	Language          string `json:"language,omitempty"`
+	Reference         string `json:"reference,omitempty"`
+	StatusCallbackURL string `json:"status_callback_url,omitempty"`
+}
+
+type ttsCallResponse struct {
+	CallID string `json:"call_id"`
+	Status string `json:"status"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// PlaceCall starts a call that reads the message aloud and returns the call ID
+func (s *HTTPVoiceSender) PlaceCall(ctx context.Context, msg *VoiceMessage) (string, error) {
+	payload, err := json.Marshal(ttsCallRequest{
+		To:                msg.To,
+		From:              s.cfg.From,
+		Text:              msg.Text,
+		Language:          msg.Language,
+		Reference:         msg.Reference,
+		StatusCallbackURL: s.cfg.StatusCallbackURL,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode voice request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/v1/calls", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("build voice request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("voice request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var callResp ttsCallResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&callResp)
+
+	if resp.StatusCode >= 300 || callResp.Error != nil {
+		provErr := &ProviderError{Provider: "voice", StatusCode: resp.StatusCode}
+		if callResp.Error != nil {
+			provErr.Code = callResp.Error.Code
+			provErr.Message = callResp.Error.Message
+		}
+		if provErr.Message == "" {
+			provErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return "", provErr
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("decode voice response: %w", decodeErr)
+	}
+	if callResp.CallID == "" {
+		return "", &ProviderError{Provider: "voice", StatusCode: resp.StatusCode, Message: "response did not include a call id"}
+	}
+
+	return callResp.CallID, nil
+}
@@ -0,0 +1,123 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// twilioErrorMessages maps common Twilio error codes to readable descriptions
+var twilioErrorMessages = map[int]string{
+	20003: "authentication failed",
+	21211: "invalid recipient phone number",
+	21408: "permission to send to this region is not enabled",
+	21610: "recipient has unsubscribed from messages",
+	21612: "recipient is not reachable from the sender number",
+	21614: "recipient number is not a mobile number",
+	30003: "recipient handset is unreachable",
+	30005: "unknown recipient number",
+	30006: "recipient is a landline or unreachable carrier",
+	30007: "message filtered by carrier",
+}
+
+// TwilioSender speaks the Twilio Programmable Messaging REST API
+type TwilioSender struct {
+	cfg    config.TwilioConfig
+	client *http.Client
+}
+
+func NewTwilioSender(cfg config.TwilioConfig, client *http.Client) (*TwilioSender, error) {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return nil, fmt.Errorf("twilio account sid and auth token are required")
+	}
+	if cfg.From == "" && cfg.MessagingServiceSID == "" {
+		return nil, fmt.Errorf("twilio from number or messaging service sid is required")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	return &TwilioSender{cfg: cfg, client: client}, nil
+}
+
+type twilioMessageResponse struct {
+	SID          string  `json:"sid"`
+	Status       string  `json:"status"`
+	ErrorCode    *int    `json:"error_code"`
+	ErrorMessage *string `json:"error_message"`
+}
+
+type twilioErrorResponse struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+	Status   int    `json:"status"`
+}
+
+// SendSMS creates a message resource and returns its SID
+func (s *TwilioSender) SendSMS(ctx context.Context, msg *SMSMessage) (string, error) {
+	form := url.Values{}
+	form.Set("To", msg.To)
+	form.Set("Body", msg.Body)
+	if s.cfg.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", s.cfg.MessagingServiceSID)
+	} else {
+		form.Set("From", s.cfg.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.cfg.BaseURL, url.PathEscape(s.cfg.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("build twilio request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(s.cfg.AccountSID, s.cfg.AuthToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp twilioErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return "", twilioError(resp.StatusCode, errResp.Code, errResp.Message)
+	}
+
+	var msgResp twilioMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msgResp); err != nil {
+		return "", fmt.Errorf("decode twilio response: %w", err)
+	}
+
+	// The message was accepted but then rejected; sending it again will not help
+	if msgResp.ErrorCode != nil {
+		message := ""
+		if msgResp.ErrorMessage != nil {
+			message = *msgResp.ErrorMessage
+		}
+		return msgResp.SID, Permanent(twilioError(resp.StatusCode, *msgResp.ErrorCode, message))
+	}
+
+	return msgResp.SID, nil
+}
+
+func twilioError(statusCode, code int, message string) *ProviderError {
+	if known, ok := twilioErrorMessages[code]; ok {
+		message = known
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	provErr := &ProviderError{Provider: "twilio", StatusCode: statusCode, Message: message}
+	if code != 0 {
+		provErr.Code = strconv.Itoa(code)
+	}
+	return provErr
+}
@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+func newTestTwilioSender(t *testing.T, handler http.HandlerFunc) *TwilioSender {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	sender, err := NewTwilioSender(config.TwilioConfig{
+		BaseURL:    srv.URL + "/",
+		AccountSID: "AC123",
+		AuthToken:  "token",
+		From:       "+15005550006",
+	}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewTwilioSender: %v", err)
+	}
+	return sender
+}
+
+func TestTwilioSenderSuccess(t *testing.T) {
+	sender := newTestTwilioSender(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "AC123" || pass != "token" {
+			t.Errorf("basic auth = %q/%q", user, pass)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+		if r.PostForm.Get("To") != "+2348012345678" || r.PostForm.Get("From") != "+15005550006" ||
+			r.PostForm.Get("Body") != "Your code is 123456" {
+			t.Errorf("form = %v", r.PostForm)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM0123","status":"queued","error_code":null,"error_message":null}`))
+	})
+
+	sid, err := sender.SendSMS(context.Background(), &SMSMessage{To: "+2348012345678", Body: "Your code is 123456"})
+	if err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+	if sid != "SM0123" {
+		t.Errorf("sid = %q, want SM0123", sid)
+	}
+}
+
+func TestTwilioSenderErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantCode    string
+		wantMessage string
+		retryable   bool
+	}{
+		{
+			name:        "invalid number",
+			status:      http.StatusBadRequest,
+			body:        `{"code":21211,"message":"The 'To' number is not valid.","status":400}`,
+			wantCode:    "21211",
+			wantMessage: "invalid recipient phone number",
+		},
+		{
+			name:        "authentication",
+			status:      http.StatusUnauthorized,
+			body:        `{"code":20003,"message":"Authenticate","status":401}`,
+			wantCode:    "20003",
+			wantMessage: "authentication failed",
+		},
+		{
+			name:        "unknown code keeps provider message",
+			status:      http.StatusBadRequest,
+			body:        `{"code":29999,"message":"Something odd","status":400}`,
+			wantCode:    "29999",
+			wantMessage: "Something odd",
+		},
+		{
+			name:        "rate limited",
+			status:      http.StatusTooManyRequests,
+			body:        `{"code":20429,"message":"Too Many Requests","status":429}`,
+			wantCode:    "20429",
+			wantMessage: "Too Many Requests",
+			retryable:   true,
+		},
+		{
+			name:        "server error without body",
+			status:      http.StatusServiceUnavailable,
+			wantMessage: "Service Unavailable",
+			retryable:   true,
+		},
+		{
+			name:        "rejected in a created response",
+			status:      http.StatusCreated,
+			body:        `{"sid":"SM0456","status":"failed","error_code":30007,"error_message":"Carrier violation"}`,
+			wantCode:    "30007",
+			wantMessage: "message filtered by carrier",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := newTestTwilioSender(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := sender.SendSMS(context.Background(), &SMSMessage{To: "+2348012345678", Body: "hi"})
+			var provErr *ProviderError
+			if !errors.As(err, &provErr) {
+				t.Fatalf("err = %v, want a ProviderError", err)
+			}
+			if provErr.Provider != "twilio" || provErr.Code != tt.wantCode || provErr.Message != tt.wantMessage {
+				t.Errorf("error = %+v, want code %q message %q", provErr, tt.wantCode, tt.wantMessage)
+			}
+			if got := IsRetryable(err); got != tt.retryable {
+				t.Errorf("IsRetryable = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestTwilioSenderMessagingService(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("MessagingServiceSid") != "MG1" || r.PostForm.Has("From") {
+			t.Errorf("form = %v, want MessagingServiceSid instead of From", r.PostForm)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM1"}`))
+	}))
+	defer srv.Close()
+
+	sender, err := NewTwilioSender(config.TwilioConfig{
+		BaseURL: srv.URL, AccountSID: "AC1", AuthToken: "t", MessagingServiceSID: "MG1",
+	}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewTwilioSender: %v", err)
+	}
+	if _, err := sender.SendSMS(context.Background(), &SMSMessage{To: "+15551234567", Body: "hi"}); err != nil {
+		t.Fatalf("SendSMS: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// VoiceMessage is a provider-agnostic outbound voice call that reads Text aloud
+type VoiceMessage struct {
+	To       string
+	Text     string
+	Language string
+	// Reference is echoed back in status callbacks to correlate the call
+	Reference string
+}
+
+// VoiceSender places text-to-speech calls and returns the provider call ID. The
+// call's outcome is reported later through a status callback.
+type VoiceSender interface {
+	PlaceCall(ctx context.Context, msg *VoiceMessage) (string, error)
+}
+
+// NewVoiceSender builds the voice sender selected by cfg.VoiceProvider
+func NewVoiceSender(cfg config.Config) (VoiceSender, error) {
+	switch cfg.VoiceProvider {
+	case "http":
+		return NewHTTPVoiceSender(cfg.Voice, &http.Client{Timeout: cfg.VoiceTimeout})
+	case "log", "":
+		return &LogVoiceSender{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported voice provider: %s", cfg.VoiceProvider)
+	}
+}
+
+// LogVoiceSender writes call scripts to the service log instead of placing calls
+type LogVoiceSender struct{}
+
+func (s *LogVoiceSender) PlaceCall(ctx context.Context, msg *VoiceMessage) (string, error) {
+	log.Printf("[VOICE] To: %s", msg.To)
+	log.Printf("[VOICE] Script: %s", msg.Text)
+	return "", nil
+}
+
+// VoiceCallStatus is a call outcome reported by the provider's status callback
+type VoiceCallStatus struct {
+	CallID    string `json:"call_id"`
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+}
+
+// Answered reports whether the call was picked up
+func (s VoiceCallStatus) Answered() bool {
+	switch strings.ToLower(s.Status) {
+	case "answered", "completed":
+		return true
+	default:
+		return false
+	}
+}
+
+// Unanswered reports whether the call ended without being picked up. Statuses
+// for calls still in progress are neither answered nor unanswered.
+func (s VoiceCallStatus) Unanswered() bool {
+	switch strings.ToLower(s.Status) {
+	case "no_answer", "no-answer", "busy", "failed", "canceled", "cancelled", "rejected":
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseVoiceCallback authenticates a status callback against the shared secret
+// and decodes it. The signature is the hex HMAC-SHA256 of the raw body.
+func ParseVoiceCallback(secret string, body []byte, signature string) (*VoiceCallStatus, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("voice %w", ErrCallbackNotConfigured)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(strings.TrimSpace(signature)))) {
+		return nil, ErrInvalidCallbackSignature
+	}
+
+	var status VoiceCallStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("decode voice callback: %w", err)
+	}
+	if status.Reference == "" || status.Status == "" {
+		return nil, fmt.Errorf("voice callback requires reference and status")
+	}
+
+	return &status, nil
+}
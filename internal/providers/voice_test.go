@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+func signCallback(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseVoiceCallback(t *testing.T) {
+	const secret = "callback-secret"
+	body := []byte(`{"call_id":"CA123","reference":"voice-1","status":"no-answer"}`)
+
+	status, err := ParseVoiceCallback(secret, body, strings.ToUpper(signCallback(secret, body)))
+	if err != nil {
+		t.Fatalf("ParseVoiceCallback: %v", err)
+	}
+	if status.CallID != "CA123" || status.Reference != "voice-1" || !status.Unanswered() || status.Answered() {
+		t.Errorf("status = %+v", status)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		wantErr   error
+	}{
+		{"wrong secret", secret, body, signCallback("other", body), ErrInvalidCallbackSignature},
+		{"tampered body", secret, []byte(`{"call_id":"CA123","reference":"voice-2","status":"no-answer"}`), signCallback(secret, body), ErrInvalidCallbackSignature},
+		{"no signature", secret, body, "", ErrInvalidCallbackSignature},
+		{"no secret configured", "", body, signCallback("", body), ErrCallbackNotConfigured},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseVoiceCallback(tt.secret, tt.body, tt.signature); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	incomplete := []byte(`{"call_id":"CA123","status":"busy"}`)
+	if _, err := ParseVoiceCallback(secret, incomplete, signCallback(secret, incomplete)); err == nil {
+		t.Error("accepted a callback without a reference")
+	}
+}
+
+func TestNewHTTPVoiceSenderRequiresCallbackSecret(t *testing.T) {
+	cfg := config.VoiceConfig{BaseURL: "https://voice.example.com", APIKey: "key", From: "+15005550006"}
+	if _, err := NewHTTPVoiceSender(cfg, http.DefaultClient); err == nil {
+		t.Fatal("NewHTTPVoiceSender accepted a config without a callback secret")
+	}
+
+	cfg.CallbackSecret = "callback-secret"
+	if _, err := NewHTTPVoiceSender(cfg, http.DefaultClient); err != nil {
+		t.Fatalf("NewHTTPVoiceSender: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+type DeviceRepository struct {
+	db *sql.DB
+}
+
+func NewDeviceRepository(db *sql.DB) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+// Register inserts or refreshes a device token for a user's device
+func (r *DeviceRepository) Register(ctx context.Context, device *models.Device) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A token belongs to exactly one device; drop it from any previous owner
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM notification_devices
+		WHERE token = $1
+		  AND NOT (user_id = $2 AND device_id = $3)
+	`, device.Token, device.UserID, device.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to release device token: %w", err)
+	}
+
+	query := `
+		INSERT INTO notification_devices (
+			user_id, merchant_id, device_id, platform, token,
+			app_version, active, last_seen_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, TRUE, NOW(), NOW(), NOW())
+		ON CONFLICT (user_id, device_id) DO UPDATE SET
+			merchant_id = EXCLUDED.merchant_id,
+			platform = EXCLUDED.platform,
+			token = EXCLUDED.token,
+			app_version = EXCLUDED.app_version,
+			active = TRUE,
+			last_seen_at = NOW(),
+			updated_at = NOW()
+		RETURNING id, active, last_seen_at, created_at, updated_at
+	`
+
+	err = tx.QueryRowContext(
+		ctx, query,
+		device.UserID, device.MerchantID, device.DeviceID, device.Platform,
+		device.Token, device.AppVersion,
+	).Scan(&device.ID, &device.Active, &device.LastSeenAt, &device.CreatedAt, &device.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to register device: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit device registration: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a device by ID
+func (r *DeviceRepository) GetByID(ctx context.Context, id string) (*models.Device, error) {
+	query := `
+		SELECT id, user_id, merchant_id, device_id, platform, token,
+		       app_version, active, last_seen_at, created_at, updated_at
+		FROM notification_devices
+		WHERE id = $1
+	`
+
+	var device models.Device
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&device.ID, &device.UserID, &device.MerchantID, &device.DeviceID,
+		&device.Platform, &device.Token, &device.AppVersion, &device.Active,
+		&device.LastSeenAt, &device.CreatedAt, &device.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("device not found")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+
+	return &device, nil
+}
+
+// ListByUserID retrieves all devices registered by a user
+func (r *DeviceRepository) ListByUserID(ctx context.Context, userID string) ([]*models.Device, error) {
+	query := `
+		SELECT id, user_id, merchant_id, device_id, platform, token,
+		       app_version, active, last_seen_at, created_at, updated_at
+		FROM notification_devices
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+// ListActiveByUserID retrieves the devices a push notification should fan out to
+func (r *DeviceRepository) ListActiveByUserID(ctx context.Context, userID string) ([]*models.Device, error) {
+	query := `
+		SELECT id, user_id, merchant_id, device_id, platform, token,
+		       app_version, active, last_seen_at, created_at, updated_at
+		FROM notification_devices
+		WHERE user_id = $1
+		  AND active
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active devices: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+// Deactivate stops push delivery to a device without forgetting it
+func (r *DeviceRepository) Deactivate(ctx context.Context, id string) error {
+	query := `
+		UPDATE notification_devices SET
+			active = FALSE,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate device: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("device not found")
+	}
+
+	return nil
+}
+
+// Delete removes a device registration
+func (r *DeviceRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notification_devices WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete device: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("device not found")
+	}
+
+	return nil
+}
+
+// scanDevices is a helper to scan multiple device rows
+func scanDevices(rows *sql.Rows) ([]*models.Device, error) {
+	var devices []*models.Device
+	for rows.Next() {
+		var device models.Device
+		err := rows.Scan(
+			&device.ID, &device.UserID, &device.MerchantID, &device.DeviceID,
+			&device.Platform, &device.Token, &device.AppVersion, &device.Active,
+			&device.LastSeenAt, &device.CreatedAt, &device.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		devices = append(devices, &device)
+	}
+
+	return devices, nil
+}
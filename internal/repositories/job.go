@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// JobRepository coordinates scheduled jobs across replicas and records their runs
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// JobLease holds a job's advisory lock until Finish. The lock is scoped to a
+// transaction so it is released with the run's record, or by Postgres if the
+// replica dies mid-run, and never outlives the job. The transaction keeps one
+// pool connection for the whole run, up to SCHEDULER_JOB_TIMEOUT; the scheduler
+// runs its jobs one at a time, so each replica holds at most one.
+type JobLease struct {
+	tx   *sql.Tx
+	name string
+}
+
+// Acquire takes the job's transaction-scoped advisory lock. It returns nil, with
+// no error, when another replica holds the lock or the job's last run started
+// less than interval ago.
+func (r *JobRepository) Acquire(ctx context.Context, name string, interval time.Duration) (*JobLease, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, jobLockKey(name)).Scan(&locked); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	if !locked {
+		tx.Rollback()
+		return nil, nil
+	}
+
+	query := `
+		SELECT NOT EXISTS (
+			SELECT 1
+			FROM scheduled_job_runs
+			WHERE name = $1
+			  AND started_at > NOW() - make_interval(secs => $2)
+		)
+	`
+
+	var due bool
+	if err := tx.QueryRowContext(ctx, query, name, interval.Seconds()).Scan(&due); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check job schedule: %w", err)
+	}
+	if !due {
+		tx.Rollback()
+		return nil, nil
+	}
+
+	return &JobLease{tx: tx, name: name}, nil
+}
+
+// Finish records the run and releases the lock
+func (l *JobLease) Finish(ctx context.Context, run *models.JobRun) error {
+	defer l.tx.Rollback()
+
+	query := `
+		INSERT INTO scheduled_job_runs (
+			name, status, affected, error, runner, started_at, finished_at, last_succeeded_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (name) DO UPDATE SET
+			status = EXCLUDED.status,
+			affected = EXCLUDED.affected,
+			error = EXCLUDED.error,
+			runner = EXCLUDED.runner,
+			started_at = EXCLUDED.started_at,
+			finished_at = EXCLUDED.finished_at,
+			last_succeeded_at = COALESCE(EXCLUDED.last_succeeded_at, scheduled_job_runs.last_succeeded_at)
+	`
+
+	var succeededAt *time.Time
+	if run.Status == models.JobSucceeded {
+		succeededAt = &run.FinishedAt
+	}
+
+	_, err := l.tx.ExecContext(
+		ctx, query,
+		l.name, run.Status, run.Affected, run.Error, run.Runner, run.StartedAt, run.FinishedAt, succeededAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record job run: %w", err)
+	}
+
+	if err := l.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit job run: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the latest run of every job that has run
+func (r *JobRepository) List(ctx context.Context) ([]*models.JobRun, error) {
+	query := `
+		SELECT name, status, affected, error, runner, started_at, finished_at, last_succeeded_at
+		FROM scheduled_job_runs
+		ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*models.JobRun
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(
+			&run.Name, &run.Status, &run.Affected, &run.Error, &run.Runner,
+			&run.StartedAt, &run.FinishedAt, &run.LastSucceededAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		runs = append(runs, &run)
+	}
+
+	return runs, rows.Err()
+}
+
+// jobLockKey derives a stable advisory lock key from the job name
+func jobLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("notification-service:job:" + name))
+	return int64(h.Sum64())
+}
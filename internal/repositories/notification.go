@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,6 +14,15 @@ import (
 	"github.com/lib/pq"
 )
 
+var (
+	// ErrNotificationNotFound is returned when no notification matches the given ID
+	ErrNotificationNotFound = errors.New("notification not found")
+	// ErrNotificationNotDeadLettered is returned when requeueing a notification that is not dead-lettered
+	ErrNotificationNotDeadLettered = errors.New("notification is not dead-lettered")
+	// ErrPreferencesModified is returned when preferences changed since the caller read them
+	ErrPreferencesModified = errors.New("notification preferences have been modified since they were read")
+)
+
 type NotificationRepository struct {
 	db *sql.DB
 }
@@ -31,6 +41,16 @@ func NewNotificationRepository(dsn string) (*NotificationRepository, error) {
 	return &NotificationRepository{db: db}, nil
 }
 
+// NewNotificationRepositoryWithDB wraps an existing connection pool
+func NewNotificationRepositoryWithDB(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// DB exposes the underlying connection pool so sibling repositories can share it
+func (r *NotificationRepository) DB() *sql.DB {
+	return r.db
+}
+
 // Create inserts a new notification
 func (r *NotificationRepository) Create(ctx context.Context, notif *models.Notification) error {
 	templateDataJSON, _ := json.Marshal(notif.TemplateData)
@@ -39,17 +59,18 @@ func (r *NotificationRepository) Create(ctx context.Context, notif *models.Notif
 	query := `
 		INSERT INTO notifications (
 			merchant_id, user_id, type, channel, recipient,
-			subject, message, template_name, template_data,
-			status, metadata, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+			subject, message, html_message, template_name, template_version,
+			template_data, locale, status, metadata, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW())
 		RETURNING id, created_at
 	`
 
 	err := r.db.QueryRowContext(
 		ctx, query,
 		notif.MerchantID, notif.UserID, notif.Type, notif.Channel,
-		notif.Recipient, notif.Subject, notif.Message, notif.TemplateName,
-		templateDataJSON, notif.Status, metadataJSON,
+		notif.Recipient, notif.Subject, notif.Message, notif.HTMLMessage,
+		notif.TemplateName, notif.TemplateVersion, templateDataJSON,
+		notif.Locale, notif.Status, metadataJSON,
 	).Scan(&notif.ID, &notif.CreatedAt)
 
 	if err != nil {
@@ -63,7 +84,7 @@ func (r *NotificationRepository) Create(ctx context.Context, notif *models.Notif
 func (r *NotificationRepository) GetByID(ctx context.Context, id string) (*models.Notification, error) {
 	query := `
 		SELECT id, merchant_id, user_id, type, channel, recipient,
-		       subject, message, template_name, template_data,
+		       subject, message, html_message, template_name, template_version, locale, template_data,
 		       status, sent_at, delivered_at, error_message,
 		       retry_count, metadata, created_at
 		FROM notifications
@@ -75,14 +96,14 @@ func (r *NotificationRepository) GetByID(ctx context.Context, id string) (*model
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&notif.ID, &notif.MerchantID, &notif.UserID, &notif.Type,
-		&notif.Channel, &notif.Recipient, &notif.Subject, &notif.Message,
-		&notif.TemplateName, &templateDataJSON, &notif.Status,
+		&notif.Channel, &notif.Recipient, &notif.Subject, &notif.Message, &notif.HTMLMessage,
+		&notif.TemplateName, &notif.TemplateVersion, &notif.Locale, &templateDataJSON, &notif.Status,
 		&notif.SentAt, &notif.DeliveredAt, &notif.ErrorMessage,
 		&notif.RetryCount, &metadataJSON, &notif.CreatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("notification not found")
+		return nil, ErrNotificationNotFound
 	}
 
 	if err != nil {
@@ -112,8 +133,7 @@ func (r *NotificationRepository) UpdateStatus(
 			status = $2,
 			error_message = $3,
 			sent_at = CASE WHEN $2 = 'sent' OR $2 = 'delivered' THEN NOW() ELSE sent_at END,
-			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END,
-			retry_count = retry_count + 1
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END
 		WHERE id = $1
 	`
 
@@ -125,22 +145,245 @@ func (r *NotificationRepository) UpdateStatus(
 	return nil
 }
 
-// ListPending retrieves pending notifications for processing
-func (r *NotificationRepository) ListPending(ctx context.Context, limit int) ([]*models.Notification, error) {
+// ScheduleRetry records a failed delivery attempt and returns the notification to
+// the queue, to be picked up again at nextAttemptAt
+func (r *NotificationRepository) ScheduleRetry(
+	ctx context.Context,
+	id string,
+	errorMessage string,
+	nextAttemptAt time.Time,
+) error {
+	query := `
+		UPDATE notifications SET
+			status = 'pending',
+			error_message = $2,
+			retry_count = retry_count + 1,
+			next_attempt_at = $3,
+			claimed_at = NULL
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, errorMessage, nextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("failed to schedule notification retry: %w", err)
+	}
+
+	return nil
+}
+
+// Defer returns a claimed notification to the queue until nextAttemptAt without
+// counting an attempt, merging metadata recording why into the notification
+func (r *NotificationRepository) Defer(
+	ctx context.Context,
+	id string,
+	nextAttemptAt time.Time,
+	metadata map[string]interface{},
+) error {
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification metadata: %w", err)
+	}
+
+	query := `
+		UPDATE notifications SET
+			status = 'pending',
+			next_attempt_at = $2,
+			claimed_at = NULL,
+			metadata = COALESCE(metadata, '{}'::jsonb) || $3::jsonb
+		WHERE id = $1
+	`
+
+	_, err = r.db.ExecContext(ctx, query, id, nextAttemptAt, metadataJSON)
+	if err != nil {
+		return fmt.Errorf("failed to defer notification: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDeadLettered records a final failed delivery attempt and parks the notification
+func (r *NotificationRepository) MarkDeadLettered(ctx context.Context, id string, errorMessage string) error {
+	query := `
+		UPDATE notifications SET
+			status = 'dead_lettered',
+			error_message = $2,
+			retry_count = retry_count + 1,
+			dead_lettered_at = NOW(),
+			claimed_at = NULL
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, errorMessage)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter notification: %w", err)
+	}
+
+	return nil
+}
+
+// AbandonClaimed parks a claimed notification whose attempt budget was already
+// spent, without counting another attempt
+func (r *NotificationRepository) AbandonClaimed(ctx context.Context, id string, errorMessage string) error {
+	query := `
+		UPDATE notifications SET
+			status = 'dead_lettered',
+			error_message = $2,
+			dead_lettered_at = NOW(),
+			claimed_at = NULL
+		WHERE id = $1
+		  AND status = 'processing'
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, errorMessage)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter notification: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeadLettered retrieves the most recently dead-lettered notifications
+func (r *NotificationRepository) ListDeadLettered(ctx context.Context, limit int) ([]*models.Notification, error) {
 	query := `
 		SELECT id, merchant_id, user_id, type, channel, recipient,
-		       subject, message, template_name, template_data,
-		       status, retry_count, metadata, created_at
+		       subject, message, html_message, template_name, template_version, locale, template_data,
+		       status, sent_at, delivered_at, error_message,
+		       retry_count, metadata, created_at
 		FROM notifications
-		WHERE status = 'pending'
-		  AND retry_count < 3
-		ORDER BY created_at ASC
+		WHERE status = 'dead_lettered'
+		ORDER BY dead_lettered_at DESC
 		LIMIT $1
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list pending notifications: %w", err)
+		return nil, fmt.Errorf("failed to list dead-lettered notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// Requeue moves a dead-lettered notification back to the queue with a fresh attempt budget
+func (r *NotificationRepository) Requeue(ctx context.Context, id string) error {
+	query := `
+		UPDATE notifications SET
+			status = 'pending',
+			retry_count = 0,
+			next_attempt_at = NOW(),
+			dead_lettered_at = NULL,
+			claimed_at = NULL
+		WHERE id = $1
+		  AND status = 'dead_lettered'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to requeue notification: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		var exists bool
+		err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM notifications WHERE id = $1)`, id).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to look up notification: %w", err)
+		}
+		if !exists {
+			return ErrNotificationNotFound
+		}
+		return ErrNotificationNotDeadLettered
+	}
+
+	return nil
+}
+
+// MergeMetadata merges the given keys into the notification metadata
+func (r *NotificationRepository) MergeMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification metadata: %w", err)
+	}
+
+	query := `
+		UPDATE notifications SET
+			metadata = COALESCE(metadata, '{}'::jsonb) || $2::jsonb
+		WHERE id = $1
+	`
+
+	_, err = r.db.ExecContext(ctx, query, id, metadataJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update notification metadata: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimMetadataKey sets a metadata key only if it is not already present,
+// reporting whether this call set it. It makes one-off follow-ups, such as a
+// fallback send, idempotent under repeated provider callbacks.
+func (r *NotificationRepository) ClaimMetadataKey(ctx context.Context, id, key string, value interface{}) (bool, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode notification metadata: %w", err)
+	}
+
+	query := `
+		UPDATE notifications SET
+			metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object($2::text, $3::jsonb)
+		WHERE id = $1
+		  AND NOT jsonb_exists(COALESCE(metadata, '{}'::jsonb), $2::text)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, key, valueJSON)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim notification metadata key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim notification metadata key: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// ClaimPending atomically moves a batch of pending notifications to processing so
+// that concurrent dispatchers never deliver the same row. Notifications stuck in
+// processing longer than claimTimeout (e.g. a crashed replica) are reclaimed, and
+// the interrupted delivery counts as a failed attempt.
+func (r *NotificationRepository) ClaimPending(
+	ctx context.Context,
+	limit int,
+	claimTimeout time.Duration,
+) ([]*models.Notification, error) {
+	query := `
+		UPDATE notifications n SET
+			status = 'processing',
+			claimed_at = NOW(),
+			retry_count = n.retry_count + CASE WHEN n.status = 'processing' THEN 1 ELSE 0 END
+		FROM (
+			SELECT id
+			FROM notifications
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'processing' AND claimed_at < NOW() - $2 * INTERVAL '1 second')
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) claimable
+		WHERE n.id = claimable.id
+		RETURNING n.id, n.merchant_id, n.user_id, n.type, n.channel, n.recipient,
+		          n.subject, n.message, n.html_message, n.template_name, n.template_version, n.locale, n.template_data,
+		          n.status, n.retry_count, n.metadata, n.created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, int64(claimTimeout.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending notifications: %w", err)
 	}
 	defer rows.Close()
 
@@ -152,7 +395,7 @@ func (r *NotificationRepository) ListPending(ctx context.Context, limit int) ([]
 		err := rows.Scan(
 			&notif.ID, &notif.MerchantID, &notif.UserID, &notif.Type,
 			&notif.Channel, &notif.Recipient, &notif.Subject, &notif.Message,
-			&notif.TemplateName, &templateDataJSON, &notif.Status,
+			&notif.HTMLMessage, &notif.TemplateName, &notif.TemplateVersion, &notif.Locale, &templateDataJSON, &notif.Status,
 			&notif.RetryCount, &metadataJSON, &notif.CreatedAt,
 		)
 		if err != nil {
@@ -170,9 +413,51 @@ func (r *NotificationRepository) ListPending(ctx context.Context, limit int) ([]
 		notifications = append(notifications, &notif)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim pending notifications: %w", err)
+	}
+
 	return notifications, nil
 }
 
+// CountByTypeAndStatus counts notifications created since the given time, optionally
+// for one merchant. Test sends (metadata test=true) are excluded.
+func (r *NotificationRepository) CountByTypeAndStatus(
+	ctx context.Context,
+	merchantID *string,
+	since time.Time,
+) ([]models.NotificationCount, error) {
+	query := `
+		SELECT type, status, COUNT(*)
+		FROM notifications
+		WHERE created_at >= $1
+		  AND COALESCE(metadata->>'test', 'false') <> 'true'
+	`
+	args := []interface{}{since}
+	if merchantID != nil {
+		query += ` AND merchant_id = $2`
+		args = append(args, *merchantID)
+	}
+	query += ` GROUP BY type, status`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.NotificationCount
+	for rows.Next() {
+		var c models.NotificationCount
+		if err := rows.Scan(&c.Type, &c.Status, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan notification count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
 // NotificationPreferencesRepository handles notification preferences
 type NotificationPreferencesRepository struct {
 	db *sql.DB
@@ -188,10 +473,8 @@ func (r *NotificationPreferencesRepository) GetByMerchantID(
 	merchantID string,
 ) (*models.NotificationPreferences, error) {
 	query := `
-		SELECT id, merchant_id, email_enabled, sms_enabled, push_enabled,
-		       transaction_notifications, payout_notifications,
-		       settlement_notifications, security_notifications,
-		       marketing_notifications, email_address, phone_number,
+		SELECT id, merchant_id, email_address, phone_number,
+		       locale, timezone, quiet_hours_start, quiet_hours_end,
 		       created_at, updated_at
 		FROM notification_preferences
 		WHERE merchant_id = $1
@@ -199,11 +482,9 @@ func (r *NotificationPreferencesRepository) GetByMerchantID(
 
 	var prefs models.NotificationPreferences
 	err := r.db.QueryRowContext(ctx, query, merchantID).Scan(
-		&prefs.ID, &prefs.MerchantID, &prefs.EmailEnabled, &prefs.SMSEnabled,
-		&prefs.PushEnabled, &prefs.TransactionNotifications,
-		&prefs.PayoutNotifications, &prefs.SettlementNotifications,
-		&prefs.SecurityNotifications, &prefs.MarketingNotifications,
+		&prefs.ID, &prefs.MerchantID,
 		&prefs.EmailAddress, &prefs.PhoneNumber,
+		&prefs.Locale, &prefs.Timezone, &prefs.QuietHoursStart, &prefs.QuietHoursEnd,
 		&prefs.CreatedAt, &prefs.UpdatedAt,
 	)
 
@@ -216,34 +497,33 @@ func (r *NotificationPreferencesRepository) GetByMerchantID(
 		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
 	}
 
+	prefs.Matrix, err = loadPreferenceRules(ctx, r.db, merchantID, merchantRulesUserID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &prefs, nil
 }
 
-// CreateDefault creates default notification preferences for a merchant
+// CreateDefault creates default notification preferences for a merchant. The
+// matrix starts empty, so every cell uses the defaults.
 func (r *NotificationPreferencesRepository) CreateDefault(
 	ctx context.Context,
 	merchantID string,
 ) (*models.NotificationPreferences, error) {
 	query := `
-		INSERT INTO notification_preferences (
-			merchant_id, email_enabled, sms_enabled, push_enabled,
-			transaction_notifications, payout_notifications,
-			settlement_notifications, security_notifications
-		) VALUES ($1, TRUE, FALSE, TRUE, TRUE, TRUE, TRUE, TRUE)
-		RETURNING id, merchant_id, email_enabled, sms_enabled, push_enabled,
-		          transaction_notifications, payout_notifications,
-		          settlement_notifications, security_notifications,
-		          marketing_notifications, email_address, phone_number,
+		INSERT INTO notification_preferences (merchant_id)
+		VALUES ($1)
+		RETURNING id, merchant_id, email_address, phone_number,
+		          locale, timezone, quiet_hours_start, quiet_hours_end,
 		          created_at, updated_at
 	`
 
-	var prefs models.NotificationPreferences
+	prefs := models.NotificationPreferences{Matrix: models.PreferenceMatrix{}}
 	err := r.db.QueryRowContext(ctx, query, merchantID).Scan(
-		&prefs.ID, &prefs.MerchantID, &prefs.EmailEnabled, &prefs.SMSEnabled,
-		&prefs.PushEnabled, &prefs.TransactionNotifications,
-		&prefs.PayoutNotifications, &prefs.SettlementNotifications,
-		&prefs.SecurityNotifications, &prefs.MarketingNotifications,
+		&prefs.ID, &prefs.MerchantID,
 		&prefs.EmailAddress, &prefs.PhoneNumber,
+		&prefs.Locale, &prefs.Timezone, &prefs.QuietHoursStart, &prefs.QuietHoursEnd,
 		&prefs.CreatedAt, &prefs.UpdatedAt,
 	)
 
@@ -263,29 +543,28 @@ func (r *NotificationPreferencesRepository) Update(
 	ctx context.Context,
 	prefs *models.NotificationPreferences,
 ) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE notification_preferences SET
-			email_enabled = $2,
-			sms_enabled = $3,
-			push_enabled = $4,
-			transaction_notifications = $5,
-			payout_notifications = $6,
-			settlement_notifications = $7,
-			security_notifications = $8,
-			marketing_notifications = $9,
-			email_address = $10,
-			phone_number = $11,
+			email_address = $2,
+			phone_number = $3,
+			locale = $4,
+			timezone = $5,
+			quiet_hours_start = $6,
+			quiet_hours_end = $7,
 			updated_at = NOW()
 		WHERE merchant_id = $1
 	`
 
-	result, err := r.db.ExecContext(
+	result, err := tx.ExecContext(
 		ctx, query,
-		prefs.MerchantID, prefs.EmailEnabled, prefs.SMSEnabled,
-		prefs.PushEnabled, prefs.TransactionNotifications,
-		prefs.PayoutNotifications, prefs.SettlementNotifications,
-		prefs.SecurityNotifications, prefs.MarketingNotifications,
-		prefs.EmailAddress, prefs.PhoneNumber,
+		prefs.MerchantID, prefs.EmailAddress, prefs.PhoneNumber, prefs.Locale,
+		prefs.Timezone, prefs.QuietHoursStart, prefs.QuietHoursEnd,
 	)
 
 	if err != nil {
@@ -301,6 +580,96 @@ func (r *NotificationPreferencesRepository) Update(
 		return fmt.Errorf("preferences not found for merchant: %s", prefs.MerchantID)
 	}
 
+	if err := replacePreferenceRules(ctx, tx, prefs.MerchantID, merchantRulesUserID, prefs.Matrix); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit preference update: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateIfUnmodified updates notification preferences only if they were last
+// updated at expectedUpdatedAt, recording the change in the audit trail in the
+// same transaction. It returns ErrPreferencesModified if the row has moved on.
+func (r *NotificationPreferencesRepository) UpdateIfUnmodified(
+	ctx context.Context,
+	prefs *models.NotificationPreferences,
+	expectedUpdatedAt time.Time,
+	change *models.NotificationPreferenceChange,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE notification_preferences SET
+			email_address = $2,
+			phone_number = $3,
+			locale = $4,
+			timezone = $5,
+			quiet_hours_start = $6,
+			quiet_hours_end = $7,
+			updated_at = NOW()
+		WHERE merchant_id = $1
+		  AND updated_at = $8
+		RETURNING updated_at
+	`
+
+	err = tx.QueryRowContext(
+		ctx, query,
+		prefs.MerchantID, prefs.EmailAddress, prefs.PhoneNumber, prefs.Locale,
+		prefs.Timezone, prefs.QuietHoursStart, prefs.QuietHoursEnd,
+		expectedUpdatedAt,
+	).Scan(&prefs.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrPreferencesModified
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update preferences: %w", err)
+	}
+
+	if err := replacePreferenceRules(ctx, tx, prefs.MerchantID, merchantRulesUserID, prefs.Matrix); err != nil {
+		return err
+	}
+
+	change.MerchantID = prefs.MerchantID
+	if err := insertPreferenceChange(ctx, tx, change); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit preference update: %w", err)
+	}
+
+	return nil
+}
+
+// insertPreferenceChange records a preferences audit entry within tx
+func insertPreferenceChange(ctx context.Context, tx *sql.Tx, change *models.NotificationPreferenceChange) error {
+	changesJSON, err := json.Marshal(change.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode preference changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO notification_preference_changes (merchant_id, user_id, changes, changed_by, request_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err = tx.QueryRowContext(
+		ctx, query,
+		change.MerchantID, change.UserID, changesJSON, change.ChangedBy, change.RequestID,
+	).Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record preference change: %w", err)
+	}
+
 	return nil
 }
 
@@ -308,7 +677,7 @@ func (r *NotificationPreferencesRepository) Update(
 func (r *NotificationRepository) ListByUserID(ctx context.Context, userID string) ([]*models.Notification, error) {
 	query := `
 		SELECT id, merchant_id, user_id, type, channel, recipient,
-		       subject, message, template_name, template_data,
+		       subject, message, html_message, template_name, template_version, locale, template_data,
 		       status, sent_at, delivered_at, error_message,
 		       retry_count, metadata, created_at
 		FROM notifications
@@ -329,7 +698,7 @@ func (r *NotificationRepository) ListByUserID(ctx context.Context, userID string
 func (r *NotificationRepository) ListByMerchantID(ctx context.Context, merchantID string) ([]*models.Notification, error) {
 	query := `
 		SELECT id, merchant_id, user_id, type, channel, recipient,
-		       subject, message, template_name, template_data,
+		       subject, message, html_message, template_name, template_version, locale, template_data,
 		       status, sent_at, delivered_at, error_message,
 		       retry_count, metadata, created_at
 		FROM notifications
@@ -355,8 +724,8 @@ func scanNotifications(rows *sql.Rows) ([]*models.Notification, error) {
 
 		err := rows.Scan(
 			&notif.ID, &notif.MerchantID, &notif.UserID, &notif.Type,
-			&notif.Channel, &notif.Recipient, &notif.Subject, &notif.Message,
-			&notif.TemplateName, &templateDataJSON, &notif.Status,
+			&notif.Channel, &notif.Recipient, &notif.Subject, &notif.Message, &notif.HTMLMessage,
+			&notif.TemplateName, &notif.TemplateVersion, &notif.Locale, &templateDataJSON, &notif.Status,
 			&notif.SentAt, &notif.DeliveredAt, &notif.ErrorMessage,
 			&notif.RetryCount, &metadataJSON, &notif.CreatedAt,
 		)
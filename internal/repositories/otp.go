@@ -4,12 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/kodra-pay/notification-service/internal/models"
 )
 
+var ErrOTPNotFound = errors.New("OTP not found")
+
 type OTPRepository struct {
 	db *sql.DB
 }
@@ -24,18 +27,18 @@ func (r *OTPRepository) Create(ctx context.Context, otp *models.OTP) error {
 
 	query := `
 		INSERT INTO otps (
-			merchant_id, user_id, purpose, code, recipient,
+			merchant_id, user_id, purpose, code, key_id, recipient,
 			delivery_method, expires_at, attempts, max_attempts,
-			reference_id, metadata, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+			reference_id, locale, metadata, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
 		RETURNING id, created_at
 	`
 
 	err := r.db.QueryRowContext(
 		ctx, query,
-		otp.MerchantID, otp.UserID, otp.Purpose, otp.Code,
+		otp.MerchantID, otp.UserID, otp.Purpose, otp.Code, otp.KeyID,
 		otp.Recipient, otp.DeliveryMethod, otp.ExpiresAt,
-		otp.Attempts, otp.MaxAttempts, otp.ReferenceID, metadataJSON,
+		otp.Attempts, otp.MaxAttempts, otp.ReferenceID, otp.Locale, metadataJSON,
 	).Scan(&otp.ID, &otp.CreatedAt)
 
 	if err != nil {
@@ -45,49 +48,18 @@ func (r *OTPRepository) Create(ctx context.Context, otp *models.OTP) error {
 	return nil
 }
 
-// GetByCode retrieves an OTP by code for verification
-func (r *OTPRepository) GetByCode(
-	ctx context.Context,
-	merchantID string,
-	purpose models.OTPPurpose,
-	code string,
-) (*models.OTP, error) {
-	query := `
-		SELECT id, merchant_id, user_id, purpose, code, recipient,
-		       delivery_method, expires_at, verified_at, attempts,
-		       max_attempts, reference_id, metadata, created_at
-		FROM otps
-		WHERE merchant_id = $1
-		  AND purpose = $2
-		  AND code = $3
-		  AND verified_at IS NULL
-		ORDER BY created_at DESC
-		LIMIT 1
-	`
-
-	var otp models.OTP
-	var metadataJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, merchantID, purpose, code).Scan(
-		&otp.ID, &otp.MerchantID, &otp.UserID, &otp.Purpose,
-		&otp.Code, &otp.Recipient, &otp.DeliveryMethod,
-		&otp.ExpiresAt, &otp.VerifiedAt, &otp.Attempts,
-		&otp.MaxAttempts, &otp.ReferenceID, &metadataJSON, &otp.CreatedAt,
-	)
+// otpColumns is the column list scanned by scanOTP
+const otpColumns = `
+	id, merchant_id, user_id, purpose, code, key_id, recipient,
+	delivery_method, expires_at, verified_at, locked_at, attempts,
+	max_attempts, reference_id, locale, metadata, created_at
+`
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("OTP not found")
-	}
+// GetByID retrieves an OTP by ID
+func (r *OTPRepository) GetByID(ctx context.Context, id string) (*models.OTP, error) {
+	query := `SELECT ` + otpColumns + ` FROM otps WHERE id = $1`
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to get OTP: %w", err)
-	}
-
-	if len(metadataJSON) > 0 {
-		json.Unmarshal(metadataJSON, &otp.Metadata)
-	}
-
-	return &otp, nil
+	return scanOTP(r.db.QueryRowContext(ctx, query, id))
 }
 
 // GetByReferenceID retrieves the latest OTP by reference ID
@@ -98,9 +70,7 @@ func (r *OTPRepository) GetByReferenceID(
 	referenceID string,
 ) (*models.OTP, error) {
 	query := `
-		SELECT id, merchant_id, user_id, purpose, code, recipient,
-		       delivery_method, expires_at, verified_at, attempts,
-		       max_attempts, reference_id, metadata, created_at
+		SELECT ` + otpColumns + `
 		FROM otps
 		WHERE merchant_id = $1
 		  AND purpose = $2
@@ -109,61 +79,108 @@ func (r *OTPRepository) GetByReferenceID(
 		LIMIT 1
 	`
 
-	var otp models.OTP
-	var metadataJSON []byte
+	return scanOTP(r.db.QueryRowContext(ctx, query, merchantID, purpose, referenceID))
+}
 
-	err := r.db.QueryRowContext(ctx, query, merchantID, purpose, referenceID).Scan(
-		&otp.ID, &otp.MerchantID, &otp.UserID, &otp.Purpose,
-		&otp.Code, &otp.Recipient, &otp.DeliveryMethod,
-		&otp.ExpiresAt, &otp.VerifiedAt, &otp.Attempts,
-		&otp.MaxAttempts, &otp.ReferenceID, &metadataJSON, &otp.CreatedAt,
-	)
+// IncrementAttempts atomically consumes one verification attempt and returns the
+// new count. It fails with ErrOTPNotFound when the OTP is no longer verifiable
+// (verified, locked, expired or out of attempts), so concurrent guesses can never
+// exceed max_attempts.
+func (r *OTPRepository) IncrementAttempts(ctx context.Context, id string) (int, error) {
+	query := `
+		UPDATE otps SET
+			attempts = attempts + 1
+		WHERE id = $1
+		  AND verified_at IS NULL
+		  AND locked_at IS NULL
+		  AND attempts < max_attempts
+		  AND expires_at > NOW()
+		RETURNING attempts
+	`
 
+	var attempts int
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&attempts)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("OTP not found")
+		return 0, ErrOTPNotFound
 	}
-
 	if err != nil {
-		return nil, fmt.Errorf("failed to get OTP: %w", err)
+		return 0, fmt.Errorf("failed to update OTP attempts: %w", err)
 	}
 
-	if len(metadataJSON) > 0 {
-		json.Unmarshal(metadataJSON, &otp.Metadata)
-	}
-
-	return &otp, nil
+	return attempts, nil
 }
 
-// UpdateAttempts increments the verification attempts counter
-func (r *OTPRepository) UpdateAttempts(ctx context.Context, id string, attempts int) error {
+// Lock marks an OTP as locked after its attempts are exhausted
+func (r *OTPRepository) Lock(ctx context.Context, id string) error {
 	query := `
 		UPDATE otps SET
-			attempts = $2
+			locked_at = NOW()
 		WHERE id = $1
+		  AND locked_at IS NULL
 	`
 
-	_, err := r.db.ExecContext(ctx, query, id, attempts)
+	_, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
-		return fmt.Errorf("failed to update OTP attempts: %w", err)
+		return fmt.Errorf("failed to lock OTP: %w", err)
 	}
 
 	return nil
 }
 
-// MarkAsVerified marks an OTP as verified
-func (r *OTPRepository) MarkAsVerified(ctx context.Context, id string) error {
+// MarkAsVerified marks an OTP as verified, returning ErrOTPNotFound if it was
+// already verified, locked, expired or invalidated by a resend in the meantime
+func (r *OTPRepository) MarkAsVerified(ctx context.Context, id string) (time.Time, error) {
 	query := `
 		UPDATE otps SET
 			verified_at = NOW()
 		WHERE id = $1
+		  AND verified_at IS NULL
+		  AND locked_at IS NULL
+		  AND expires_at > NOW()
+		RETURNING verified_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query, id)
+	var verifiedAt time.Time
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&verifiedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrOTPNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("failed to mark OTP as verified: %w", err)
+		return time.Time{}, fmt.Errorf("failed to mark OTP as verified: %w", err)
 	}
 
-	return nil
+	return verifiedAt, nil
+}
+
+// ResendWait returns how long until the resend cooldown for a reference has passed,
+// measured from its most recent OTP; zero means a new OTP may be issued now
+func (r *OTPRepository) ResendWait(
+	ctx context.Context,
+	merchantID string,
+	purpose models.OTPPurpose,
+	referenceID string,
+	cooldown time.Duration,
+) (time.Duration, error) {
+	query := `
+		SELECT GREATEST(0, EXTRACT(EPOCH FROM (created_at + $4 * INTERVAL '1 second' - NOW())))
+		FROM otps
+		WHERE merchant_id = $1
+		  AND purpose = $2
+		  AND reference_id = $3
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var seconds float64
+	err := r.db.QueryRowContext(ctx, query, merchantID, purpose, referenceID, cooldown.Seconds()).Scan(&seconds)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check OTP resend cooldown: %w", err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
 }
 
 // CleanupExpired deletes expired OTPs older than specified duration
@@ -209,3 +226,29 @@ func (r *OTPRepository) InvalidateByReferenceID(
 
 	return nil
 }
+
+func scanOTP(row *sql.Row) (*models.OTP, error) {
+	var otp models.OTP
+	var metadataJSON []byte
+
+	err := row.Scan(
+		&otp.ID, &otp.MerchantID, &otp.UserID, &otp.Purpose,
+		&otp.Code, &otp.KeyID, &otp.Recipient, &otp.DeliveryMethod,
+		&otp.ExpiresAt, &otp.VerifiedAt, &otp.LockedAt, &otp.Attempts,
+		&otp.MaxAttempts, &otp.ReferenceID, &otp.Locale, &metadataJSON, &otp.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrOTPNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OTP: %w", err)
+	}
+
+	if len(metadataJSON) > 0 {
+		json.Unmarshal(metadataJSON, &otp.Metadata)
+	}
+
+	return &otp, nil
+}
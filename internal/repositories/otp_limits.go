@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// OTPLimitRepository stores OTP rate-limit counters and verification lockouts
+type OTPLimitRepository struct {
+	db *sql.DB
+}
+
+func NewOTPLimitRepository(db *sql.DB) *OTPLimitRepository {
+	return &OTPLimitRepository{db: db}
+}
+
+// RateLimitBucket is a rate-limit counter and the most hits it allows per window
+type RateLimitBucket struct {
+	Key string
+	Max int
+}
+
+// HitAll counts a hit against every bucket in its current fixed window, all or
+// nothing: if any bucket goes over its limit none of the hits are kept. It
+// returns the index of the first bucket over its limit, or -1 if the hit was
+// allowed, and when that bucket's window ends.
+func (r *OTPLimitRepository) HitAll(
+	ctx context.Context,
+	buckets []RateLimitBucket,
+	window time.Duration,
+) (int, time.Time, error) {
+	query := `
+		INSERT INTO otp_rate_limits (bucket, window_start, count)
+		VALUES ($1, to_timestamp(floor(extract(epoch FROM NOW()) / $2) * $2), 1)
+		ON CONFLICT (bucket, window_start) DO UPDATE SET
+			count = otp_rate_limits.count + 1
+		RETURNING count, window_start
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, bucket := range buckets {
+		var count int
+		var windowStart time.Time
+		err := tx.QueryRowContext(ctx, query, bucket.Key, int64(window.Seconds())).Scan(&count, &windowStart)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("failed to update OTP rate limit: %w", err)
+		}
+		if count > bucket.Max {
+			return i, windowStart.Add(window), nil
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to commit OTP rate limits: %w", err)
+	}
+
+	return -1, time.Time{}, nil
+}
+
+// LockedUntil returns when the subject's lockout ends, or nil if it is not locked out
+func (r *OTPLimitRepository) LockedUntil(ctx context.Context, subject string) (*time.Time, error) {
+	query := `
+		SELECT locked_until
+		FROM otp_lockouts
+		WHERE subject = $1
+		  AND locked_until > NOW()
+	`
+
+	var lockedUntil time.Time
+	err := r.db.QueryRowContext(ctx, query, subject).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OTP lockout: %w", err)
+	}
+
+	return &lockedUntil, nil
+}
+
+// RecordFailure counts a failed verification for subject. Failures older than
+// window start a new count; reaching threshold locks the subject for duration.
+// It returns when the lockout ends, or nil if the subject is not locked out.
+func (r *OTPLimitRepository) RecordFailure(
+	ctx context.Context,
+	subject string,
+	window time.Duration,
+	threshold int,
+	duration time.Duration,
+) (*time.Time, error) {
+	query := `
+		INSERT INTO otp_lockouts AS l (subject, failures, window_start, locked_until)
+		VALUES ($1, 1, NOW(), CASE WHEN $3 <= 1 THEN NOW() + $4 * INTERVAL '1 second' END)
+		ON CONFLICT (subject) DO UPDATE SET
+			failures = CASE WHEN l.window_start < NOW() - $2 * INTERVAL '1 second' THEN 1 ELSE l.failures + 1 END,
+			window_start = CASE WHEN l.window_start < NOW() - $2 * INTERVAL '1 second' THEN NOW() ELSE l.window_start END,
+			locked_until = CASE
+				WHEN (CASE WHEN l.window_start < NOW() - $2 * INTERVAL '1 second' THEN 1 ELSE l.failures + 1 END) >= $3
+				THEN NOW() + $4 * INTERVAL '1 second'
+				ELSE l.locked_until
+			END
+		RETURNING locked_until
+	`
+
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(
+		ctx, query, subject,
+		int64(window.Seconds()), threshold, int64(duration.Seconds()),
+	).Scan(&lockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record OTP verification failure: %w", err)
+	}
+
+	if !lockedUntil.Valid || !lockedUntil.Time.After(time.Now()) {
+		return nil, nil
+	}
+	return &lockedUntil.Time, nil
+}
+
+// ResetFailures clears the failure count for a subject that is not locked out
+func (r *OTPLimitRepository) ResetFailures(ctx context.Context, subject string) error {
+	query := `
+		DELETE FROM otp_lockouts
+		WHERE subject = $1
+		  AND (locked_until IS NULL OR locked_until <= NOW())
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, subject); err != nil {
+		return fmt.Errorf("failed to reset OTP verification failures: %w", err)
+	}
+
+	return nil
+}
+
+// CleanupExpired removes rate-limit windows and lockouts that ended before the given time
+func (r *OTPLimitRepository) CleanupExpired(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, query := range []string{
+		`DELETE FROM otp_rate_limits WHERE window_start < $1`,
+		`DELETE FROM otp_lockouts WHERE window_start < $1 AND (locked_until IS NULL OR locked_until < $1)`,
+	} {
+		result, err := r.db.ExecContext(ctx, query, before)
+		if err != nil {
+			return total, fmt.Errorf("failed to cleanup OTP limits: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		total += rows
+	}
+
+	return total, nil
+}
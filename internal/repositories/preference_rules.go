@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// merchantRulesUserID marks preference rules that belong to the merchant rather than a user
+const merchantRulesUserID = ""
+
+// loadPreferenceRules reads one layer of the (category, delivery type) matrix
+func loadPreferenceRules(ctx context.Context, db *sql.DB, merchantID, userID string) (models.PreferenceMatrix, error) {
+	query := `
+		SELECT category, type, enabled
+		FROM notification_preference_rules
+		WHERE merchant_id = $1 AND user_id = $2
+	`
+
+	rows, err := db.QueryContext(ctx, query, merchantID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preference rules: %w", err)
+	}
+	defer rows.Close()
+
+	matrix := models.PreferenceMatrix{}
+	for rows.Next() {
+		var category models.NotificationChannel
+		var notifType models.NotificationType
+		var enabled bool
+		if err := rows.Scan(&category, &notifType, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference rule: %w", err)
+		}
+		matrix.Set(category, notifType, enabled)
+	}
+
+	return matrix, rows.Err()
+}
+
+// replacePreferenceRules replaces one layer of the matrix within tx
+func replacePreferenceRules(ctx context.Context, tx *sql.Tx, merchantID, userID string, matrix models.PreferenceMatrix) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM notification_preference_rules
+		WHERE merchant_id = $1 AND user_id = $2
+	`, merchantID, userID); err != nil {
+		return fmt.Errorf("failed to clear notification preference rules: %w", err)
+	}
+
+	var categories, types []string
+	var enabled []bool
+	for category, cells := range matrix {
+		for notifType, on := range cells {
+			categories = append(categories, string(category))
+			types = append(types, string(notifType))
+			enabled = append(enabled, on)
+		}
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO notification_preference_rules (merchant_id, user_id, category, type, enabled)
+		SELECT $1, $2, category, type, enabled
+		FROM unnest($3::text[], $4::text[], $5::boolean[]) AS r (category, type, enabled)
+	`
+
+	if _, err := tx.ExecContext(
+		ctx, query,
+		merchantID, userID, pq.Array(categories), pq.Array(types), pq.Array(enabled),
+	); err != nil {
+		return fmt.Errorf("failed to save notification preference rules: %w", err)
+	}
+
+	return nil
+}
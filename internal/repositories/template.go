@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTemplateNotFound        = errors.New("template not found")
+	ErrTemplateVersionNotFound = errors.New("template version not found")
+	ErrTemplateExists          = errors.New("template already exists")
+)
+
+type TemplateRepository struct {
+	db *sql.DB
+}
+
+func NewTemplateRepository(db *sql.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// Create inserts a new template with its initial draft
+func (r *TemplateRepository) Create(ctx context.Context, tmpl *models.NotificationTemplate) error {
+	query := `
+		INSERT INTO notification_templates (
+			name, locale, description, draft_subject, draft_text, draft_html,
+			draft_required_vars, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		tmpl.Name, tmpl.Locale, tmpl.Description, tmpl.DraftSubject, tmpl.DraftText,
+		tmpl.DraftHTML, pq.Array(tmpl.DraftRequiredVars),
+	).Scan(&tmpl.ID, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrTemplateExists
+		}
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+
+	return nil
+}
+
+// GetByName retrieves a template's locale variant and its draft
+func (r *TemplateRepository) GetByName(ctx context.Context, name, locale string) (*models.NotificationTemplate, error) {
+	query := `
+		SELECT id, name, locale, description, draft_subject, draft_text, draft_html,
+		       draft_required_vars, published_version, created_at, updated_at
+		FROM notification_templates
+		WHERE name = $1 AND locale = $2
+	`
+
+	var tmpl models.NotificationTemplate
+	err := r.db.QueryRowContext(ctx, query, name, locale).Scan(
+		&tmpl.ID, &tmpl.Name, &tmpl.Locale, &tmpl.Description, &tmpl.DraftSubject,
+		&tmpl.DraftText, &tmpl.DraftHTML, pq.Array(&tmpl.DraftRequiredVars),
+		&tmpl.PublishedVersion, &tmpl.CreatedAt, &tmpl.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	return &tmpl, nil
+}
+
+// List retrieves all template variants ordered by name and locale
+func (r *TemplateRepository) List(ctx context.Context) ([]*models.NotificationTemplate, error) {
+	query := `
+		SELECT id, name, locale, description, draft_subject, draft_text, draft_html,
+		       draft_required_vars, published_version, created_at, updated_at
+		FROM notification_templates
+		ORDER BY name ASC, locale ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	var tmpls []*models.NotificationTemplate
+	for rows.Next() {
+		var tmpl models.NotificationTemplate
+		err := rows.Scan(
+			&tmpl.ID, &tmpl.Name, &tmpl.Locale, &tmpl.Description, &tmpl.DraftSubject,
+			&tmpl.DraftText, &tmpl.DraftHTML, pq.Array(&tmpl.DraftRequiredVars),
+			&tmpl.PublishedVersion, &tmpl.CreatedAt, &tmpl.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		tmpls = append(tmpls, &tmpl)
+	}
+
+	return tmpls, nil
+}
+
+// UpdateDraft replaces the editable draft of a template's locale variant
+func (r *TemplateRepository) UpdateDraft(ctx context.Context, tmpl *models.NotificationTemplate) error {
+	query := `
+		UPDATE notification_templates SET
+			description = $3,
+			draft_subject = $4,
+			draft_text = $5,
+			draft_html = $6,
+			draft_required_vars = $7,
+			updated_at = NOW()
+		WHERE name = $1 AND locale = $2
+		RETURNING id, published_version, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		tmpl.Name, tmpl.Locale, tmpl.Description, tmpl.DraftSubject, tmpl.DraftText,
+		tmpl.DraftHTML, pq.Array(tmpl.DraftRequiredVars),
+	).Scan(&tmpl.ID, &tmpl.PublishedVersion, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return ErrTemplateNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to update template draft: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a template's locale variant and all of its versions
+func (r *TemplateRepository) Delete(ctx context.Context, name, locale string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notification_templates WHERE name = $1 AND locale = $2`, name, locale)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+// CreateVersion snapshots the current draft into the next immutable version
+func (r *TemplateRepository) CreateVersion(ctx context.Context, name, locale string) (*models.TemplateVersion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the template row so concurrent snapshots get distinct version numbers
+	var templateID string
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM notification_templates WHERE name = $1 AND locale = $2 FOR UPDATE
+	`, name, locale).Scan(&templateID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock template: %w", err)
+	}
+
+	query := `
+		INSERT INTO notification_template_versions (
+			template_id, version, subject, text_body, html_body,
+			required_vars, created_at
+		)
+		SELECT t.id,
+		       COALESCE((SELECT MAX(version) FROM notification_template_versions WHERE template_id = t.id), 0) + 1,
+		       t.draft_subject, t.draft_text, t.draft_html, t.draft_required_vars, NOW()
+		FROM notification_templates t
+		WHERE t.id = $1
+		RETURNING id, template_id, version, subject, text_body, html_body,
+		          required_vars, created_at
+	`
+
+	version := models.TemplateVersion{Name: name, Locale: locale}
+	err = tx.QueryRowContext(ctx, query, templateID).Scan(
+		&version.ID, &version.TemplateID, &version.Version, &version.Subject,
+		&version.TextBody, &version.HTMLBody, pq.Array(&version.RequiredVars),
+		&version.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit template version: %w", err)
+	}
+
+	return &version, nil
+}
+
+// GetVersion retrieves a specific version of a template
+func (r *TemplateRepository) GetVersion(ctx context.Context, name, locale string, version int) (*models.TemplateVersion, error) {
+	query := `
+		SELECT v.id, v.template_id, t.name, t.locale, v.version, v.subject, v.text_body,
+		       v.html_body, v.required_vars, v.created_at
+		FROM notification_template_versions v
+		JOIN notification_templates t ON t.id = v.template_id
+		WHERE t.name = $1
+		  AND t.locale = $2
+		  AND v.version = $3
+	`
+
+	return r.scanVersion(r.db.QueryRowContext(ctx, query, name, locale, version))
+}
+
+// GetPublished retrieves the version a template's published pointer refers to
+func (r *TemplateRepository) GetPublished(ctx context.Context, name, locale string) (*models.TemplateVersion, error) {
+	query := `
+		SELECT v.id, v.template_id, t.name, t.locale, v.version, v.subject, v.text_body,
+		       v.html_body, v.required_vars, v.created_at
+		FROM notification_templates t
+		JOIN notification_template_versions v
+		  ON v.template_id = t.id AND v.version = t.published_version
+		WHERE t.name = $1
+		  AND t.locale = $2
+	`
+
+	return r.scanVersion(r.db.QueryRowContext(ctx, query, name, locale))
+}
+
+// ListVersions retrieves all versions of a template, newest first
+func (r *TemplateRepository) ListVersions(ctx context.Context, name, locale string) ([]*models.TemplateVersion, error) {
+	query := `
+		SELECT v.id, v.template_id, t.name, t.locale, v.version, v.subject, v.text_body,
+		       v.html_body, v.required_vars, v.created_at
+		FROM notification_template_versions v
+		JOIN notification_templates t ON t.id = v.template_id
+		WHERE t.name = $1
+		  AND t.locale = $2
+		ORDER BY v.version DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, name, locale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list template versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*models.TemplateVersion
+	for rows.Next() {
+		var v models.TemplateVersion
+		err := rows.Scan(
+			&v.ID, &v.TemplateID, &v.Name, &v.Locale, &v.Version, &v.Subject,
+			&v.TextBody, &v.HTMLBody, pq.Array(&v.RequiredVars), &v.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template version: %w", err)
+		}
+		versions = append(versions, &v)
+	}
+
+	return versions, nil
+}
+
+// Publish points the template at an existing version
+func (r *TemplateRepository) Publish(ctx context.Context, name, locale string, version int) error {
+	query := `
+		UPDATE notification_templates t SET
+			published_version = $3,
+			updated_at = NOW()
+		WHERE t.name = $1
+		  AND t.locale = $2
+		  AND EXISTS (
+			SELECT 1 FROM notification_template_versions v
+			WHERE v.template_id = t.id AND v.version = $3
+		  )
+	`
+
+	result, err := r.db.ExecContext(ctx, query, name, locale, version)
+	if err != nil {
+		return fmt.Errorf("failed to publish template version: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrTemplateVersionNotFound
+	}
+
+	return nil
+}
+
+func (r *TemplateRepository) scanVersion(row *sql.Row) (*models.TemplateVersion, error) {
+	var v models.TemplateVersion
+	err := row.Scan(
+		&v.ID, &v.TemplateID, &v.Name, &v.Locale, &v.Version, &v.Subject,
+		&v.TextBody, &v.HTMLBody, pq.Array(&v.RequiredVars), &v.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateVersionNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template version: %w", err)
+	}
+
+	return &v, nil
+}
@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+var ErrTOTPEnrollmentNotFound = errors.New("TOTP enrollment not found")
+
+type TOTPRepository struct {
+	db *sql.DB
+}
+
+func NewTOTPRepository(db *sql.DB) *TOTPRepository {
+	return &TOTPRepository{db: db}
+}
+
+// UpsertPending stores a new unconfirmed enrollment, replacing any earlier
+// unconfirmed one for the user. It fails with ErrTOTPAlreadyEnrolled if the user
+// has a confirmed enrollment.
+func (r *TOTPRepository) UpsertPending(ctx context.Context, enrollment *models.TOTPEnrollment) error {
+	query := `
+		INSERT INTO totp_enrollments (
+			merchant_id, user_id, account_name, secret, key_id,
+			last_used_step, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, 0, NOW(), NOW())
+		ON CONFLICT (merchant_id, user_id) DO UPDATE SET
+			account_name = EXCLUDED.account_name,
+			secret = EXCLUDED.secret,
+			key_id = EXCLUDED.key_id,
+			last_used_step = 0,
+			created_at = NOW(),
+			updated_at = NOW()
+		WHERE totp_enrollments.confirmed_at IS NULL
+		RETURNING id, last_used_step, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		enrollment.MerchantID, enrollment.UserID, enrollment.AccountName,
+		enrollment.Secret, enrollment.KeyID,
+	).Scan(&enrollment.ID, &enrollment.LastUsedStep, &enrollment.CreatedAt, &enrollment.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrTOTPAlreadyEnrolled
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create TOTP enrollment: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUser retrieves a user's enrollment, confirmed or not
+func (r *TOTPRepository) GetByUser(ctx context.Context, merchantID, userID string) (*models.TOTPEnrollment, error) {
+	query := `
+		SELECT id, merchant_id, user_id, account_name, secret, key_id,
+		       last_used_step, confirmed_at, created_at, updated_at
+		FROM totp_enrollments
+		WHERE merchant_id = $1
+		  AND user_id = $2
+	`
+
+	var enrollment models.TOTPEnrollment
+	err := r.db.QueryRowContext(ctx, query, merchantID, userID).Scan(
+		&enrollment.ID, &enrollment.MerchantID, &enrollment.UserID, &enrollment.AccountName,
+		&enrollment.Secret, &enrollment.KeyID, &enrollment.LastUsedStep,
+		&enrollment.ConfirmedAt, &enrollment.CreatedAt, &enrollment.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrTOTPEnrollmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TOTP enrollment: %w", err)
+	}
+
+	return &enrollment, nil
+}
+
+// UseStep records step as the latest accepted time step. It returns false when a
+// code for this or a later step was already accepted, so each code works once.
+func (r *TOTPRepository) UseStep(ctx context.Context, id string, step int64) (bool, error) {
+	query := `
+		UPDATE totp_enrollments SET
+			last_used_step = $2,
+			updated_at = NOW()
+		WHERE id = $1
+		  AND last_used_step < $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to update TOTP step: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update TOTP step: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// Confirm activates a pending enrollment and stores its first recovery codes.
+// It fails with ErrTOTPAlreadyEnrolled if the enrollment was already confirmed.
+func (r *TOTPRepository) Confirm(ctx context.Context, id string, recoveryDigests []string) (time.Time, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var confirmedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		UPDATE totp_enrollments SET
+			confirmed_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1
+		  AND confirmed_at IS NULL
+		RETURNING confirmed_at
+	`, id).Scan(&confirmedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, models.ErrTOTPAlreadyEnrolled
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to confirm TOTP enrollment: %w", err)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, id, recoveryDigests); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("failed to commit TOTP confirmation: %w", err)
+	}
+
+	return confirmedAt, nil
+}
+
+// ReplaceRecoveryCodes discards an enrollment's recovery codes, used or not, and stores new ones
+func (r *TOTPRepository) ReplaceRecoveryCodes(ctx context.Context, enrollmentID string, digests []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, enrollmentID, digests); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, enrollmentID string, digests []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE enrollment_id = $1`, enrollmentID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO totp_recovery_codes (enrollment_id, code_hash, created_at)
+		SELECT $1, code_hash, NOW()
+		FROM unnest($2::text[]) AS code_hash
+	`, enrollmentID, pq.Array(digests))
+	if err != nil {
+		return fmt.Errorf("failed to create recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode marks the unused recovery code matching one of digests as
+// used. It returns false if none matched, so each code works once.
+func (r *TOTPRepository) ConsumeRecoveryCode(ctx context.Context, enrollmentID string, digests []string) (bool, error) {
+	query := `
+		UPDATE totp_recovery_codes SET
+			used_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM totp_recovery_codes
+			WHERE enrollment_id = $1
+			  AND code_hash = ANY($2)
+			  AND used_at IS NULL
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, enrollmentID, pq.Array(digests))
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// DeletePendingBefore removes enrollments that were never confirmed, started before the given time
+func (r *TOTPRepository) DeletePendingBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM totp_enrollments
+		WHERE confirmed_at IS NULL
+		  AND created_at < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup pending TOTP enrollments: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
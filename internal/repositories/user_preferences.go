@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// ErrUserPreferencesNotFound is returned when a user has no preference overrides
+var ErrUserPreferencesNotFound = errors.New("user notification preferences not found")
+
+// UserNotificationPreferencesRepository handles per-user preference overrides
+type UserNotificationPreferencesRepository struct {
+	db *sql.DB
+}
+
+func NewUserNotificationPreferencesRepository(db *sql.DB) *UserNotificationPreferencesRepository {
+	return &UserNotificationPreferencesRepository{db: db}
+}
+
+// GetByUser retrieves a user's overrides within a merchant
+func (r *UserNotificationPreferencesRepository) GetByUser(
+	ctx context.Context,
+	merchantID, userID string,
+) (*models.UserNotificationPreferences, error) {
+	query := `
+		SELECT id, merchant_id, user_id,
+		       email_address, phone_number, locale,
+		       timezone, quiet_hours_start, quiet_hours_end, created_at, updated_at
+		FROM user_notification_preferences
+		WHERE merchant_id = $1 AND user_id = $2
+	`
+
+	var prefs models.UserNotificationPreferences
+	err := r.db.QueryRowContext(ctx, query, merchantID, userID).Scan(
+		&prefs.ID, &prefs.MerchantID, &prefs.UserID,
+		&prefs.EmailAddress, &prefs.PhoneNumber, &prefs.Locale,
+		&prefs.Timezone, &prefs.QuietHoursStart, &prefs.QuietHoursEnd,
+		&prefs.CreatedAt, &prefs.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserPreferencesNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user notification preferences: %w", err)
+	}
+
+	prefs.Matrix, err = loadPreferenceRules(ctx, r.db, merchantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// Save creates a user's overrides when expectedUpdatedAt is nil, or otherwise
+// updates them only if they were last updated at expectedUpdatedAt. The change is
+// audited in the same transaction. It returns ErrPreferencesModified if another
+// writer got there first.
+func (r *UserNotificationPreferencesRepository) Save(
+	ctx context.Context,
+	prefs *models.UserNotificationPreferences,
+	expectedUpdatedAt *time.Time,
+	change *models.NotificationPreferenceChange,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{
+		prefs.MerchantID, prefs.UserID,
+		prefs.EmailAddress, prefs.PhoneNumber, prefs.Locale,
+		prefs.Timezone, prefs.QuietHoursStart, prefs.QuietHoursEnd,
+	}
+
+	if expectedUpdatedAt == nil {
+		query := `
+			INSERT INTO user_notification_preferences (
+				merchant_id, user_id, email_address, phone_number, locale,
+				timezone, quiet_hours_start, quiet_hours_end
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (merchant_id, user_id) DO NOTHING
+			RETURNING id, created_at, updated_at
+		`
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&prefs.ID, &prefs.CreatedAt, &prefs.UpdatedAt)
+	} else {
+		query := `
+			UPDATE user_notification_preferences SET
+				email_address = $3,
+				phone_number = $4,
+				locale = $5,
+				timezone = $6,
+				quiet_hours_start = $7,
+				quiet_hours_end = $8,
+				updated_at = NOW()
+			WHERE merchant_id = $1 AND user_id = $2
+			  AND updated_at = $9
+			RETURNING updated_at
+		`
+		err = tx.QueryRowContext(ctx, query, append(args, *expectedUpdatedAt)...).Scan(&prefs.UpdatedAt)
+	}
+	if err == sql.ErrNoRows {
+		return ErrPreferencesModified
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save user notification preferences: %w", err)
+	}
+
+	if err := replacePreferenceRules(ctx, tx, prefs.MerchantID, prefs.UserID, prefs.Matrix); err != nil {
+		return err
+	}
+
+	change.MerchantID = prefs.MerchantID
+	change.UserID = &prefs.UserID
+	if err := insertPreferenceChange(ctx, tx, change); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user preference update: %w", err)
+	}
+
+	return nil
+}
@@ -1,14 +1,27 @@
 package routes
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/kodra-pay/notification-service/internal/handlers"
 	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/providers"
 	"github.com/kodra-pay/notification-service/internal/repositories"
 	"github.com/kodra-pay/notification-service/internal/services"
+	"github.com/kodra-pay/notification-service/internal/templates"
 )
 
-func Register(app *fiber.App, serviceName string) {
+// Background holds the long-running workers wired alongside the HTTP routes
+type Background struct {
+	Dispatcher *services.Dispatcher
+	Scheduler  *services.Scheduler
+}
+
+// Register wires the service's dependencies and routes. It fails if the
+// configuration is incomplete, e.g. a required signing key is missing.
+func Register(app *fiber.App, serviceName string) (*Background, error) {
 	health := handlers.NewHealthHandler(serviceName)
 	health.Register(app)
 
@@ -17,14 +30,166 @@ func Register(app *fiber.App, serviceName string) {
 	// connect DB
 	repo, err := repositories.NewNotificationRepository(cfg.PostgresDSN)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("connect database: %w", err)
 	}
 
-	notifSvc := services.NewNotificationService(repo)
+	prefsRepo := repositories.NewNotificationPreferencesRepository(repo.DB())
+	userPrefsRepo := repositories.NewUserNotificationPreferencesRepository(repo.DB())
+	deviceRepo := repositories.NewDeviceRepository(repo.DB())
+
+	// delivery providers
+	emailSender, err := providers.NewEmailSender(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("configure email provider: %w", err)
+	}
+	smsSender, err := providers.NewSMSSender(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("configure SMS provider: %w", err)
+	}
+	voiceSender, err := providers.NewVoiceSender(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("configure voice provider: %w", err)
+	}
+	pushSender, err := providers.NewPushSender(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("configure push provider: %w", err)
+	}
+
+	templateRepo := repositories.NewTemplateRepository(repo.DB())
+	templateEngine, err := templates.NewEngine(services.NewTemplateSource(templateRepo))
+	if err != nil {
+		return nil, fmt.Errorf("load templates: %w", err)
+	}
+
+	unsubscribeTokens, err := services.NewUnsubscribeTokens(cfg.Unsubscribe)
+	if err != nil {
+		return nil, fmt.Errorf("configure unsubscribe links: %w", err)
+	}
+
+	otpRepo := repositories.NewOTPRepository(repo.DB())
+	otpHasher, err := services.NewOTPHasher(cfg.OTP)
+	if err != nil {
+		return nil, fmt.Errorf("configure OTP hashing: %w", err)
+	}
+	otpSecrets, err := services.NewOTPDeliverySecrets(otpRepo, otpHasher, cfg.OTP.MagicLink.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("configure OTP delivery: %w", err)
+	}
+
+	retryPolicies := services.NewRetryPolicies(cfg.Retry)
+	notifSvcV2 := services.NewNotificationServiceV2(
+		repo, prefsRepo, userPrefsRepo, emailSender, smsSender, voiceSender, pushSender, deviceRepo,
+		retryPolicies, templateEngine, unsubscribeTokens, otpSecrets,
+	)
+	dispatcher := services.NewDispatcher(repo, notifSvcV2, cfg.Dispatcher)
+
+	notifSvc := services.NewNotificationService(repo, notifSvcV2)
 	notifHandler := handlers.NewNotificationHandler(notifSvc)
 
 	app.Post("/notifications", notifHandler.Send)
+	app.Get("/notifications/dead-lettered", notifHandler.ListDeadLettered)
+	app.Get("/notifications/stats", notifHandler.Stats)
+	app.Post("/notifications/:id/requeue", notifHandler.Requeue)
 	app.Get("/notifications/:id", notifHandler.Get)
 	app.Get("/notifications/user/:userID", notifHandler.ListByUserID)
 	app.Get("/notifications/merchant/:merchantID", notifHandler.ListByMerchantID)
+
+	prefsSvc := services.NewPreferencesService(prefsRepo, userPrefsRepo, unsubscribeTokens)
+	prefsHandler := handlers.NewPreferencesHandler(prefsSvc)
+	app.Get("/merchants/:merchantID/notification-preferences", prefsHandler.Get)
+	app.Put("/merchants/:merchantID/notification-preferences", prefsHandler.Replace)
+	app.Patch("/merchants/:merchantID/notification-preferences", prefsHandler.Patch)
+	app.Get("/merchants/:merchantID/users/:userID/notification-preferences", prefsHandler.GetUser)
+	app.Put("/merchants/:merchantID/users/:userID/notification-preferences", prefsHandler.ReplaceUser)
+
+	// Public: the signed token in the link authorises the opt-out
+	unsubscribeHandler := handlers.NewUnsubscribeHandler(prefsSvc)
+	app.Get("/unsubscribe", unsubscribeHandler.Confirm)
+	app.Post("/unsubscribe", unsubscribeHandler.Unsubscribe)
+
+	voiceHandler := handlers.NewVoiceHandler(notifSvcV2, cfg.Voice.CallbackSecret)
+	app.Post("/webhooks/voice/status", voiceHandler.Status)
+
+	templateSvc := services.NewTemplateService(templateRepo, templateEngine, notifSvcV2, cfg.TemplateTestAllowlist)
+	templateHandler := handlers.NewTemplateHandler(templateSvc)
+
+	app.Post("/templates", templateHandler.Create)
+	app.Get("/templates", templateHandler.List)
+	app.Get("/templates/:name", templateHandler.Get)
+	app.Put("/templates/:name/draft", templateHandler.UpdateDraft)
+	app.Delete("/templates/:name", templateHandler.Delete)
+	app.Post("/templates/:name/versions", templateHandler.CreateVersion)
+	app.Get("/templates/:name/versions", templateHandler.ListVersions)
+	app.Get("/templates/:name/versions/:version", templateHandler.GetVersion)
+	app.Post("/templates/:name/publish", templateHandler.Publish)
+	app.Post("/templates/:name/preview", templateHandler.Preview)
+	app.Post("/templates/:name/test-send", templateHandler.TestSend)
+
+	otpLimiter, err := services.NewOTPLimiter(repositories.NewOTPLimitRepository(repo.DB()), otpRepo, cfg.OTP.Limits)
+	if err != nil {
+		return nil, fmt.Errorf("configure OTP rate limits: %w", err)
+	}
+	totpBox, err := services.NewTOTPSecretBox(cfg.OTP.TOTP)
+	if err != nil {
+		return nil, fmt.Errorf("configure TOTP encryption: %w", err)
+	}
+	totpSvc := services.NewTOTPService(repositories.NewTOTPRepository(repo.DB()), totpBox, otpHasher, otpLimiter, cfg.OTP.TOTP)
+	proofSigner, err := services.NewProofSigner(cfg.OTP.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("configure OTP proof signing: %w", err)
+	}
+	otpPolicies, err := services.NewOTPPolicies(cfg.OTP.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("configure OTP policies: %w", err)
+	}
+	otpSvc := services.NewOTPService(
+		otpRepo, notifSvcV2, otpHasher, otpLimiter, totpSvc, proofSigner, otpSecrets, otpPolicies,
+	)
+	otpHandler := handlers.NewOTPHandler(otpSvc, cfg.OTP.MagicLink.RedirectURL)
+	totpHandler := handlers.NewTOTPHandler(totpSvc)
+
+	app.Post("/otp", otpHandler.Generate)
+	app.Post("/otp/verify", otpHandler.Verify)
+	app.Post("/otp/resend", otpHandler.Resend)
+	app.Get("/otp/magic-link", otpHandler.ConfirmLink)
+	app.Post("/otp/magic-link", otpHandler.VerifyLink)
+	app.Post("/otp/proof/verify", otpHandler.VerifyProof)
+	app.Get("/.well-known/jwks.json", otpHandler.JWKS)
+	app.Post("/otp/totp/enroll", totpHandler.Enroll)
+	app.Post("/otp/totp/confirm", totpHandler.Confirm)
+	app.Post("/otp/totp/recovery-codes", totpHandler.RegenerateRecoveryCodes)
+
+	scheduler := services.NewScheduler(repositories.NewJobRepository(repo.DB()), cfg.Scheduler,
+		services.ScheduledJob{
+			Name:     "otp_cleanup",
+			Interval: cfg.Scheduler.OTPCleanupInterval,
+			Run: func(ctx context.Context) (int64, error) {
+				return otpSvc.CleanupExpired(ctx, cfg.Scheduler.OTPRetention)
+			},
+		},
+		services.ScheduledJob{
+			Name:     "otp_limits_cleanup",
+			Interval: cfg.Scheduler.OTPLimitsCleanupInterval,
+			Run:      otpLimiter.CleanupExpired,
+		},
+		services.ScheduledJob{
+			Name:     "totp_pending_cleanup",
+			Interval: cfg.Scheduler.TOTPPendingCleanupInterval,
+			Run: func(ctx context.Context) (int64, error) {
+				return totpSvc.CleanupPending(ctx, cfg.Scheduler.TOTPPendingRetention)
+			},
+		},
+	)
+	adminHandler := handlers.NewAdminHandler(scheduler)
+
+	app.Get("/admin/jobs", adminHandler.Jobs)
+
+	deviceSvc := services.NewDeviceService(deviceRepo)
+	deviceHandler := handlers.NewDeviceHandler(deviceSvc)
+
+	app.Post("/devices", deviceHandler.Register)
+	app.Get("/devices/user/:userID", deviceHandler.ListByUserID)
+	app.Delete("/devices/:id", deviceHandler.Delete)
+
+	return &Background{Dispatcher: dispatcher, Scheduler: scheduler}, nil
 }
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+type DeviceService struct {
+	repo *repositories.DeviceRepository
+}
+
+func NewDeviceService(repo *repositories.DeviceRepository) *DeviceService {
+	return &DeviceService{repo: repo}
+}
+
+// Register stores or refreshes the push token for a user's device
+func (s *DeviceService) Register(ctx context.Context, req dto.RegisterDeviceRequest) (dto.DeviceResponse, error) {
+	platform := models.DevicePlatform(strings.ToLower(req.Platform))
+	switch {
+	case req.UserID == "":
+		return dto.DeviceResponse{}, validationErrorf("user_id is required")
+	case req.DeviceID == "":
+		return dto.DeviceResponse{}, validationErrorf("device_id is required")
+	case strings.TrimSpace(req.Token) == "":
+		return dto.DeviceResponse{}, validationErrorf("token is required")
+	case !models.IsValidPlatform(platform):
+		return dto.DeviceResponse{}, validationErrorf("platform must be one of fcm, apns, web")
+	}
+
+	device := &models.Device{
+		UserID:     req.UserID,
+		MerchantID: req.MerchantID,
+		DeviceID:   req.DeviceID,
+		Platform:   platform,
+		Token:      strings.TrimSpace(req.Token),
+		AppVersion: req.AppVersion,
+	}
+	if err := s.repo.Register(ctx, device); err != nil {
+		return dto.DeviceResponse{}, err
+	}
+
+	return toDeviceResponse(device), nil
+}
+
+func (s *DeviceService) ListByUserID(ctx context.Context, userID string) (dto.DeviceListResponse, error) {
+	devices, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return dto.DeviceListResponse{}, err
+	}
+
+	resp := dto.DeviceListResponse{Devices: []dto.DeviceResponse{}}
+	for _, device := range devices {
+		resp.Devices = append(resp.Devices, toDeviceResponse(device))
+	}
+	return resp, nil
+}
+
+func (s *DeviceService) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func toDeviceResponse(device *models.Device) dto.DeviceResponse {
+	return dto.DeviceResponse{
+		ID:         device.ID,
+		UserID:     device.UserID,
+		MerchantID: device.MerchantID,
+		DeviceID:   device.DeviceID,
+		Platform:   string(device.Platform),
+		AppVersion: device.AppVersion,
+		Active:     device.Active,
+		LastSeenAt: device.LastSeenAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+const defaultClaimTimeout = 5 * time.Minute
+
+// Dispatcher drains queued notifications in the background. Rows are claimed with
+// FOR UPDATE SKIP LOCKED so any number of replicas can run a dispatcher concurrently.
+type Dispatcher struct {
+	repo         *repositories.NotificationRepository
+	notifService *NotificationServiceV2
+	cfg          config.DispatcherConfig
+}
+
+func NewDispatcher(
+	repo *repositories.NotificationRepository,
+	notifService *NotificationServiceV2,
+	cfg config.DispatcherConfig,
+) *Dispatcher {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	// Claims are timed out in whole seconds; anything shorter would make rows
+	// reclaimable while they are still being delivered
+	if cfg.ClaimTimeout < time.Second {
+		if cfg.ClaimTimeout > 0 {
+			log.Printf("Dispatcher claim timeout %s is below one second; using %s", cfg.ClaimTimeout, defaultClaimTimeout)
+		}
+		cfg.ClaimTimeout = defaultClaimTimeout
+	}
+	return &Dispatcher{
+		repo:         repo,
+		notifService: notifService,
+		cfg:          cfg,
+	}
+}
+
+// Run polls for pending notifications until ctx is cancelled. In-flight deliveries
+// are allowed to finish before Run returns.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if !d.cfg.Enabled {
+		log.Printf("Notification dispatcher disabled")
+		return
+	}
+
+	log.Printf("Notification dispatcher started (batch=%d, concurrency=%d)", d.cfg.BatchSize, d.cfg.Concurrency)
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed := d.dispatchBatch(ctx)
+
+		// Keep draining while batches come back full
+		if claimed >= d.cfg.BatchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Notification dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchBatch claims and delivers one batch, returning the number of notifications claimed
+func (d *Dispatcher) dispatchBatch(ctx context.Context) int {
+	notifs, err := d.repo.ClaimPending(ctx, d.cfg.BatchSize, d.cfg.ClaimTimeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to claim pending notifications: %v", err)
+		}
+		return 0
+	}
+
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, notif := range notifs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(notif *models.Notification) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.deliver(notif)
+		}(notif)
+	}
+	wg.Wait()
+
+	return len(notifs)
+}
+
+// deliver sends a single notification. It deliberately does not inherit the
+// dispatcher's context so a shutdown does not abort a send half-way through.
+func (d *Dispatcher) deliver(notif *models.Notification) {
+	ctx := context.Background()
+	if d.cfg.DeliveryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.cfg.DeliveryTimeout)
+		defer cancel()
+	}
+
+	// Non-critical notifications wait for the recipient's quiet hours to end. If
+	// their preferences cannot be read the notification is sent rather than held.
+	deferred, err := d.notifService.DeferForQuietHours(ctx, notif)
+	if err != nil {
+		log.Printf("Failed to check quiet hours for notification %s: %v", notif.ID, err)
+	}
+	if deferred {
+		return
+	}
+
+	if err := d.notifService.Deliver(ctx, notif); err != nil {
+		log.Printf("Failed to deliver %s notification %s: %v", notif.Type, notif.ID, err)
+	}
+}
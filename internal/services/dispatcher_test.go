@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/providers"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+func TestNewDispatcherClaimTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		in   time.Duration
+		want time.Duration
+	}{
+		{"unset", 0, defaultClaimTimeout},
+		{"negative", -time.Minute, defaultClaimTimeout},
+		{"sub-second", 500 * time.Millisecond, defaultClaimTimeout},
+		{"one second", time.Second, time.Second},
+		{"configured", 10 * time.Minute, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDispatcher(nil, nil, config.DispatcherConfig{ClaimTimeout: tt.in})
+			if d.cfg.ClaimTimeout != tt.want {
+				t.Errorf("ClaimTimeout = %s, want %s", d.cfg.ClaimTimeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverAbandonsReclaimedNotificationWithSpentBudget(t *testing.T) {
+	fake, db := newFakeDB(t)
+	push := &fakePushSender{}
+	s := &NotificationServiceV2{
+		repo:       repositories.NewNotificationRepositoryWithDB(db),
+		pushSender: push,
+		retry:      RetryPolicies{models.TypePush: {MaxAttempts: 2}},
+	}
+
+	userID := "user-1"
+	notif := &models.Notification{ID: "notif-1", Type: models.TypePush, UserID: &userID, RetryCount: 2}
+	err := s.Deliver(context.Background(), notif)
+	if err == nil || providers.IsRetryable(err) {
+		t.Fatalf("err = %v, want a permanent error", err)
+	}
+	if len(push.sent) != 0 {
+		t.Errorf("sent to %v, want no delivery", push.sent)
+	}
+	abandoned := fake.execsMatching("status = 'dead_lettered'")
+	if len(abandoned) != 1 || abandoned[0].args[0] != "notif-1" {
+		t.Fatalf("dead-letter updates = %+v", abandoned)
+	}
+	if len(fake.execsMatching("retry_count = retry_count + 1")) != 0 {
+		t.Error("abandoning counted another attempt")
+	}
+}
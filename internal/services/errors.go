@@ -0,0 +1,28 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotificationSuppressed is returned when preferences opt the recipient out of a notification
+var ErrNotificationSuppressed = errors.New("notification disabled by merchant preferences")
+
+// ErrRecipientNotAllowed is returned when a template test-send targets an address outside the internal allowlist
+var ErrRecipientNotAllowed = errors.New("recipient is not on the template test allowlist")
+
+// ErrPreconditionRequired is returned when an update omits the If-Match or updated_at it must be conditional on
+var ErrPreconditionRequired = errors.New("If-Match header or updated_at is required")
+
+// ValidationError reports input that was rejected before any work was done
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func validationErrorf(format string, args ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
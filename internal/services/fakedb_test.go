@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql driver that records statements and answers queries
+// from a callback, so services can be tested without Postgres
+type fakeDB struct {
+	mu        sync.Mutex
+	execs     []fakeStatement
+	commits   int
+	rollbacks int
+
+	// query answers a SELECT or RETURNING statement; nil returns no rows
+	query func(query string, args []driver.Value) (columns []string, rows [][]driver.Value)
+	// affected reports the rows an Exec touched; nil means one
+	affected func(query string, args []driver.Value) int64
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+func newFakeDB(t *testing.T) (*fakeDB, *sql.DB) {
+	t.Helper()
+	f := &fakeDB{}
+	db := sql.OpenDB(fakeConnector{f})
+	t.Cleanup(func() { db.Close() })
+	return f, db
+}
+
+// execsMatching returns the recorded statements containing substr
+func (f *fakeDB) execsMatching(substr string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matched []fakeStatement
+	for _, e := range f.execs {
+		if strings.Contains(e.query, substr) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use fakeConnector") }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{c.db}, nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	args := namedValues(named)
+	c.db.mu.Lock()
+	c.db.execs = append(c.db.execs, fakeStatement{query: query, args: args})
+	affected := c.db.affected
+	c.db.mu.Unlock()
+
+	n := int64(1)
+	if affected != nil {
+		n = affected(query, args)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	args := namedValues(named)
+	c.db.mu.Lock()
+	c.db.execs = append(c.db.execs, fakeStatement{query: query, args: args})
+	answer := c.db.query
+	c.db.mu.Unlock()
+
+	if answer == nil {
+		return &fakeRows{}, nil
+	}
+	columns, rows := answer(query, args)
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func namedValues(named []driver.NamedValue) []driver.Value {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		args[i] = nv.Value
+	}
+	return args
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -5,31 +5,76 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/kodra-pay/notification-service/internal/dto"
 	"github.com/kodra-pay/notification-service/internal/models"
 	"github.com/kodra-pay/notification-service/internal/repositories"
 )
 
 type NotificationService struct {
-	repo *repositories.NotificationRepository
+	repo         *repositories.NotificationRepository
+	notifService *NotificationServiceV2
 }
 
-func NewNotificationService(repo *repositories.NotificationRepository) *NotificationService {
-	return &NotificationService{repo: repo}
+func NewNotificationService(
+	repo *repositories.NotificationRepository,
+	notifService *NotificationServiceV2,
+) *NotificationService {
+	return &NotificationService{repo: repo, notifService: notifService}
 }
 
+// Send queues a notification; delivery happens asynchronously in the Dispatcher
 func (s *NotificationService) Send(ctx context.Context, req dto.NotificationRequest) (dto.NotificationResponse, error) {
+	notifType := models.NotificationType(req.Type)
+	if notifType == "" {
+		notifType = models.TypeEmail
+	}
+	channel, err := requestCategory(req.Channel)
+	if err != nil {
+		return dto.NotificationResponse{}, err
+	}
 	notif := &models.Notification{
-		Channel:   models.NotificationChannel(req.Channel),
-		Recipient: req.To,
-		Subject:   &req.Subject,
-		Message:   req.Body,
-		Status:    models.StatusPending,
+		MerchantID:      req.MerchantID,
+		UserID:          req.UserID,
+		Type:            notifType,
+		Channel:         channel,
+		Recipient:       req.To,
+		Message:         req.Body,
+		TemplateName:    req.TemplateName,
+		TemplateVersion: req.TemplateVersion,
+		TemplateData:    req.TemplateData,
+		Locale:          req.Locale,
+	}
+	if req.Subject != "" {
+		notif.Subject = &req.Subject
 	}
-	if err := s.repo.Create(ctx, notif); err != nil {
+	if err := s.notifService.Send(ctx, notif); err != nil {
 		return dto.NotificationResponse{}, err
 	}
-	return dto.NotificationResponse{ID: notif.ID, Status: string(notif.Status)}, nil
+	return dto.NotificationResponse{
+		ID:              notif.ID,
+		Status:          string(notif.Status),
+		TemplateName:    notif.TemplateName,
+		TemplateVersion: notif.TemplateVersion,
+	}, nil
+}
+
+// requestCategory returns the category of a notification sent through the API,
+// transaction when none is given. Mandatory categories skip quiet hours and
+// unsubscribes, so only the service's own notifications may use them.
+func requestCategory(name string) (models.NotificationChannel, error) {
+	if name == "" {
+		return models.ChannelTransaction, nil
+	}
+	if !preferenceCategoryPattern.MatchString(name) {
+		return "", validationErrorf("invalid category %q", name)
+	}
+	category := models.NotificationChannel(name)
+	if models.IsMandatoryCategory(category) {
+		return "", validationErrorf("%s notifications are reserved for the notification service", category)
+	}
+	return category, nil
 }
 
 func (s *NotificationService) Get(ctx context.Context, id string) (dto.NotificationResponse, error) {
@@ -42,9 +87,11 @@ func (s *NotificationService) Get(ctx context.Context, id string) (dto.Notificat
 		sentAt = notif.SentAt.Format(time.RFC3339)
 	}
 	return dto.NotificationResponse{
-		ID:        notif.ID,
-		Status:    string(notif.Status),
-        		SentAt:    sentAt,
+		ID:              notif.ID,
+		Status:          string(notif.Status),
+		SentAt:          sentAt,
+		TemplateName:    notif.TemplateName,
+		TemplateVersion: notif.TemplateVersion,
         	}, nil
         }
         
@@ -79,4 +126,63 @@ func (s *NotificationService) Get(ctx context.Context, id string) (dto.Notificat
         	}
         	return resp
         }
-        
+
+// ListDeadLettered returns notifications that exhausted their retries or failed permanently
+func (s *NotificationService) ListDeadLettered(ctx context.Context, limit int) (dto.DeadLetterListResponse, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	notifs, err := s.repo.ListDeadLettered(ctx, limit)
+	if err != nil {
+		return dto.DeadLetterListResponse{}, err
+	}
+
+	resp := dto.DeadLetterListResponse{Notifications: []dto.DeadLetterResponse{}}
+	for _, notif := range notifs {
+		errMsg := ""
+		if notif.ErrorMessage != nil {
+			errMsg = *notif.ErrorMessage
+		}
+		resp.Notifications = append(resp.Notifications, dto.DeadLetterResponse{
+			ID:           notif.ID,
+			Type:         string(notif.Type),
+			Channel:      string(notif.Channel),
+			Recipient:    notif.Recipient,
+			ErrorMessage: errMsg,
+			Attempts:     notif.RetryCount,
+			CreatedAt:    notif.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return resp, nil
+}
+
+// Stats summarises notifications created since the given time, excluding template test-sends
+func (s *NotificationService) Stats(ctx context.Context, merchantID *string, since time.Time) (dto.NotificationStatsResponse, error) {
+	counts, err := s.repo.CountByTypeAndStatus(ctx, merchantID, since)
+	if err != nil {
+		return dto.NotificationStatsResponse{}, err
+	}
+
+	resp := dto.NotificationStatsResponse{
+		Since:    since.Format(time.RFC3339),
+		ByStatus: map[string]int{},
+		ByType:   map[string]int{},
+	}
+	for _, c := range counts {
+		resp.Total += c.Count
+		resp.ByStatus[string(c.Status)] += c.Count
+		resp.ByType[string(c.Type)] += c.Count
+	}
+	return resp, nil
+}
+
+// Requeue returns a dead-lettered notification to the delivery queue
+func (s *NotificationService) Requeue(ctx context.Context, id string) (dto.NotificationResponse, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return dto.NotificationResponse{}, repositories.ErrNotificationNotFound
+	}
+	if err := s.repo.Requeue(ctx, id); err != nil {
+		return dto.NotificationResponse{}, err
+	}
+	return dto.NotificationResponse{ID: id, Status: string(models.StatusPending)}, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+func TestRequeueErrors(t *testing.T) {
+	const id = "9f1c1a52-7d0e-4c84-9b55-3c1f0b0a2e11"
+	tests := []struct {
+		name     string
+		id       string
+		requeued bool
+		exists   bool
+		wantErr  error
+	}{
+		{name: "requeued", id: id, requeued: true},
+		{name: "not dead-lettered", id: id, exists: true, wantErr: repositories.ErrNotificationNotDeadLettered},
+		{name: "unknown", id: id, wantErr: repositories.ErrNotificationNotFound},
+		{name: "malformed ID", id: "not-a-uuid", wantErr: repositories.ErrNotificationNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, db := newFakeDB(t)
+			fake.affected = func(string, []driver.Value) int64 {
+				if tt.requeued {
+					return 1
+				}
+				return 0
+			}
+			fake.query = func(string, []driver.Value) ([]string, [][]driver.Value) {
+				return []string{"exists"}, [][]driver.Value{{tt.exists}}
+			}
+			s := NewNotificationService(repositories.NewNotificationRepositoryWithDB(db), nil)
+
+			resp, err := s.Requeue(context.Background(), tt.id)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && resp.Status != "pending" {
+				t.Errorf("status = %q, want pending", resp.Status)
+			}
+		})
+	}
+}
+
+func TestRequestCategory(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    models.NotificationChannel
+		wantErr bool
+	}{
+		{name: "", want: models.ChannelTransaction},
+		{name: "payout", want: models.ChannelPayout},
+		{name: "marketing", want: models.ChannelMarketing},
+		{name: "loyalty_rewards", want: "loyalty_rewards"},
+		{name: "security", wantErr: true},
+		{name: "system", wantErr: true},
+		{name: "Payout", wantErr: true},
+		{name: "payout\x00security", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := requestCategory(tt.name)
+			var validationErr *ValidationError
+			if tt.wantErr {
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("err = %v, want a ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("category = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendRejectsMandatoryCategories(t *testing.T) {
+	s := NewNotificationService(nil, nil)
+	for _, category := range models.MandatoryCategories {
+		_, err := s.Send(context.Background(), dto.NotificationRequest{Channel: string(category), To: "ada@example.com", Body: "hi"})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: err = %v, want a ValidationError", category, err)
+		}
+	}
+}
+
+func TestStatsExcludesTestSends(t *testing.T) {
+	type stored struct {
+		notifType, status string
+		test              bool
+	}
+	notifications := []stored{
+		{"email", "sent", false},
+		{"email", "sent", false},
+		{"sms", "failed", false},
+		{"email", "sent", true},
+		{"sms", "sent", true},
+	}
+
+	fake, db := newFakeDB(t)
+	fake.query = func(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+		// Group the stored notifications the way the query would, honouring its test filter
+		excludeTests := strings.Contains(query, "COALESCE(metadata->>'test', 'false') <> 'true'")
+		counts := map[[2]string]int64{}
+		for _, n := range notifications {
+			if n.test && excludeTests {
+				continue
+			}
+			counts[[2]string{n.notifType, n.status}]++
+		}
+		var rows [][]driver.Value
+		for key, count := range counts {
+			rows = append(rows, []driver.Value{key[0], key[1], count})
+		}
+		return []string{"type", "status", "count"}, rows
+	}
+	s := NewNotificationService(repositories.NewNotificationRepositoryWithDB(db), nil)
+
+	merchantID := "merchant-1"
+	resp, err := s.Stats(context.Background(), &merchantID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	if resp.Total != 3 {
+		t.Errorf("total = %d, want 3 with test sends excluded", resp.Total)
+	}
+	if resp.ByType["email"] != 2 || resp.ByType["sms"] != 1 {
+		t.Errorf("by type = %v, want email:2 sms:1", resp.ByType)
+	}
+	if resp.ByStatus["sent"] != 2 || resp.ByStatus["failed"] != 1 {
+		t.Errorf("by status = %v, want sent:2 failed:1", resp.ByStatus)
+	}
+	if q := fake.execsMatching("COUNT(*)"); len(q) != 1 || !strings.Contains(q[0].query, "merchant_id = $2") {
+		t.Errorf("stats query not scoped to the merchant: %+v", q)
+	}
+}
@@ -2,29 +2,65 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/providers"
 	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/templates"
 )
 
 type NotificationServiceV2 struct {
-	repo      *repositories.NotificationRepository
-	prefsRepo *repositories.NotificationPreferencesRepository
+	repo        *repositories.NotificationRepository
+	prefsRepo   *repositories.NotificationPreferencesRepository
+	userPrefs   *repositories.UserNotificationPreferencesRepository
+	emailSender providers.EmailSender
+	smsSender   providers.SMSSender
+	voiceSender providers.VoiceSender
+	pushSender  providers.PushSender
+	deviceRepo  *repositories.DeviceRepository
+	retry       RetryPolicies
+	templates   *templates.Engine
+	unsubscribe *UnsubscribeTokens
+	otpSecrets  *OTPDeliverySecrets
 }
 
 func NewNotificationServiceV2(
 	repo *repositories.NotificationRepository,
 	prefsRepo *repositories.NotificationPreferencesRepository,
+	userPrefs *repositories.UserNotificationPreferencesRepository,
+	emailSender providers.EmailSender,
+	smsSender providers.SMSSender,
+	voiceSender providers.VoiceSender,
+	pushSender providers.PushSender,
+	deviceRepo *repositories.DeviceRepository,
+	retry RetryPolicies,
+	templateEngine *templates.Engine,
+	unsubscribe *UnsubscribeTokens,
+	otpSecrets *OTPDeliverySecrets,
 ) *NotificationServiceV2 {
 	return &NotificationServiceV2{
-		repo:      repo,
-		prefsRepo: prefsRepo,
+		repo:        repo,
+		prefsRepo:   prefsRepo,
+		userPrefs:   userPrefs,
+		emailSender: emailSender,
+		smsSender:   smsSender,
+		voiceSender: voiceSender,
+		pushSender:  pushSender,
+		deviceRepo:  deviceRepo,
+		retry:       retry,
+		templates:   templateEngine,
+		unsubscribe: unsubscribe,
+		otpSecrets:  otpSecrets,
 	}
 }
 
-// Send creates and sends a notification
+// Send validates a notification against the merchant's preferences, overridden by
+// the user's own where it is addressed to one, and queues it for asynchronous
+// delivery by the Dispatcher
 func (s *NotificationServiceV2) Send(ctx context.Context, notif *models.Notification) error {
 	// Get merchant's notification preferences
 	if notif.MerchantID != nil {
@@ -33,33 +69,219 @@ func (s *NotificationServiceV2) Send(ctx context.Context, notif *models.Notifica
 			log.Printf("Failed to get notification preferences: %v", err)
 			// Continue anyway - use defaults
 		} else {
+			prefs = s.withUserPreferences(ctx, prefs, notif.UserID)
+
 			// Check if notification should be sent based on preferences
 			if !prefs.ShouldSend(notif.Type, notif.Channel) {
-				return fmt.Errorf("notification disabled by merchant preferences")
+				return ErrNotificationSuppressed
+			}
+
+			// Fall back to the merchant's preferred locale
+			if notif.Locale == nil && prefs.Locale != nil {
+				notif.Locale = prefs.Locale
 			}
 
 			// Use preference contact info if not specified
 			if notif.Recipient == "" {
 				if notif.Type == models.TypeEmail && prefs.EmailAddress != nil {
 					notif.Recipient = *prefs.EmailAddress
-				} else if notif.Type == models.TypeSMS && prefs.PhoneNumber != nil {
+				} else if (notif.Type == models.TypeSMS || notif.Type == models.TypeVoice) && prefs.PhoneNumber != nil {
 					notif.Recipient = *prefs.PhoneNumber
 				}
 			}
 		}
 	}
 
+	if notif.Locale != nil {
+		locale := templates.NormalizeLocale(*notif.Locale)
+		if locale == "" {
+			notif.Locale = nil
+		} else {
+			notif.Locale = &locale
+		}
+	}
+
+	// Render the message from its template, if any
+	if err := s.renderForQueue(ctx, notif); err != nil {
+		return err
+	}
+
+	return s.Enqueue(ctx, notif)
+}
+
+// withUserPreferences layers a user's overrides over the merchant's preferences.
+// Without a user, or if their overrides cannot be read, the merchant's apply.
+func (s *NotificationServiceV2) withUserPreferences(
+	ctx context.Context,
+	prefs *models.NotificationPreferences,
+	userID *string,
+) *models.NotificationPreferences {
+	if userID == nil || *userID == "" {
+		return prefs
+	}
+
+	user, err := s.userPrefs.GetByUser(ctx, prefs.MerchantID, *userID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrUserPreferencesNotFound) {
+			log.Printf("Failed to get user notification preferences: %v", err)
+		}
+		return prefs
+	}
+	return prefs.WithUserOverrides(user)
+}
+
+// Enqueue queues an already rendered notification for delivery, bypassing
+// preference checks and template rendering
+func (s *NotificationServiceV2) Enqueue(ctx context.Context, notif *models.Notification) error {
+	// Push notifications fan out to the user's registered devices
+	if notif.Recipient == "" && notif.Type == models.TypePush && notif.UserID != nil {
+		notif.Recipient = *notif.UserID
+	}
+
 	// Validate recipient
 	if notif.Recipient == "" {
-		return fmt.Errorf("recipient is required")
+		return validationErrorf("recipient is required")
 	}
 
-	// Create notification in database
+	// Queue notification for delivery
 	notif.Status = models.StatusPending
 	if err := s.repo.Create(ctx, notif); err != nil {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
+	return nil
+}
+
+// renderForQueue renders a templated notification before it is queued. OTP
+// notifications are rendered only to check their template, as the text holds
+// the code; they are rendered again at delivery.
+func (s *NotificationServiceV2) renderForQueue(ctx context.Context, notif *models.Notification) error {
+	if notif.TemplateName == nil {
+		return nil
+	}
+	if err := s.render(ctx, notif); err != nil {
+		return err
+	}
+
+	if rendersAtDelivery(notif) {
+		notif.Subject = nil
+		notif.Message = ""
+		notif.HTMLMessage = nil
+	}
+	return nil
+}
+
+// rendersAtDelivery reports whether notif delivers an OTP, whose code is only
+// filled in while it is being delivered
+func rendersAtDelivery(notif *models.Notification) bool {
+	_, ok := notif.Metadata[otpIDKey].(string)
+	return ok
+}
+
+// render fills the notification's subject and bodies from its template and data
+func (s *NotificationServiceV2) render(ctx context.Context, notif *models.Notification) error {
+	locale := templates.DefaultLocale
+	if notif.Locale != nil {
+		locale = *notif.Locale
+	}
+
+	data := notif.TemplateData
+	if rendersAtDelivery(notif) {
+		if s.otpSecrets == nil {
+			return fmt.Errorf("OTP delivery is not configured")
+		}
+		var err error
+		if data, err = s.otpSecrets.TemplateData(ctx, notif); err != nil {
+			return err
+		}
+	}
+
+	rendered, err := s.templates.Render(ctx, *notif.TemplateName, locale, notif.TemplateVersion, data)
+	if err != nil {
+		var missingErr *templates.MissingVariablesError
+		if errors.As(err, &missingErr) || errors.Is(err, templates.ErrTemplateNotFound) {
+			return validationErrorf("%v", err)
+		}
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	if rendered.Subject != "" {
+		notif.Subject = &rendered.Subject
+	}
+	notif.Message = rendered.Text
+	if rendered.HTML != "" {
+		notif.HTMLMessage = &rendered.HTML
+	}
+	if rendered.Version > 0 {
+		notif.TemplateVersion = &rendered.Version
+	}
+	// Record which variant the fallback chain settled on
+	if notif.Metadata == nil {
+		notif.Metadata = map[string]interface{}{}
+	}
+	notif.Metadata["template_locale"] = rendered.Locale
+
+	return nil
+}
+
+// DeferForQuietHours returns a claimed notification to the queue if its recipient
+// is in their quiet hours, reporting whether it did. Critical notifications and
+// those without a merchant are never deferred. The recipient's current
+// preferences are used, so a change to their quiet hours applies to
+// notifications already queued.
+func (s *NotificationServiceV2) DeferForQuietHours(ctx context.Context, notif *models.Notification) (bool, error) {
+	if notif.IsCritical() || notif.MerchantID == nil {
+		return false, nil
+	}
+
+	prefs, err := s.prefsRepo.GetByMerchantID(ctx, *notif.MerchantID)
+	if err != nil {
+		return false, err
+	}
+	prefs = s.withUserPreferences(ctx, prefs, notif.UserID)
+
+	now := time.Now()
+	until, quiet := prefs.QuietUntil(now)
+	if !quiet {
+		return false, nil
+	}
+
+	metadata := map[string]interface{}{
+		"deferred_reason": "quiet_hours",
+		"deferred_at":     now.UTC().Format(time.RFC3339),
+		"deferred_until":  until.UTC().Format(time.RFC3339),
+		"quiet_hours":     *prefs.QuietHoursStart + "-" + *prefs.QuietHoursEnd,
+		"timezone":        until.Location().String(),
+	}
+	if err := s.repo.Defer(ctx, notif.ID, until, metadata); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Deliver sends a persisted notification through its channel's provider and records the outcome
+func (s *NotificationServiceV2) Deliver(ctx context.Context, notif *models.Notification) error {
+	// A notification reclaimed from a crashed worker has had each interrupted
+	// attempt counted; give up once they have used the whole budget
+	if policy := s.retry.For(notif.Type); notif.RetryCount >= policy.MaxAttempts {
+		errMsg := fmt.Sprintf("abandoned after %d interrupted delivery attempt(s)", notif.RetryCount)
+		log.Printf("Dead-lettering %s notification %s: %s", notif.Type, notif.ID, errMsg)
+		if err := s.repo.AbandonClaimed(ctx, notif.ID, errMsg); err != nil {
+			log.Printf("Failed to dead-letter notification %s: %v", notif.ID, err)
+		}
+		return providers.Permanent(errors.New(errMsg))
+	}
+
+	// OTP notifications are stored without their text, which would hold the code
+	if rendersAtDelivery(notif) && notif.TemplateName != nil {
+		if err := s.render(ctx, notif); err != nil {
+			err = fmt.Errorf("failed to render notification: %w", err)
+			s.recordFailure(ctx, notif, err)
+			return err
+		}
+	}
+
 	// Send notification based on type
 	var err error
 	switch notif.Type {
@@ -67,69 +289,331 @@ func (s *NotificationServiceV2) Send(ctx context.Context, notif *models.Notifica
 		err = s.sendEmail(ctx, notif)
 	case models.TypeSMS:
 		err = s.sendSMS(ctx, notif)
+	case models.TypeVoice:
+		err = s.sendVoice(ctx, notif)
 	case models.TypePush:
 		err = s.sendPush(ctx, notif)
 	default:
-		err = fmt.Errorf("unsupported notification type: %s", notif.Type)
+		err = providers.Permanent(fmt.Errorf("unsupported notification type: %s", notif.Type))
 	}
 
 	// Update status based on result
 	if err != nil {
-		errMsg := err.Error()
-		s.repo.UpdateStatus(ctx, notif.ID, models.StatusFailed, &errMsg)
+		s.recordFailure(ctx, notif, err)
 		return err
 	}
 
-	s.repo.UpdateStatus(ctx, notif.ID, models.StatusSent, nil)
-	return nil
+	return s.repo.UpdateStatus(ctx, notif.ID, models.StatusSent, nil)
+}
+
+// recordFailure schedules a retry with backoff for transient errors, or dead-letters
+// the notification once the error is permanent or the attempt budget is spent
+func (s *NotificationServiceV2) recordFailure(ctx context.Context, notif *models.Notification, deliveryErr error) {
+	policy := s.retry.For(notif.Type)
+	failedAttempts := notif.RetryCount + 1
+	errMsg := deliveryErr.Error()
+
+	if providers.IsRetryable(deliveryErr) && failedAttempts < policy.MaxAttempts {
+		nextAttemptAt := time.Now().Add(policy.Backoff(failedAttempts))
+		if err := s.repo.ScheduleRetry(ctx, notif.ID, errMsg, nextAttemptAt); err != nil {
+			log.Printf("Failed to schedule retry for notification %s: %v", notif.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Dead-lettering %s notification %s after %d attempt(s): %s", notif.Type, notif.ID, failedAttempts, errMsg)
+	if err := s.repo.MarkDeadLettered(ctx, notif.ID, errMsg); err != nil {
+		log.Printf("Failed to dead-letter notification %s: %v", notif.ID, err)
+	}
 }
 
 // sendEmail sends an email notification
 func (s *NotificationServiceV2) sendEmail(ctx context.Context, notif *models.Notification) error {
-	// TODO: Integrate with email service (SendGrid, AWS SES, etc.)
-	log.Printf("Sending email to %s: %s", notif.Recipient, notif.Message)
-
-	// For now, just log the email
-	// In production, you would call an email provider API here
-	log.Printf("[EMAIL] To: %s", notif.Recipient)
+	msg := &providers.EmailMessage{
+		To:       []string{notif.Recipient},
+		TextBody: notif.Message,
+	}
 	if notif.Subject != nil {
-		log.Printf("[EMAIL] Subject: %s", *notif.Subject)
+		msg.Subject = *notif.Subject
 	}
-	log.Printf("[EMAIL] Message: %s", notif.Message)
+	if notif.HTMLMessage != nil {
+		msg.HTMLBody = *notif.HTMLMessage
+	}
+	msg.Headers = s.unsubscribeHeaders(notif)
 
+	messageID, err := s.emailSender.SendEmail(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	s.recordProviderMessageID(ctx, notif, messageID)
 	return nil
 }
 
+// unsubscribeHeaders returns the RFC 8058 one-click unsubscribe headers for an
+// email, or nil for critical and mandatory notifications, which recipients
+// cannot opt out of, and when unsubscribe links are not configured
+func (s *NotificationServiceV2) unsubscribeHeaders(notif *models.Notification) map[string]string {
+	if !s.unsubscribe.Enabled() || notif.MerchantID == nil ||
+		notif.IsCritical() || models.IsMandatoryCategory(notif.Channel) {
+		return nil
+	}
+
+	claims := UnsubscribeClaims{
+		MerchantID: *notif.MerchantID,
+		Category:   notif.Channel,
+		Type:       models.TypeEmail,
+	}
+	if notif.UserID != nil {
+		claims.UserID = *notif.UserID
+	}
+
+	return map[string]string{
+		"List-Unsubscribe":      "<" + s.unsubscribe.URL(claims) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
 // sendSMS sends an SMS notification
 func (s *NotificationServiceV2) sendSMS(ctx context.Context, notif *models.Notification) error {
-	// TODO: Integrate with SMS service (Twilio, Termii, etc.)
-	log.Printf("Sending SMS to %s: %s", notif.Recipient, notif.Message)
+	messageID, err := s.smsSender.SendSMS(ctx, &providers.SMSMessage{
+		To:   notif.Recipient,
+		Body: notif.Message,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+
+	s.recordProviderMessageID(ctx, notif, messageID)
+	return nil
+}
+
+// sendVoice places a text-to-speech call reading out the notification message.
+// Whether the call was answered is reported later to HandleVoiceStatus.
+func (s *NotificationServiceV2) sendVoice(ctx context.Context, notif *models.Notification) error {
+	msg := &providers.VoiceMessage{
+		To:        notif.Recipient,
+		Text:      notif.Message,
+		Language:  templates.DefaultLocale,
+		Reference: notif.ID,
+	}
+	if notif.Locale != nil {
+		msg.Language = *notif.Locale
+	}
+
+	callID, err := s.voiceSender.PlaceCall(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("failed to place voice call: %w", err)
+	}
+
+	s.recordProviderMessageID(ctx, notif, callID)
+	return nil
+}
+
+// HandleVoiceStatus records a call outcome reported by the voice provider. When a
+// call goes unanswered and the notification names an SMS fallback template, the
+// same template data is sent once by SMS instead.
+func (s *NotificationServiceV2) HandleVoiceStatus(ctx context.Context, status *providers.VoiceCallStatus) error {
+	notif, err := s.repo.GetByID(ctx, status.Reference)
+	if err != nil {
+		return err
+	}
+	if notif.Type != models.TypeVoice {
+		return repositories.ErrNotificationNotFound
+	}
+	if callID, ok := notif.Metadata["provider_message_id"].(string); ok && status.CallID != "" && callID != status.CallID {
+		return repositories.ErrNotificationNotFound
+	}
+
+	if err := s.repo.MergeMetadata(ctx, notif.ID, map[string]interface{}{"call_status": status.Status}); err != nil {
+		return err
+	}
+
+	switch {
+	case status.Answered():
+		return s.repo.UpdateStatus(ctx, notif.ID, models.StatusDelivered, nil)
+	case !status.Unanswered():
+		return nil
+	}
+
+	errMsg := fmt.Sprintf("voice call not answered: %s", status.Status)
+	if err := s.repo.UpdateStatus(ctx, notif.ID, models.StatusFailed, &errMsg); err != nil {
+		return err
+	}
 
-	// For now, just log the SMS
-	// In production, you would call an SMS provider API here
-	log.Printf("[SMS] To: %s", notif.Recipient)
-	log.Printf("[SMS] Message: %s", notif.Message)
+	fallbackTemplate, ok := notif.Metadata["sms_fallback_template"].(string)
+	if !ok || fallbackTemplate == "" {
+		return nil
+	}
+
+	// Providers may report the same outcome more than once; only the first falls back
+	claimed, err := s.repo.ClaimMetadataKey(ctx, notif.ID, "sms_fallback_at", time.Now().UTC().Format(time.RFC3339))
+	if err != nil || !claimed {
+		return err
+	}
+
+	fallback := &models.Notification{
+		MerchantID:   notif.MerchantID,
+		UserID:       notif.UserID,
+		Type:         models.TypeSMS,
+		Channel:      notif.Channel,
+		Recipient:    notif.Recipient,
+		TemplateName: &fallbackTemplate,
+		TemplateData: notif.TemplateData,
+		Locale:       notif.Locale,
+		Metadata:     map[string]interface{}{"fallback_for": notif.ID},
+	}
+	if otpID, ok := notif.Metadata[otpIDKey].(string); ok {
+		fallback.Metadata[otpIDKey] = otpID
+	}
+	if err := s.renderForQueue(ctx, fallback); err != nil {
+		return fmt.Errorf("failed to render SMS fallback: %w", err)
+	}
+	if err := s.Enqueue(ctx, fallback); err != nil {
+		return fmt.Errorf("failed to queue SMS fallback: %w", err)
+	}
+
+	log.Printf("Voice call for notification %s was not answered (%s); queued SMS fallback %s", notif.ID, status.Status, fallback.ID)
+	if err := s.repo.MergeMetadata(ctx, notif.ID, map[string]interface{}{"sms_fallback_notification_id": fallback.ID}); err != nil {
+		log.Printf("Failed to record SMS fallback for notification %s: %v", notif.ID, err)
+	}
 
 	return nil
 }
 
-// sendPush sends a push notification
+// recordProviderMessageID stores the provider's message ID so delivery receipts can be correlated
+func (s *NotificationServiceV2) recordProviderMessageID(ctx context.Context, notif *models.Notification, messageID string) {
+	if messageID == "" {
+		return
+	}
+
+	if notif.Metadata == nil {
+		notif.Metadata = map[string]interface{}{}
+	}
+	notif.Metadata["provider_message_id"] = messageID
+
+	if err := s.repo.MergeMetadata(ctx, notif.ID, map[string]interface{}{"provider_message_id": messageID}); err != nil {
+		log.Printf("Failed to record provider message ID for notification %s: %v", notif.ID, err)
+	}
+}
+
+// sendPush fans a push notification out to every active device of the user,
+// pruning tokens the provider reports as unregistered
 func (s *NotificationServiceV2) sendPush(ctx context.Context, notif *models.Notification) error {
-	// TODO: Integrate with push notification service (Firebase, OneSignal, etc.)
-	log.Printf("Sending push notification to %s: %s", notif.Recipient, notif.Message)
+	if notif.UserID == nil {
+		return providers.Permanent(fmt.Errorf("push notifications require a user_id"))
+	}
+
+	devices, err := s.deviceRepo.ListActiveByUserID(ctx, *notif.UserID)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return providers.Permanent(fmt.Errorf("no active devices registered for user %s", *notif.UserID))
+	}
 
-	// For now, just log the push notification
-	// In production, you would call a push notification provider API here
-	log.Printf("[PUSH] To: %s", notif.Recipient)
+	title := ""
 	if notif.Subject != nil {
-		log.Printf("[PUSH] Title: %s", *notif.Subject)
+		title = *notif.Subject
+	}
+	data := map[string]string{
+		"notification_id": notif.ID,
+		"channel":         string(notif.Channel),
+	}
+
+	// Devices reached on an earlier attempt are skipped so a retry only goes to
+	// the ones that failed
+	deliveredDevices := metadataStrings(notif.Metadata, "push_delivered_devices")
+	messageIDs := metadataStrings(notif.Metadata, "provider_message_ids")
+	alreadyDelivered := make(map[string]bool, len(deliveredDevices))
+	for _, id := range deliveredDevices {
+		alreadyDelivered[id] = true
+	}
+
+	var sent, failed int
+	var lastErr, transientErr error
+	for _, device := range devices {
+		if alreadyDelivered[device.ID] {
+			continue
+		}
+		messageID, err := s.pushSender.SendPush(ctx, &providers.PushMessage{
+			Platform: device.Platform,
+			Token:    device.Token,
+			Title:    title,
+			Body:     notif.Message,
+			Data:     data,
+		})
+		if err != nil {
+			if errors.Is(err, providers.ErrUnregisteredToken) {
+				log.Printf("Pruning unregistered %s device %s for user %s", device.Platform, device.ID, device.UserID)
+				if err := s.deviceRepo.Deactivate(ctx, device.ID); err != nil {
+					log.Printf("Failed to deactivate device %s: %v", device.ID, err)
+				}
+			}
+			if providers.IsRetryable(err) {
+				transientErr = err
+			}
+			lastErr = err
+			failed++
+			continue
+		}
+		sent++
+		deliveredDevices = append(deliveredDevices, device.ID)
+		if messageID != "" {
+			messageIDs = append(messageIDs, messageID)
+		}
+	}
+
+	if sent > 0 {
+		pushMetadata := map[string]interface{}{
+			"push_devices_targeted":  len(devices),
+			"push_devices_delivered": len(deliveredDevices),
+			"push_delivered_devices": deliveredDevices,
+			"provider_message_ids":   messageIDs,
+		}
+		if notif.Metadata == nil {
+			notif.Metadata = map[string]interface{}{}
+		}
+		for k, v := range pushMetadata {
+			notif.Metadata[k] = v
+		}
+		if err := s.repo.MergeMetadata(ctx, notif.ID, pushMetadata); err != nil {
+			log.Printf("Failed to record push delivery metadata for notification %s: %v", notif.ID, err)
+		}
+	}
+
+	// Any transient failure makes the whole notification retryable, even if other
+	// devices were reached
+	if transientErr != nil {
+		return fmt.Errorf("failed to send push notification to %d of %d devices: %w", failed, len(devices), transientErr)
+	}
+	if len(deliveredDevices) == 0 {
+		return fmt.Errorf("failed to send push notification to any device: %w", lastErr)
 	}
-	log.Printf("[PUSH] Message: %s", notif.Message)
 
 	return nil
 }
 
-// SendTransactionNotification sends a transaction-related notification
+// metadataStrings reads a string list from notification metadata, which holds
+// []string before a round trip through the database and []interface{} after
+func metadataStrings(metadata map[string]interface{}, key string) []string {
+	switch v := metadata[key].(type) {
+	case []string:
+		return append([]string(nil), v...)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// SendTransactionNotification sends a transaction-related notification; amount is in
+// the currency's minor units (e.g. kobo for NGN)
 func (s *NotificationServiceV2) SendTransactionNotification(
 	ctx context.Context,
 	merchantID string,
@@ -138,25 +622,24 @@ func (s *NotificationServiceV2) SendTransactionNotification(
 	currency string,
 	status string,
 ) error {
-	subject := "Transaction Notification"
-	message := fmt.Sprintf(
-		"Transaction of %s %d has been %s",
-		currency, amount/100, status,
-	)
-
+	templateName := templates.TransactionNotification
 	notif := &models.Notification{
-		MerchantID: &merchantID,
-		Type:       models.TypeEmail,
-		Channel:    models.ChannelTransaction,
-		Recipient:  recipient,
-		Subject:    &subject,
-		Message:    message,
+		MerchantID:   &merchantID,
+		Type:         models.TypeEmail,
+		Channel:      models.ChannelTransaction,
+		Recipient:    recipient,
+		TemplateName: &templateName,
+		TemplateData: map[string]interface{}{
+			"amount":   amount, // minor units, formatted by the money template func
+			"currency": currency,
+			"status":   status,
+		},
 	}
 
 	return s.Send(ctx, notif)
 }
 
-// SendPayoutNotification sends a payout-related notification
+// SendPayoutNotification sends a payout-related notification; amount is in minor units
 func (s *NotificationServiceV2) SendPayoutNotification(
 	ctx context.Context,
 	merchantID string,
@@ -165,19 +648,18 @@ func (s *NotificationServiceV2) SendPayoutNotification(
 	currency string,
 	status string,
 ) error {
-	subject := "Payout Notification"
-	message := fmt.Sprintf(
-		"Payout of %s %d has been %s",
-		currency, amount/100, status,
-	)
-
+	templateName := templates.PayoutNotification
 	notif := &models.Notification{
-		MerchantID: &merchantID,
-		Type:       models.TypeEmail,
-		Channel:    models.ChannelPayout,
-		Recipient:  recipient,
-		Subject:    &subject,
-		Message:    message,
+		MerchantID:   &merchantID,
+		Type:         models.TypeEmail,
+		Channel:      models.ChannelPayout,
+		Recipient:    recipient,
+		TemplateName: &templateName,
+		TemplateData: map[string]interface{}{
+			"amount":   amount, // minor units, formatted by the money template func
+			"currency": currency,
+			"status":   status,
+		},
 	}
 
 	return s.Send(ctx, notif)
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/providers"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/templates"
+)
+
+func TestDeliverSMSRecordsProviderMessageID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM0123","status":"queued"}`))
+	}))
+	defer srv.Close()
+
+	sender, err := providers.NewTwilioSender(config.TwilioConfig{
+		BaseURL: srv.URL, AccountSID: "AC123", AuthToken: "token", From: "+15005550006",
+	}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewTwilioSender: %v", err)
+	}
+
+	fake, db := newFakeDB(t)
+	s := &NotificationServiceV2{
+		repo:      repositories.NewNotificationRepositoryWithDB(db),
+		smsSender: sender,
+	}
+
+	notif := &models.Notification{
+		ID:        "9f1c1a52-7d0e-4c84-9b55-3c1f0b0a2e11",
+		Type:      models.TypeSMS,
+		Recipient: "+2348012345678",
+		Message:   "Your payment was received",
+	}
+	if err := s.Deliver(context.Background(), notif); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	if got := notif.Metadata["provider_message_id"]; got != "SM0123" {
+		t.Errorf("metadata provider_message_id = %v, want SM0123", got)
+	}
+
+	merges := fake.execsMatching("metadata = COALESCE")
+	if len(merges) != 1 {
+		t.Fatalf("metadata updates = %d, want 1", len(merges))
+	}
+	if id, patch := merges[0].args[0], string(merges[0].args[1].([]byte)); id != notif.ID || patch != `{"provider_message_id":"SM0123"}` {
+		t.Errorf("metadata update = (%v, %s)", id, patch)
+	}
+	if len(fake.execsMatching("status = $2")) != 1 {
+		t.Error("notification was not marked sent")
+	}
+}
+
+// fakePushSender answers each push by device token
+type fakePushSender struct {
+	errs map[string]error
+	sent []string
+}
+
+func (f *fakePushSender) SendPush(_ context.Context, msg *providers.PushMessage) (string, error) {
+	if err := f.errs[msg.Token]; err != nil {
+		return "", err
+	}
+	f.sent = append(f.sent, msg.Token)
+	return "msg-" + msg.Token, nil
+}
+
+func TestSendPushRetriesOnlyFailedDevices(t *testing.T) {
+	devices := []string{"dev-1", "dev-2", "dev-3"}
+	fake, db := newFakeDB(t)
+	fake.query = func(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+		columns := []string{"id", "user_id", "merchant_id", "device_id", "platform", "token",
+			"app_version", "active", "last_seen_at", "created_at", "updated_at"}
+		now := time.Now()
+		var rows [][]driver.Value
+		for _, id := range devices {
+			rows = append(rows, []driver.Value{id, "user-1", nil, id, "fcm", "token-" + id, nil, true, now, now, now})
+		}
+		return columns, rows
+	}
+
+	push := &fakePushSender{errs: map[string]error{
+		"token-dev-2": &providers.ProviderError{Provider: "fcm", StatusCode: http.StatusServiceUnavailable, Message: "Service Unavailable"},
+		"token-dev-3": &providers.ProviderError{Provider: "fcm", StatusCode: http.StatusNotFound, Err: providers.ErrUnregisteredToken},
+	}}
+	s := &NotificationServiceV2{
+		repo:       repositories.NewNotificationRepositoryWithDB(db),
+		deviceRepo: repositories.NewDeviceRepository(db),
+		pushSender: push,
+	}
+
+	userID := "user-1"
+	notif := &models.Notification{ID: "notif-1", Type: models.TypePush, UserID: &userID, Message: "hi"}
+
+	err := s.sendPush(context.Background(), notif)
+	if err == nil || !providers.IsRetryable(err) {
+		t.Fatalf("first attempt err = %v, want a retryable error", err)
+	}
+	if got := notif.Metadata["push_delivered_devices"]; !reflect.DeepEqual(got, []string{"dev-1"}) {
+		t.Errorf("push_delivered_devices = %v, want [dev-1]", got)
+	}
+	if len(fake.execsMatching("active = FALSE")) != 1 {
+		t.Error("unregistered device was not deactivated")
+	}
+
+	// The retry sees the metadata as decoded from JSON and the pruned device gone
+	notif.Metadata = map[string]interface{}{
+		"push_delivered_devices": []interface{}{"dev-1"},
+		"provider_message_ids":   []interface{}{"msg-token-dev-1"},
+	}
+	devices = []string{"dev-1", "dev-2"}
+	delete(push.errs, "token-dev-2")
+	push.sent = nil
+
+	if err := s.sendPush(context.Background(), notif); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if !reflect.DeepEqual(push.sent, []string{"token-dev-2"}) {
+		t.Errorf("retry sent to %v, want only token-dev-2", push.sent)
+	}
+	if got := notif.Metadata["push_delivered_devices"]; !reflect.DeepEqual(got, []string{"dev-1", "dev-2"}) {
+		t.Errorf("push_delivered_devices = %v, want [dev-1 dev-2]", got)
+	}
+	if got := notif.Metadata["provider_message_ids"]; !reflect.DeepEqual(got, []string{"msg-token-dev-1", "msg-token-dev-2"}) {
+		t.Errorf("provider_message_ids = %v", got)
+	}
+}
+
+func TestSendPushPermanentFailuresAreNotRetried(t *testing.T) {
+	fake, db := newFakeDB(t)
+	fake.query = func(string, []driver.Value) ([]string, [][]driver.Value) {
+		now := time.Now()
+		return []string{"id", "user_id", "merchant_id", "device_id", "platform", "token",
+				"app_version", "active", "last_seen_at", "created_at", "updated_at"},
+			[][]driver.Value{{"dev-1", "user-1", nil, "dev-1", "fcm", "token-dev-1", nil, true, now, now, now}}
+	}
+	s := &NotificationServiceV2{
+		repo:       repositories.NewNotificationRepositoryWithDB(db),
+		deviceRepo: repositories.NewDeviceRepository(db),
+		pushSender: &fakePushSender{errs: map[string]error{
+			"token-dev-1": &providers.ProviderError{Provider: "fcm", StatusCode: http.StatusBadRequest, Message: "invalid payload"},
+		}},
+	}
+
+	userID := "user-1"
+	err := s.sendPush(context.Background(), &models.Notification{ID: "notif-1", Type: models.TypePush, UserID: &userID})
+	if err == nil || providers.IsRetryable(err) {
+		t.Fatalf("err = %v, want a permanent error", err)
+	}
+}
+
+// fakeVoiceCall answers the queries HandleVoiceStatus makes about one voice
+// notification, letting ClaimMetadataKey succeed only once like the real update
+type fakeVoiceCall struct {
+	mu       sync.Mutex
+	metadata map[string]interface{}
+}
+
+func (f *fakeVoiceCall) query(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.Contains(query, "INSERT INTO notifications"):
+		return []string{"id", "created_at"}, [][]driver.Value{{"sms-1", time.Now()}}
+	case strings.Contains(query, "FROM notifications"):
+		metadata, _ := json.Marshal(f.metadata)
+		return []string{"id", "merchant_id", "user_id", "type", "channel", "recipient",
+				"subject", "message", "html_message", "template_name", "template_version", "locale", "template_data",
+				"status", "sent_at", "delivered_at", "error_message", "retry_count", "metadata", "created_at"},
+			[][]driver.Value{{"voice-1", "merchant-1", nil, "voice", "transaction", "+2348012345678",
+				nil, "Your transaction was approved", nil, nil, nil, nil,
+				[]byte(`{"amount":150000,"currency":"NGN","status":"approved"}`),
+				"sent", time.Now(), nil, nil, int64(0), metadata, time.Now()}}
+	}
+	return nil, nil
+}
+
+func (f *fakeVoiceCall) affected(query string, args []driver.Value) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.Contains(query, "jsonb_exists") {
+		return 1
+	}
+	key := args[1].(string)
+	if _, claimed := f.metadata[key]; claimed {
+		return 0
+	}
+	f.metadata[key] = string(args[2].([]byte))
+	return 1
+}
+
+func newVoiceCallFixture(t *testing.T) (*NotificationServiceV2, *fakeDB) {
+	t.Helper()
+	call := &fakeVoiceCall{metadata: map[string]interface{}{
+		"provider_message_id":   "CA123",
+		"sms_fallback_template": templates.TransactionNotification,
+	}}
+	fake, db := newFakeDB(t)
+	fake.query = call.query
+	fake.affected = call.affected
+
+	engine, err := templates.NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	s := &NotificationServiceV2{repo: repositories.NewNotificationRepositoryWithDB(db), templates: engine}
+	return s, fake
+}
+
+func TestHandleVoiceStatusFallsBackToSMS(t *testing.T) {
+	for _, status := range []string{"no-answer", "busy", "failed"} {
+		t.Run(status, func(t *testing.T) {
+			s, fake := newVoiceCallFixture(t)
+			ctx := context.Background()
+			callback := &providers.VoiceCallStatus{CallID: "CA123", Reference: "voice-1", Status: status}
+
+			if err := s.HandleVoiceStatus(ctx, callback); err != nil {
+				t.Fatalf("HandleVoiceStatus: %v", err)
+			}
+
+			failed := fake.execsMatching("status = $2")
+			if len(failed) != 1 || failed[0].args[1] != string(models.StatusFailed) {
+				t.Errorf("status updates = %+v, want the call marked failed", failed)
+			}
+			queued := fake.execsMatching("INSERT INTO notifications")
+			if len(queued) != 1 {
+				t.Fatalf("queued %d SMS fallbacks, want 1", len(queued))
+			}
+			if queued[0].args[2] != string(models.TypeSMS) || queued[0].args[4] != "+2348012345678" {
+				t.Errorf("fallback type, recipient = %v, %v", queued[0].args[2], queued[0].args[4])
+			}
+			if msg := queued[0].args[6].(string); !strings.Contains(msg, "₦1,500.00") {
+				t.Errorf("fallback message = %q, want it rendered from the call's template data", msg)
+			}
+
+			// The provider retries its callback; the fallback is not sent again
+			if err := s.HandleVoiceStatus(ctx, callback); err != nil {
+				t.Fatalf("repeated HandleVoiceStatus: %v", err)
+			}
+			if n := len(fake.execsMatching("INSERT INTO notifications")); n != 1 {
+				t.Errorf("queued %d SMS fallbacks after a repeated callback, want 1", n)
+			}
+		})
+	}
+}
+
+func TestHandleVoiceStatusWithoutFallback(t *testing.T) {
+	tests := []struct {
+		status     string
+		wantStatus driver.Value
+	}{
+		{"completed", string(models.StatusDelivered)},
+		{"ringing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			s, fake := newVoiceCallFixture(t)
+
+			err := s.HandleVoiceStatus(context.Background(), &providers.VoiceCallStatus{CallID: "CA123", Reference: "voice-1", Status: tt.status})
+			if err != nil {
+				t.Fatalf("HandleVoiceStatus: %v", err)
+			}
+
+			updates := fake.execsMatching("status = $2")
+			if tt.wantStatus == nil && len(updates) != 0 {
+				t.Errorf("status updated for a call in progress: %+v", updates)
+			}
+			if tt.wantStatus != nil && (len(updates) != 1 || updates[0].args[1] != tt.wantStatus) {
+				t.Errorf("status updates = %+v, want %v", updates, tt.wantStatus)
+			}
+			if n := len(fake.execsMatching("INSERT INTO notifications")); n != 0 {
+				t.Errorf("queued %d SMS fallbacks, want none", n)
+			}
+		})
+	}
+}
+
+func TestHandleVoiceStatusRejectsOtherCalls(t *testing.T) {
+	s, fake := newVoiceCallFixture(t)
+
+	err := s.HandleVoiceStatus(context.Background(), &providers.VoiceCallStatus{CallID: "CA999", Reference: "voice-1", Status: "busy"})
+	if !errors.Is(err, repositories.ErrNotificationNotFound) {
+		t.Fatalf("err = %v, want ErrNotificationNotFound", err)
+	}
+	if n := len(fake.execsMatching("UPDATE notifications")); n != 0 {
+		t.Errorf("%d updates for a callback about another call, want none", n)
+	}
+}
@@ -2,42 +2,82 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"net/mail"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
 	"github.com/kodra-pay/notification-service/internal/models"
 	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/templates"
 )
 
 type OTPService struct {
-	otpRepo       *repositories.OTPRepository
-	notifService  *NotificationServiceV2
+	otpRepo      *repositories.OTPRepository
+	notifService *NotificationServiceV2
+	hasher       *OTPHasher
+	limiter      *OTPLimiter
+	totp         *TOTPService
+	proofs       *ProofSigner
+	secrets      *OTPDeliverySecrets
+	policies     OTPPolicies
 }
 
 func NewOTPService(
 	otpRepo *repositories.OTPRepository,
 	notifService *NotificationServiceV2,
+	hasher *OTPHasher,
+	limiter *OTPLimiter,
+	totp *TOTPService,
+	proofs *ProofSigner,
+	secrets *OTPDeliverySecrets,
+	policies OTPPolicies,
 ) *OTPService {
 	return &OTPService{
 		otpRepo:      otpRepo,
 		notifService: notifService,
+		hasher:       hasher,
+		limiter:      limiter,
+		totp:         totp,
+		proofs:       proofs,
+		secrets:      secrets,
+		policies:     policies,
 	}
 }
 
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
 // Generate creates and sends a new OTP
-func (s *OTPService) Generate(ctx context.Context, req *models.CreateOTPRequest) (*models.OTP, error) {
-	// Set defaults
-	if req.ExpiryMinutes == 0 {
-		req.ExpiryMinutes = 10 // Default 10 minutes
+func (s *OTPService) Generate(ctx context.Context, req *models.CreateOTPRequest) (dto.OTPResponse, error) {
+	if err := validateCreateOTPRequest(req); err != nil {
+		return dto.OTPResponse{}, err
+	}
+	policy, err := s.policies.Apply(req)
+	if err != nil {
+		return dto.OTPResponse{}, err
+	}
+	if req.DeliveryMethod == models.DeliveryTOTP {
+		// The challenge is addressed to the user; the code comes from their app
+		req.Recipient = *req.UserID
 	}
-	if req.MaxAttempts == 0 {
-		req.MaxAttempts = 3 // Default 3 attempts
+	if err := s.limiter.AllowIssue(ctx, req); err != nil {
+		return dto.OTPResponse{}, err
 	}
 
-	// Generate OTP code
-	code, err := models.GenerateCode(6) // 6-digit code
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate OTP code: %w", err)
+	return s.issue(ctx, req, policy)
+}
+
+// issue creates, stores and sends a new OTP for a request the purpose's policy has been applied to
+func (s *OTPService) issue(ctx context.Context, req *models.CreateOTPRequest, policy OTPPolicy) (dto.OTPResponse, error) {
+	if req.MagicLink && !s.secrets.MagicLinksEnabled() {
+		return dto.OTPResponse{}, validationErrorf("magic links are not configured")
 	}
 
 	// Create OTP record
@@ -45,130 +85,456 @@ func (s *OTPService) Generate(ctx context.Context, req *models.CreateOTPRequest)
 		MerchantID:     req.MerchantID,
 		UserID:         req.UserID,
 		Purpose:        req.Purpose,
-		Code:           code,
 		Recipient:      req.Recipient,
 		DeliveryMethod: req.DeliveryMethod,
 		ExpiresAt:      time.Now().Add(time.Duration(req.ExpiryMinutes) * time.Minute),
 		Attempts:       0,
 		MaxAttempts:    req.MaxAttempts,
 		ReferenceID:    req.ReferenceID,
+		Locale:         req.Locale,
 		Metadata:       req.Metadata,
 	}
 
+	// TOTP challenges carry no code of their own; Verify checks the user's authenticator
+	if req.DeliveryMethod == models.DeliveryTOTP {
+		if err := s.totp.CheckEnrolled(ctx, req.MerchantID, *req.UserID); err != nil {
+			return dto.OTPResponse{}, err
+		}
+		if err := s.otpRepo.Create(ctx, otp); err != nil {
+			return dto.OTPResponse{}, fmt.Errorf("failed to create OTP: %w", err)
+		}
+		return toOTPResponse(otp), nil
+	}
+
+	// Generate OTP code
+	code, err := models.GenerateCodeFrom(policy.Alphabet, policy.Length)
+	if err != nil {
+		return dto.OTPResponse{}, fmt.Errorf("failed to generate OTP code: %w", err)
+	}
+
+	// Only the keyed digest of the code is stored
+	digest, keyID := s.hasher.Hash(req.MerchantID, req.Purpose, code)
+	otp.Code = digest
+	otp.KeyID = &keyID
+
 	// Save to database
 	if err := s.otpRepo.Create(ctx, otp); err != nil {
-		return nil, fmt.Errorf("failed to create OTP: %w", err)
+		return dto.OTPResponse{}, fmt.Errorf("failed to create OTP: %w", err)
 	}
 
 	// Send OTP via notification
-	if err := s.sendOTP(ctx, otp); err != nil {
-		return nil, fmt.Errorf("failed to send OTP: %w", err)
+	if err := s.sendOTP(ctx, otp, code, req.MagicLink); err != nil {
+		return dto.OTPResponse{}, fmt.Errorf("failed to send OTP: %w", err)
 	}
 
 	// Don't return the actual code in the response for security
-	otpResponse := *otp
-	otpResponse.Code = "******" // Mask the code
-	return &otpResponse, nil
+	return toOTPResponse(otp), nil
 }
 
-// Verify validates an OTP code
-func (s *OTPService) Verify(ctx context.Context, req *models.VerifyOTPRequest) (*models.OTP, error) {
-	// Get OTP by code
-	otp, err := s.otpRepo.GetByCode(ctx, req.MerchantID, req.Purpose, req.Code)
+// Verify checks a code against the OTP identified by ID or reference. Every
+// failed attempt is counted atomically and the OTP is locked once its attempts
+// are exhausted.
+func (s *OTPService) Verify(ctx context.Context, req *models.VerifyOTPRequest) (dto.VerifyOTPResponse, error) {
+	if err := validateVerifyOTPRequest(req); err != nil {
+		return dto.VerifyOTPResponse{}, err
+	}
+
+	otp, err := s.lookup(ctx, req)
+	if err != nil {
+		return dto.VerifyOTPResponse{}, err
+	}
+
+	if err := s.limiter.CheckLockout(ctx, otp.MerchantID, otp.Recipient); err != nil {
+		return dto.VerifyOTPResponse{}, err
+	}
+
+	if err := otp.CheckVerifiable(); err != nil {
+		return dto.VerifyOTPResponse{}, err
+	}
+
+	// Consume an attempt before comparing so concurrent guesses are bounded by max_attempts
+	attempts, err := s.otpRepo.IncrementAttempts(ctx, otp.ID)
+	if errors.Is(err, repositories.ErrOTPNotFound) {
+		return dto.VerifyOTPResponse{}, s.verifyFailure(ctx, otp.ID)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid or expired OTP")
+		return dto.VerifyOTPResponse{}, err
 	}
+	otp.Attempts = attempts
 
-	// If reference ID is provided, validate it matches
-	if req.ReferenceID != nil && otp.ReferenceID != nil {
-		if *req.ReferenceID != *otp.ReferenceID {
-			return nil, fmt.Errorf("OTP reference mismatch")
+	if err := s.checkCode(ctx, otp, req.Code); err != nil {
+		if !errors.Is(err, models.ErrOTPInvalidCode) && !errors.Is(err, models.ErrTOTPCodeReused) {
+			return dto.VerifyOTPResponse{}, err
+		}
+		// Repeated failures across OTPs lock the recipient out entirely
+		if err := s.limiter.RecordFailure(ctx, otp.MerchantID, otp.Recipient); err != nil {
+			return dto.VerifyOTPResponse{}, err
+		}
+		if otp.Attempts >= otp.MaxAttempts {
+			if err := s.otpRepo.Lock(ctx, otp.ID); err != nil {
+				return dto.VerifyOTPResponse{}, err
+			}
+			return dto.VerifyOTPResponse{}, models.ErrOTPAttemptsExceeded
 		}
+		return dto.VerifyOTPResponse{}, err
 	}
 
-	// Attempt verification
-	err = otp.Verify(req.Code)
+	// Mark as verified
+	verifiedAt, err := s.otpRepo.MarkAsVerified(ctx, otp.ID)
+	if errors.Is(err, repositories.ErrOTPNotFound) {
+		return dto.VerifyOTPResponse{}, s.verifyFailure(ctx, otp.ID)
+	}
+	if err != nil {
+		return dto.VerifyOTPResponse{}, err
+	}
 
-	// Update attempts count
-	s.otpRepo.UpdateAttempts(ctx, otp.ID, otp.Attempts)
+	if err := s.limiter.Reset(ctx, otp.MerchantID, otp.Recipient); err != nil {
+		log.Printf("Failed to reset OTP verification failures: %v", err)
+	}
 
+	return s.verifiedResponse(otp, verifiedAt)
+}
+
+// CheckLink reports whether a magic link token could verify its OTP, without
+// consuming it. Links are opened by mail scanners and prefetchers as well as by
+// their recipients, so following one only shows a confirmation.
+func (s *OTPService) CheckLink(ctx context.Context, token string) error {
+	_, err := s.linkOTP(ctx, token)
+	return err
+}
+
+// VerifyLink consumes a magic link token, verifying the OTP it was issued for.
+// Tokens are checked against the OTP they name, so a forged or altered token
+// fails with ErrOTPInvalidLink without consuming an attempt.
+func (s *OTPService) VerifyLink(ctx context.Context, token string) (dto.VerifyOTPResponse, error) {
+	otp, err := s.linkOTP(ctx, token)
 	if err != nil {
-		return nil, err
+		return dto.VerifyOTPResponse{}, err
 	}
 
-	// Mark as verified
-	if err := s.otpRepo.MarkAsVerified(ctx, otp.ID); err != nil {
-		return nil, fmt.Errorf("failed to mark OTP as verified: %w", err)
+	// Marking the OTP verified is conditional, so a link works only once
+	verifiedAt, err := s.otpRepo.MarkAsVerified(ctx, otp.ID)
+	if errors.Is(err, repositories.ErrOTPNotFound) {
+		return dto.VerifyOTPResponse{}, s.verifyFailure(ctx, otp.ID)
+	}
+	if err != nil {
+		return dto.VerifyOTPResponse{}, err
+	}
+
+	if err := s.limiter.Reset(ctx, otp.MerchantID, otp.Recipient); err != nil {
+		log.Printf("Failed to reset OTP verification failures: %v", err)
 	}
 
+	return s.verifiedResponse(otp, verifiedAt)
+}
+
+// linkOTP returns the OTP a magic link token was signed for, provided its
+// recipient is not locked out and it can still be verified
+func (s *OTPService) linkOTP(ctx context.Context, token string) (*models.OTP, error) {
+	otpID, keyID, signature, ok := parseLinkToken(token)
+	if !ok {
+		return nil, models.ErrOTPInvalidLink
+	}
+
+	otp, err := s.otpRepo.GetByID(ctx, otpID)
+	if errors.Is(err, repositories.ErrOTPNotFound) {
+		return nil, models.ErrOTPInvalidLink
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.hasher.LinkMatches(otp, keyID, signature) {
+		return nil, models.ErrOTPInvalidLink
+	}
+
+	if err := s.limiter.CheckLockout(ctx, otp.MerchantID, otp.Recipient); err != nil {
+		return nil, err
+	}
+	if err := otp.CheckVerifiable(); err != nil {
+		return nil, err
+	}
 	return otp, nil
 }
 
+// verifiedResponse reports a successful verification with a signed proof of it
+func (s *OTPService) verifiedResponse(otp *models.OTP, verifiedAt time.Time) (dto.VerifyOTPResponse, error) {
+	proof, proofExpiresAt, err := s.proofs.Sign(otp, verifiedAt)
+	if err != nil {
+		return dto.VerifyOTPResponse{}, err
+	}
+
+	return dto.VerifyOTPResponse{
+		Verified:       true,
+		OTPID:          otp.ID,
+		Purpose:        string(otp.Purpose),
+		ReferenceID:    otp.ReferenceID,
+		VerifiedAt:     verifiedAt.Format(time.RFC3339),
+		ProofToken:     proof,
+		ProofExpiresAt: proofExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// VerifyProof validates a proof token issued by Verify and checks it was issued
+// for the expected merchant, purpose and reference, where given
+func (s *OTPService) VerifyProof(req *dto.VerifyProofRequest) (dto.VerifyProofResponse, error) {
+	if req.Token == "" {
+		return dto.VerifyProofResponse{}, validationErrorf("token is required")
+	}
+
+	claims, err := s.proofs.Parse(req.Token)
+	if err != nil {
+		return dto.VerifyProofResponse{}, err
+	}
+
+	if req.MerchantID != "" && claims.MerchantID != req.MerchantID {
+		return dto.VerifyProofResponse{}, models.ErrOTPInvalidProof
+	}
+	if req.Purpose != "" && claims.Purpose != req.Purpose {
+		return dto.VerifyProofResponse{}, models.ErrOTPInvalidProof
+	}
+	if req.ReferenceID != nil && (claims.ReferenceID == nil || *claims.ReferenceID != *req.ReferenceID) {
+		return dto.VerifyProofResponse{}, models.ErrOTPInvalidProof
+	}
+
+	return dto.VerifyProofResponse{Valid: true, Claims: *claims}, nil
+}
+
+// JWKS returns the public keys that proof tokens can be validated against
+func (s *OTPService) JWKS() dto.JWKSResponse {
+	return s.proofs.JWKS()
+}
+
+// checkCode compares a submitted code with the stored digest, or for TOTP
+// challenges with the user's authenticator app or recovery codes
+func (s *OTPService) checkCode(ctx context.Context, otp *models.OTP, code string) error {
+	if otp.DeliveryMethod == models.DeliveryTOTP {
+		if otp.UserID == nil {
+			return models.ErrTOTPNotEnrolled
+		}
+		return s.totp.Check(ctx, otp.MerchantID, *otp.UserID, code)
+	}
+
+	// Alphanumeric codes are issued in upper case but may be typed in any case
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !s.hasher.Matches(otp, code) {
+		return models.ErrOTPInvalidCode
+	}
+	return nil
+}
+
+// lookup finds the OTP a verification request refers to, scoped to its merchant and purpose
+func (s *OTPService) lookup(ctx context.Context, req *models.VerifyOTPRequest) (*models.OTP, error) {
+	if req.OTPID != nil && *req.OTPID != "" {
+		otp, err := s.otpRepo.GetByID(ctx, *req.OTPID)
+		if err != nil {
+			return nil, err
+		}
+		if otp.MerchantID != req.MerchantID || otp.Purpose != req.Purpose {
+			return nil, repositories.ErrOTPNotFound
+		}
+		if req.ReferenceID != nil && (otp.ReferenceID == nil || *otp.ReferenceID != *req.ReferenceID) {
+			return nil, repositories.ErrOTPNotFound
+		}
+		return otp, nil
+	}
+
+	return s.otpRepo.GetByReferenceID(ctx, req.MerchantID, req.Purpose, *req.ReferenceID)
+}
+
+// verifyFailure explains why an OTP stopped being verifiable between the lookup
+// and the conditional update, e.g. a concurrent request used the last attempt
+func (s *OTPService) verifyFailure(ctx context.Context, id string) error {
+	otp, err := s.otpRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := otp.CheckVerifiable(); err != nil {
+		return err
+	}
+	return models.ErrOTPAttemptsExceeded
+}
+
 // Resend generates and sends a new OTP for the same purpose and reference
-func (s *OTPService) Resend(ctx context.Context, req *models.CreateOTPRequest) (*models.OTP, error) {
+func (s *OTPService) Resend(ctx context.Context, req *models.CreateOTPRequest) (dto.OTPResponse, error) {
+	if req.ReferenceID == nil || *req.ReferenceID == "" {
+		return dto.OTPResponse{}, validationErrorf("reference_id is required to resend an OTP")
+	}
+	if req.DeliveryMethod == models.DeliveryTOTP {
+		return dto.OTPResponse{}, validationErrorf("totp codes come from the authenticator app and cannot be resent")
+	}
+	if err := validateCreateOTPRequest(req); err != nil {
+		return dto.OTPResponse{}, err
+	}
+	policy, err := s.policies.Apply(req)
+	if err != nil {
+		return dto.OTPResponse{}, err
+	}
+	if err := s.limiter.AllowIssue(ctx, req); err != nil {
+		return dto.OTPResponse{}, err
+	}
+
 	// Invalidate existing OTPs for this reference
-	if req.ReferenceID != nil {
-		s.otpRepo.InvalidateByReferenceID(ctx, req.MerchantID, req.Purpose, *req.ReferenceID)
+	if err := s.otpRepo.InvalidateByReferenceID(ctx, req.MerchantID, req.Purpose, *req.ReferenceID); err != nil {
+		return dto.OTPResponse{}, err
 	}
 
 	// Generate new OTP
-	return s.Generate(ctx, req)
+	return s.issue(ctx, req, policy)
 }
 
-// sendOTP sends the OTP code via the specified delivery method
-func (s *OTPService) sendOTP(ctx context.Context, otp *models.OTP) error {
-	var message string
+// sendOTP sends the OTP code, and a magic link if requested, via the specified
+// delivery method. The notification stores the code sealed and the link not at
+// all; both are filled in only to deliver it.
+func (s *OTPService) sendOTP(ctx context.Context, otp *models.OTP, code string, magicLink bool) error {
 	var notifType models.NotificationType
 
-	// Build OTP message based on purpose
-	switch otp.Purpose {
-	case models.PurposePayout:
-		message = fmt.Sprintf("Your KodraPay payout verification code is: %s. Valid for %d minutes. Do not share this code with anyone.",
-			otp.Code, int(otp.ExpiresAt.Sub(otp.CreatedAt).Minutes()))
-	case models.PurposeWithdrawal:
-		message = fmt.Sprintf("Your KodraPay withdrawal verification code is: %s. Valid for %d minutes. Do not share this code with anyone.",
-			otp.Code, int(otp.ExpiresAt.Sub(otp.CreatedAt).Minutes()))
-	case models.PurposeSettingsChange:
-		message = fmt.Sprintf("Your KodraPay settings change verification code is: %s. Valid for %d minutes.",
-			otp.Code, int(otp.ExpiresAt.Sub(otp.CreatedAt).Minutes()))
-	case models.PurposeLogin:
-		message = fmt.Sprintf("Your KodraPay login verification code is: %s. Valid for %d minutes.",
-			otp.Code, int(otp.ExpiresAt.Sub(otp.CreatedAt).Minutes()))
-	case models.Purpose2FA:
-		message = fmt.Sprintf("Your KodraPay 2FA code is: %s. Valid for %d minutes.",
-			otp.Code, int(otp.ExpiresAt.Sub(otp.CreatedAt).Minutes()))
-	default:
-		message = fmt.Sprintf("Your KodraPay verification code is: %s. Valid for %d minutes.",
-			otp.Code, int(otp.ExpiresAt.Sub(otp.CreatedAt).Minutes()))
+	sealedCode, err := s.secrets.Seal(otp, code)
+	if err != nil {
+		return err
 	}
 
+	// Message is rendered from the purpose-specific OTP template
+	templateName := templates.OTPTemplateName(string(otp.Purpose))
+	// Marks the notification as an OTP so it is never held back for quiet hours
+	metadata := map[string]interface{}{"otp_purpose": string(otp.Purpose), otpIDKey: otp.ID}
+
 	// Determine notification type based on delivery method
 	switch otp.DeliveryMethod {
 	case models.DeliveryEmail:
 		notifType = models.TypeEmail
 	case models.DeliverySMS:
 		notifType = models.TypeSMS
+	case models.DeliveryVoice:
+		// Calls read the code out from the voice script; if the call goes
+		// unanswered the purpose's usual template is sent by SMS instead
+		notifType = models.TypeVoice
+		metadata["sms_fallback_template"] = templateName
+		templateName = templates.OTPVoice
 	default:
 		return fmt.Errorf("unsupported delivery method: %s", otp.DeliveryMethod)
 	}
-
-	// Send notification
-	subject := "KodraPay Verification Code"
+	if magicLink {
+		metadata[otpMagicLinkKey] = true
+	}
 	notif := &models.Notification{
-		MerchantID: &otp.MerchantID,
-		UserID:     otp.UserID,
-		Type:       notifType,
-		Channel:    models.ChannelSecurity,
-		Recipient:  otp.Recipient,
-		Subject:    &subject,
-		Message:    message,
+		MerchantID:   &otp.MerchantID,
+		UserID:       otp.UserID,
+		Type:         notifType,
+		Channel:      models.ChannelSecurity,
+		Recipient:    otp.Recipient,
+		TemplateName: &templateName,
+		Locale:       otp.Locale,
+		TemplateData: map[string]interface{}{
+			otpSealedCodeKey: sealedCode,
+			"expiry_minutes": int(math.Round(otp.ExpiresAt.Sub(otp.CreatedAt).Minutes())),
+		},
+		Metadata: metadata,
 	}
 
 	return s.notifService.Send(ctx, notif)
 }
 
-// CleanupExpired removes expired OTPs from the database
-func (s *OTPService) CleanupExpired(ctx context.Context) (int64, error) {
-	// Delete OTPs expired more than 24 hours ago
-	return s.otpRepo.CleanupExpired(ctx, 24*time.Hour)
+// CleanupExpired removes OTPs that expired more than retention ago
+func (s *OTPService) CleanupExpired(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.otpRepo.CleanupExpired(ctx, retention)
+}
+
+func validateCreateOTPRequest(req *models.CreateOTPRequest) error {
+	if req.MerchantID == "" {
+		return validationErrorf("merchant_id is required")
+	}
+	if !models.IsValidPurpose(req.Purpose) {
+		return validationErrorf("unsupported purpose: %q", req.Purpose)
+	}
+	if !models.IsValidDeliveryMethod(req.DeliveryMethod) {
+		return validationErrorf("delivery_method must be email, sms, voice or totp")
+	}
+	if req.Recipient == "" && req.DeliveryMethod != models.DeliveryTOTP {
+		return validationErrorf("recipient is required")
+	}
+
+	switch req.DeliveryMethod {
+	case models.DeliveryEmail:
+		if addr, err := mail.ParseAddress(req.Recipient); err != nil || addr.Address != req.Recipient {
+			return validationErrorf("recipient must be a valid email address")
+		}
+	case models.DeliverySMS, models.DeliveryVoice:
+		if !e164Pattern.MatchString(req.Recipient) {
+			return validationErrorf("recipient must be an E.164 phone number")
+		}
+	case models.DeliveryTOTP:
+		if req.Purpose != models.Purpose2FA && req.Purpose != models.PurposeLogin {
+			return validationErrorf("delivery_method totp is only available for 2fa and login")
+		}
+		if req.UserID == nil || *req.UserID == "" {
+			return validationErrorf("user_id is required for totp")
+		}
+	}
+	if req.MagicLink && req.DeliveryMethod != models.DeliveryEmail {
+		return validationErrorf("magic_link is only available for email delivery")
+	}
+
+	// Upper bounds depend on the purpose's policy, applied after validation
+	if req.ExpiryMinutes < 0 {
+		return validationErrorf("expiry_minutes must not be negative")
+	}
+	if req.MaxAttempts < 0 {
+		return validationErrorf("max_attempts must not be negative")
+	}
+	return nil
+}
+
+func validateVerifyOTPRequest(req *models.VerifyOTPRequest) error {
+	if req.MerchantID == "" {
+		return validationErrorf("merchant_id is required")
+	}
+	if !models.IsValidPurpose(req.Purpose) {
+		return validationErrorf("unsupported purpose: %q", req.Purpose)
+	}
+	if req.Code == "" {
+		return validationErrorf("code is required")
+	}
+	if (req.OTPID == nil || *req.OTPID == "") && (req.ReferenceID == nil || *req.ReferenceID == "") {
+		return validationErrorf("otp_id or reference_id is required")
+	}
+	if req.OTPID != nil && *req.OTPID != "" {
+		if _, err := uuid.Parse(*req.OTPID); err != nil {
+			return validationErrorf("otp_id must be a UUID")
+		}
+	}
+	return nil
+}
+
+func toOTPResponse(otp *models.OTP) dto.OTPResponse {
+	return dto.OTPResponse{
+		ID:             otp.ID,
+		MerchantID:     otp.MerchantID,
+		Purpose:        string(otp.Purpose),
+		Recipient:      maskRecipient(otp.Recipient),
+		DeliveryMethod: string(otp.DeliveryMethod),
+		ExpiresAt:      otp.ExpiresAt.Format(time.RFC3339),
+		MaxAttempts:    otp.MaxAttempts,
+		ReferenceID:    otp.ReferenceID,
+	}
+}
+
+// maskRecipient hides most of an email local part or phone number, e.g.
+// ad***@example.com and +234******5678
+func maskRecipient(recipient string) string {
+	if local, domain, ok := strings.Cut(recipient, "@"); ok {
+		keep := len(local) / 3
+		if keep > 2 {
+			keep = 2
+		}
+		return local[:keep] + strings.Repeat("*", len(local)-keep) + "@" + domain
+	}
+
+	if len(recipient) <= 4 {
+		return strings.Repeat("*", len(recipient))
+	}
+	prefix := 0
+	if strings.HasPrefix(recipient, "+") && len(recipient) > 8 {
+		prefix = 4
+	}
+	return recipient[:prefix] + strings.Repeat("*", len(recipient)-prefix-4) + recipient[len(recipient)-4:]
 }
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/providers"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+const (
+	// otpSealScope derives the key codes are sealed with from each OTP hash key
+	otpSealScope = "otp_delivery_seal"
+	// otpSealedCodeKey holds the sealed code in an OTP notification's template data
+	otpSealedCodeKey = "sealed_code"
+	// otpIDKey names the OTP an OTP notification delivers, in its metadata
+	otpIDKey = "otp_id"
+	// otpMagicLinkKey marks, in its metadata, an OTP notification that carries a magic link
+	otpMagicLinkKey = "otp_magic_link"
+)
+
+// OTPDeliverySecrets keeps OTP codes and magic links out of stored notifications.
+// The code is sealed with AES-256-GCM under a key derived from the OTP hash
+// pepper, and is only opened while the notification is rendered for delivery.
+// Magic links are not stored at all but signed afresh from the OTP.
+type OTPDeliverySecrets struct {
+	otpRepo  *repositories.OTPRepository
+	hasher   *OTPHasher
+	keys     map[string]cipher.AEAD
+	activeID string
+	// magicLinkBaseURL is where emailed magic links point; empty disables them
+	magicLinkBaseURL string
+}
+
+func NewOTPDeliverySecrets(
+	otpRepo *repositories.OTPRepository,
+	hasher *OTPHasher,
+	magicLinkBaseURL string,
+) (*OTPDeliverySecrets, error) {
+	keys := make(map[string]cipher.AEAD, len(hasher.keys))
+	for id, pepper := range hasher.keys {
+		mac := hmac.New(sha256.New, pepper)
+		mac.Write([]byte(otpSealScope))
+		block, err := aes.NewCipher(mac.Sum(nil))
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive OTP seal key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive OTP seal key %q: %w", id, err)
+		}
+		keys[id] = aead
+	}
+
+	return &OTPDeliverySecrets{
+		otpRepo:          otpRepo,
+		hasher:           hasher,
+		keys:             keys,
+		activeID:         hasher.activeID,
+		magicLinkBaseURL: magicLinkBaseURL,
+	}, nil
+}
+
+// MagicLinksEnabled reports whether a magic link base URL is configured
+func (s *OTPDeliverySecrets) MagicLinksEnabled() bool {
+	return s.magicLinkBaseURL != ""
+}
+
+// Seal encrypts code for delivery of otp, returning the key ID and the
+// nonce-prefixed ciphertext as keyID.base64url
+func (s *OTPDeliverySecrets) Seal(otp *models.OTP, code string) (string, error) {
+	aead := s.keys[s.activeID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(code), sealAAD(otp))
+	return s.activeID + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a code sealed for otp
+func (s *OTPDeliverySecrets) open(otp *models.OTP, sealed string) (string, error) {
+	sep := strings.LastIndex(sealed, ".")
+	if sep < 0 {
+		return "", fmt.Errorf("sealed OTP code is malformed")
+	}
+	aead, ok := s.keys[sealed[:sep]]
+	if !ok {
+		return "", fmt.Errorf("OTP seal key %q is not configured", sealed[:sep])
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(sealed[sep+1:])
+	if err != nil || len(ciphertext) < aead.NonceSize() {
+		return "", fmt.Errorf("sealed OTP code is malformed")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	code, err := aead.Open(nil, nonce, ciphertext, sealAAD(otp))
+	if err != nil {
+		return "", fmt.Errorf("failed to open sealed OTP code: %w", err)
+	}
+	return string(code), nil
+}
+
+// TemplateData returns the template data of an OTP notification with its code
+// opened and its magic link, if any, signed. OTPs that can no longer be verified
+// are not worth delivering, so they fail permanently.
+func (s *OTPDeliverySecrets) TemplateData(ctx context.Context, notif *models.Notification) (map[string]interface{}, error) {
+	otpID, _ := notif.Metadata[otpIDKey].(string)
+	otp, err := s.otpRepo.GetByID(ctx, otpID)
+	if errors.Is(err, repositories.ErrOTPNotFound) {
+		return nil, providers.Permanent(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := otp.CheckVerifiable(); err != nil {
+		return nil, providers.Permanent(fmt.Errorf("OTP is no longer valid: %w", err))
+	}
+
+	data := make(map[string]interface{}, len(notif.TemplateData))
+	for k, v := range notif.TemplateData {
+		data[k] = v
+	}
+	sealed, _ := data[otpSealedCodeKey].(string)
+	delete(data, otpSealedCodeKey)
+
+	code, err := s.open(otp, sealed)
+	if err != nil {
+		return nil, providers.Permanent(err)
+	}
+	data["code"] = code
+
+	// The link is always passed to the template, empty unless one was requested
+	data["link"] = ""
+	if withLink, _ := notif.Metadata[otpMagicLinkKey].(bool); withLink && s.MagicLinksEnabled() {
+		data["link"] = s.magicLinkURL(s.hasher.SignLink(otp))
+	}
+
+	return data, nil
+}
+
+// magicLinkURL appends a signed token to the magic link endpoint
+func (s *OTPDeliverySecrets) magicLinkURL(token string) string {
+	sep := "?"
+	if strings.Contains(s.magicLinkBaseURL, "?") {
+		sep = "&"
+	}
+	return s.magicLinkBaseURL + sep + "token=" + url.QueryEscape(token)
+}
+
+// sealAAD binds a sealed code to the OTP it was issued for
+func sealAAD(otp *models.OTP) []byte {
+	return []byte(otp.MerchantID + "\x00" + otp.ID)
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/providers"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/templates"
+)
+
+// fakeSMSSender records the messages it is asked to send
+type fakeSMSSender struct {
+	sent []*providers.SMSMessage
+}
+
+func (f *fakeSMSSender) SendSMS(_ context.Context, msg *providers.SMSMessage) (string, error) {
+	f.sent = append(f.sent, msg)
+	return "SM1", nil
+}
+
+// fakeEmailSender records the emails it is asked to send
+type fakeEmailSender struct {
+	sent []*providers.EmailMessage
+}
+
+func (f *fakeEmailSender) SendEmail(_ context.Context, msg *providers.EmailMessage) (string, error) {
+	f.sent = append(f.sent, msg)
+	return "", nil
+}
+
+// otpDeliveryFixture wires a notification service able to deliver OTPs against
+// a fake database holding a single OTP
+type otpDeliveryFixture struct {
+	db      *fakeDB
+	svc     *NotificationServiceV2
+	secrets *OTPDeliverySecrets
+	sms     *fakeSMSSender
+	email   *fakeEmailSender
+	hasher  *OTPHasher
+	otp     *models.OTP
+}
+
+func newOTPDeliveryFixture(t *testing.T) *otpDeliveryFixture {
+	t.Helper()
+
+	otp := &models.OTP{
+		ID:             "5d0e8f3c-2b8a-4d7e-9a51-0f6c1e2d3b4a",
+		MerchantID:     "merchant-1",
+		Purpose:        models.PurposeLogin,
+		Recipient:      "+2348012345678",
+		DeliveryMethod: models.DeliverySMS,
+		ExpiresAt:      time.Now().Add(10 * time.Minute),
+		MaxAttempts:    3,
+		CreatedAt:      time.Now(),
+	}
+
+	fake, db := newFakeDB(t)
+	fake.query = func(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "FROM otps"):
+			return []string{"id", "merchant_id", "user_id", "purpose", "code", "key_id", "recipient",
+					"delivery_method", "expires_at", "verified_at", "locked_at", "attempts",
+					"max_attempts", "reference_id", "locale", "metadata", "created_at"},
+				[][]driver.Value{{otp.ID, otp.MerchantID, nil, string(otp.Purpose), "digest", "k1", otp.Recipient,
+					string(otp.DeliveryMethod), otp.ExpiresAt, nil, nil, int64(otp.Attempts),
+					int64(otp.MaxAttempts), nil, nil, nil, otp.CreatedAt}}
+		case strings.Contains(query, "INSERT INTO notifications"):
+			return []string{"id", "created_at"}, [][]driver.Value{{"notif-1", time.Now()}}
+		}
+		return nil, nil
+	}
+
+	hasher, err := NewOTPHasher(config.OTPConfig{HashKeys: map[string]string{"k1": strings.Repeat("p", 32)}})
+	if err != nil {
+		t.Fatalf("NewOTPHasher: %v", err)
+	}
+	secrets, err := NewOTPDeliverySecrets(repositories.NewOTPRepository(db), hasher, "https://pay.example/verify")
+	if err != nil {
+		t.Fatalf("NewOTPDeliverySecrets: %v", err)
+	}
+	engine, err := templates.NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	sms := &fakeSMSSender{}
+	email := &fakeEmailSender{}
+	return &otpDeliveryFixture{
+		db:      fake,
+		secrets: secrets,
+		sms:     sms,
+		email:   email,
+		hasher:  hasher,
+		otp:     otp,
+		svc: &NotificationServiceV2{
+			repo:        repositories.NewNotificationRepositoryWithDB(db),
+			smsSender:   sms,
+			emailSender: email,
+			templates:   engine,
+			otpSecrets:  secrets,
+			unsubscribe: &UnsubscribeTokens{},
+		},
+	}
+}
+
+// notification builds the notification OTPService.sendOTP queues for the code
+func (f *otpDeliveryFixture) notification(t *testing.T, code string) *models.Notification {
+	t.Helper()
+
+	sealed, err := f.secrets.Seal(f.otp, code)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	templateName := templates.OTPTemplateName(string(f.otp.Purpose))
+	return &models.Notification{
+		Type:         models.TypeSMS,
+		Channel:      models.ChannelSecurity,
+		Recipient:    f.otp.Recipient,
+		TemplateName: &templateName,
+		TemplateData: map[string]interface{}{otpSealedCodeKey: sealed, "expiry_minutes": 10},
+		Metadata:     map[string]interface{}{"otp_purpose": string(f.otp.Purpose), otpIDKey: f.otp.ID},
+	}
+}
+
+func TestOTPNotificationNeverStoresCode(t *testing.T) {
+	f := newOTPDeliveryFixture(t)
+	notif := f.notification(t, "482913")
+
+	if err := f.svc.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	inserts := f.db.execsMatching("INSERT INTO notifications")
+	if len(inserts) != 1 {
+		t.Fatalf("inserts = %d, want 1", len(inserts))
+	}
+	for i, arg := range inserts[0].args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		}
+		if strings.Contains(s, "482913") {
+			t.Errorf("insert argument %d stores the code: %s", i+1, s)
+		}
+	}
+
+	// The dispatcher renders the code back in only to send it
+	if err := f.svc.Deliver(context.Background(), notif); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if len(f.sms.sent) != 1 || !strings.Contains(f.sms.sent[0].Body, "482913") {
+		t.Fatalf("sent = %+v, want the code in the body", f.sms.sent)
+	}
+	for _, stmt := range f.db.execsMatching("UPDATE notifications") {
+		for _, arg := range stmt.args {
+			if b, ok := arg.([]byte); ok && strings.Contains(string(b), "482913") {
+				t.Errorf("update stores the code: %s", stmt.query)
+			}
+		}
+	}
+}
+
+func TestOTPNotificationNotDeliveredOnceInvalid(t *testing.T) {
+	f := newOTPDeliveryFixture(t)
+	notif := f.notification(t, "482913")
+	f.otp.ExpiresAt = time.Now().Add(-time.Minute)
+
+	err := f.svc.Deliver(context.Background(), notif)
+	if err == nil || providers.IsRetryable(err) {
+		t.Fatalf("err = %v, want a permanent error", err)
+	}
+	if len(f.sms.sent) != 0 {
+		t.Errorf("sent %d messages for an expired OTP", len(f.sms.sent))
+	}
+}
+
+func TestOTPSealBoundToOTP(t *testing.T) {
+	f := newOTPDeliveryFixture(t)
+	notif := f.notification(t, "482913")
+
+	data, err := f.secrets.TemplateData(context.Background(), notif)
+	if err != nil {
+		t.Fatalf("TemplateData: %v", err)
+	}
+	if data["code"] != "482913" || data[otpSealedCodeKey] != nil {
+		t.Errorf("template data = %v", data)
+	}
+
+	// A code sealed for one OTP cannot be opened as another's
+	f.otp.ID = "0b7d9c1e-4f2a-4c3b-8e6d-5a4f3e2d1c0b"
+	if _, err := f.secrets.TemplateData(context.Background(), notif); err == nil {
+		t.Error("opened a code sealed for a different OTP")
+	}
+}
+
+func TestOTPMagicLinkBuiltAtDelivery(t *testing.T) {
+	f := newOTPDeliveryFixture(t)
+	notif := f.notification(t, "482913")
+	notif.Type = models.TypeEmail
+	notif.Recipient = "ada@example.com"
+	notif.Metadata[otpMagicLinkKey] = true
+
+	if err := f.svc.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	token := f.hasher.SignLink(f.otp)
+	for i, arg := range f.db.execsMatching("INSERT INTO notifications")[0].args {
+		if b, ok := arg.([]byte); ok && (strings.Contains(string(b), "token=") || strings.Contains(string(b), token)) {
+			t.Errorf("insert argument %d stores the link: %s", i+1, b)
+		}
+	}
+
+	if err := f.svc.Deliver(context.Background(), notif); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if len(f.email.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(f.email.sent))
+	}
+	want := "https://pay.example/verify?token=" + url.QueryEscape(token)
+	if !strings.Contains(f.email.sent[0].TextBody, want) {
+		t.Errorf("body = %q, want the link %s", f.email.sent[0].TextBody, want)
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// OTPHasher derives the at-rest form of OTP codes: an HMAC-SHA256 digest keyed by
+// a server-side pepper, bound to the merchant and purpose so a digest cannot be
+// replayed against another OTP scope. Several keys may be configured so peppers
+// can be rotated without invalidating OTPs already in flight.
+type OTPHasher struct {
+	keys     map[string][]byte
+	activeID string
+}
+
+func NewOTPHasher(cfg config.OTPConfig) (*OTPHasher, error) {
+	if len(cfg.HashKeys) == 0 {
+		return nil, fmt.Errorf("at least one OTP hash key is required (OTP_HASH_KEYS)")
+	}
+
+	activeID := cfg.ActiveKeyID
+	if activeID == "" && len(cfg.HashKeys) == 1 {
+		for id := range cfg.HashKeys {
+			activeID = id
+		}
+	}
+	if _, ok := cfg.HashKeys[activeID]; !ok {
+		return nil, fmt.Errorf("active OTP hash key %q is not configured", activeID)
+	}
+
+	keys := make(map[string][]byte, len(cfg.HashKeys))
+	for id, secret := range cfg.HashKeys {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("OTP hash key %q must be at least 32 bytes", id)
+		}
+		keys[id] = []byte(secret)
+	}
+
+	return &OTPHasher{keys: keys, activeID: activeID}, nil
+}
+
+// Hash returns the digest of code under the active key, along with that key's ID
+func (h *OTPHasher) Hash(merchantID string, purpose models.OTPPurpose, code string) (digest, keyID string) {
+	return h.digest(h.keys[h.activeID], merchantID, purpose, code), h.activeID
+}
+
+// Matches reports whether code is the one stored on otp, comparing in constant
+// time. OTPs without a key ID predate hashing and hold the plaintext code.
+func (h *OTPHasher) Matches(otp *models.OTP, code string) bool {
+	expected := code
+	if otp.KeyID != nil {
+		key, ok := h.keys[*otp.KeyID]
+		if !ok {
+			return false
+		}
+		expected = h.digest(key, otp.MerchantID, otp.Purpose, code)
+	}
+	return hmac.Equal([]byte(expected), []byte(otp.Code))
+}
+
+func (h *OTPHasher) digest(key []byte, merchantID string, purpose models.OTPPurpose, code string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(merchantID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// recoveryCodeScope keeps recovery code digests apart from OTP digests
+const recoveryCodeScope models.OTPPurpose = "totp_recovery"
+
+// HashRecoveryCode returns the digest of a TOTP recovery code under the active key,
+// bound to the enrollment it belongs to
+func (h *OTPHasher) HashRecoveryCode(enrollmentID, code string) string {
+	return h.digest(h.keys[h.activeID], enrollmentID, recoveryCodeScope, code)
+}
+
+// RecoveryCodeDigests returns the digest of a recovery code under every configured
+// key, so codes issued before a key rotation still match
+func (h *OTPHasher) RecoveryCodeDigests(enrollmentID, code string) []string {
+	digests := make([]string, 0, len(h.keys))
+	for _, key := range h.keys {
+		digests = append(digests, h.digest(key, enrollmentID, recoveryCodeScope, code))
+	}
+	return digests
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+// RateLimitError is returned when an OTP request exceeds a limit or the
+// recipient is locked out; RetryAfter says when the caller may try again
+type RateLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Message
+}
+
+// OTPLimiter enforces OTP issuance limits and failed-verification lockouts,
+// using Postgres so the limits hold across replicas
+type OTPLimiter struct {
+	repo    *repositories.OTPLimitRepository
+	otpRepo *repositories.OTPRepository
+	cfg     config.OTPLimitsConfig
+}
+
+func NewOTPLimiter(
+	repo *repositories.OTPLimitRepository,
+	otpRepo *repositories.OTPRepository,
+	cfg config.OTPLimitsConfig,
+) (*OTPLimiter, error) {
+	// Windows are counted in whole seconds
+	if (cfg.MaxPerRecipient > 0 || cfg.MaxPerMerchant > 0 || cfg.MaxPerPurpose > 0) && cfg.Window < time.Second {
+		return nil, fmt.Errorf("OTP rate limit window must be at least 1s (OTP_RATE_WINDOW)")
+	}
+	if cfg.LockoutThreshold > 0 {
+		if cfg.LockoutWindow < time.Second {
+			return nil, fmt.Errorf("OTP lockout window must be at least 1s (OTP_LOCKOUT_WINDOW)")
+		}
+		if cfg.LockoutDuration < time.Second {
+			return nil, fmt.Errorf("OTP lockout duration must be at least 1s (OTP_LOCKOUT_DURATION)")
+		}
+	}
+
+	return &OTPLimiter{repo: repo, otpRepo: otpRepo, cfg: cfg}, nil
+}
+
+// AllowIssue checks whether a new OTP may be issued for the request, counting it
+// against the per-recipient, per-merchant and per-purpose windows only if all of
+// them allow it
+func (l *OTPLimiter) AllowIssue(ctx context.Context, req *models.CreateOTPRequest) error {
+	if err := l.CheckLockout(ctx, req.MerchantID, req.Recipient); err != nil {
+		return err
+	}
+
+	if req.ReferenceID != nil && l.cfg.ResendCooldown > 0 {
+		wait, err := l.otpRepo.ResendWait(ctx, req.MerchantID, req.Purpose, *req.ReferenceID, l.cfg.ResendCooldown)
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return &RateLimitError{Message: "OTP was sent recently, please wait before requesting another", RetryAfter: wait}
+		}
+	}
+
+	limits := []struct {
+		bucket string
+		max    int
+		scope  string
+	}{
+		{fmt.Sprintf("recipient:%s:%s", req.MerchantID, req.Recipient), l.cfg.MaxPerRecipient, "recipient"},
+		{fmt.Sprintf("merchant:%s", req.MerchantID), l.cfg.MaxPerMerchant, "merchant"},
+		{fmt.Sprintf("purpose:%s:%s", req.MerchantID, req.Purpose), l.cfg.MaxPerPurpose, "purpose"},
+	}
+	var buckets []repositories.RateLimitBucket
+	var scopes []string
+	for _, limit := range limits {
+		if limit.max <= 0 {
+			continue
+		}
+		buckets = append(buckets, repositories.RateLimitBucket{Key: limit.bucket, Max: limit.max})
+		scopes = append(scopes, limit.scope)
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	// A rejected request is not counted against any window, so requests refused
+	// by one limit do not use up the others
+	rejected, resetAt, err := l.repo.HitAll(ctx, buckets, l.cfg.Window)
+	if err != nil {
+		return err
+	}
+	if rejected >= 0 {
+		return &RateLimitError{
+			Message:    fmt.Sprintf("too many OTP requests for this %s", scopes[rejected]),
+			RetryAfter: time.Until(resetAt),
+		}
+	}
+
+	return nil
+}
+
+// CheckLockout returns a RateLimitError if the recipient is locked out after repeated failed verifications
+func (l *OTPLimiter) CheckLockout(ctx context.Context, merchantID, recipient string) error {
+	if l.cfg.LockoutThreshold <= 0 {
+		return nil
+	}
+
+	lockedUntil, err := l.repo.LockedUntil(ctx, lockoutSubject(merchantID, recipient))
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return lockedOutError(*lockedUntil)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed verification and returns a RateLimitError if it
+// triggered a lockout
+func (l *OTPLimiter) RecordFailure(ctx context.Context, merchantID, recipient string) error {
+	if l.cfg.LockoutThreshold <= 0 {
+		return nil
+	}
+
+	lockedUntil, err := l.repo.RecordFailure(
+		ctx, lockoutSubject(merchantID, recipient),
+		l.cfg.LockoutWindow, l.cfg.LockoutThreshold, l.cfg.LockoutDuration,
+	)
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return lockedOutError(*lockedUntil)
+	}
+	return nil
+}
+
+// Reset clears the failed verification count after a successful verification
+func (l *OTPLimiter) Reset(ctx context.Context, merchantID, recipient string) error {
+	if l.cfg.LockoutThreshold <= 0 {
+		return nil
+	}
+	return l.repo.ResetFailures(ctx, lockoutSubject(merchantID, recipient))
+}
+
+// CleanupExpired removes rate-limit windows and lockouts that can no longer affect a request
+func (l *OTPLimiter) CleanupExpired(ctx context.Context) (int64, error) {
+	horizon := l.cfg.Window
+	if l.cfg.LockoutWindow > horizon {
+		horizon = l.cfg.LockoutWindow
+	}
+	return l.repo.CleanupExpired(ctx, time.Now().Add(-horizon))
+}
+
+func lockoutSubject(merchantID, recipient string) string {
+	return fmt.Sprintf("%s:%s", merchantID, recipient)
+}
+
+func lockedOutError(until time.Time) error {
+	return &RateLimitError{
+		Message:    "too many failed verifications, try again later",
+		RetryAfter: time.Until(until),
+	}
+}
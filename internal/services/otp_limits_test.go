@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+func TestNewOTPLimiterRejectsSubSecondWindows(t *testing.T) {
+	valid := config.OTPLimitsConfig{
+		Window: time.Minute, MaxPerRecipient: 5,
+		LockoutThreshold: 3, LockoutWindow: time.Minute, LockoutDuration: time.Minute,
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*config.OTPLimitsConfig)
+		wantErr bool
+	}{
+		{name: "valid", mutate: func(*config.OTPLimitsConfig) {}},
+		{name: "sub-second window", mutate: func(c *config.OTPLimitsConfig) { c.Window = 500 * time.Millisecond }, wantErr: true},
+		{name: "zero window without limits", mutate: func(c *config.OTPLimitsConfig) { c.Window, c.MaxPerRecipient = 0, 0 }},
+		{name: "sub-second lockout window", mutate: func(c *config.OTPLimitsConfig) { c.LockoutWindow = time.Millisecond }, wantErr: true},
+		{name: "zero lockout duration", mutate: func(c *config.OTPLimitsConfig) { c.LockoutDuration = 0 }, wantErr: true},
+		{name: "lockouts disabled", mutate: func(c *config.OTPLimitsConfig) { c.LockoutThreshold, c.LockoutDuration = 0, 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.mutate(&cfg)
+			_, err := NewOTPLimiter(nil, nil, cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowIssueCountsOnlyAdmittedRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		counts     map[string]int
+		wantScope  string
+		wantCommit bool
+	}{
+		{name: "admitted", counts: map[string]int{}, wantCommit: true},
+		{name: "recipient over limit", counts: map[string]int{"recipient:": 3}, wantScope: "recipient"},
+		{name: "merchant over limit", counts: map[string]int{"merchant:": 11}, wantScope: "merchant"},
+		{name: "purpose over limit", counts: map[string]int{"purpose:": 6}, wantScope: "purpose"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, db := newFakeDB(t)
+			fake.query = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+				count := 1
+				for prefix, n := range tt.counts {
+					if strings.HasPrefix(args[0].(string), prefix) {
+						count = n
+					}
+				}
+				return []string{"count", "window_start"}, [][]driver.Value{{int64(count), time.Now()}}
+			}
+			limiter, err := NewOTPLimiter(repositories.NewOTPLimitRepository(db), nil, config.OTPLimitsConfig{
+				Window: time.Minute, MaxPerRecipient: 2, MaxPerMerchant: 10, MaxPerPurpose: 5,
+			})
+			if err != nil {
+				t.Fatalf("NewOTPLimiter: %v", err)
+			}
+
+			err = limiter.AllowIssue(context.Background(), &models.CreateOTPRequest{
+				MerchantID: "merchant-1", Purpose: models.PurposeLogin, Recipient: "+2348012345678",
+			})
+			var rateErr *RateLimitError
+			switch {
+			case tt.wantScope == "" && err != nil:
+				t.Fatalf("AllowIssue: %v", err)
+			case tt.wantScope != "" && (!errors.As(err, &rateErr) || !strings.HasSuffix(rateErr.Message, tt.wantScope)):
+				t.Fatalf("err = %v, want a %s rate limit", err, tt.wantScope)
+			}
+			if tt.wantCommit != (fake.commits == 1) || tt.wantCommit == (fake.rollbacks == 1) {
+				t.Errorf("commits = %d, rollbacks = %d; want hits kept %v", fake.commits, fake.rollbacks, tt.wantCommit)
+			}
+		})
+	}
+}
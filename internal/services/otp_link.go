@@ -0,0 +1,55 @@
+package services
+
+import (
+	"crypto/hmac"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// magicLinkScope keeps link signatures apart from OTP code digests
+const magicLinkScope = "magic_link"
+
+// SignLink returns a magic link token for otp: its ID, the signing key's ID and
+// an HMAC over the OTP's merchant, purpose and reference. The token carries no
+// state of its own; it expires with the OTP and is single-use because verifying
+// it marks the OTP verified.
+func (h *OTPHasher) SignLink(otp *models.OTP) string {
+	return otp.ID + "." + h.activeID + "." + h.linkSignature(h.keys[h.activeID], otp)
+}
+
+// parseLinkToken splits a magic link token into the OTP ID, key ID and signature
+func parseLinkToken(token string) (otpID, keyID, signature string, ok bool) {
+	otpID, rest, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", "", false
+	}
+	sep := strings.LastIndex(rest, ".")
+	if sep <= 0 {
+		return "", "", "", false
+	}
+	if _, err := uuid.Parse(otpID); err != nil {
+		return "", "", "", false
+	}
+	return otpID, rest[:sep], rest[sep+1:], true
+}
+
+// LinkMatches reports whether signature was issued for otp under keyID
+func (h *OTPHasher) LinkMatches(otp *models.OTP, keyID, signature string) bool {
+	key, ok := h.keys[keyID]
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(h.linkSignature(key, otp)), []byte(signature))
+}
+
+func (h *OTPHasher) linkSignature(key []byte, otp *models.OTP) string {
+	reference := ""
+	if otp.ReferenceID != nil {
+		reference = *otp.ReferenceID
+	}
+	claims := strings.Join([]string{magicLinkScope, otp.ID, reference}, "\x00")
+	return h.digest(key, otp.MerchantID, otp.Purpose, claims)
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+// fakeOTPRow answers the OTP queries magic links make and applies
+// MarkAsVerified's conditions the way Postgres would
+type fakeOTPRow struct {
+	mu         sync.Mutex
+	otp        *models.OTP
+	verifiedAt *time.Time
+	// expireOnVerify expires the OTP just before MarkAsVerified runs, as a
+	// resend or the clock would between the read and the update
+	expireOnVerify bool
+}
+
+func (f *fakeOTPRow) query(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.Contains(query, "SET\n\t\t\tverified_at = NOW()"):
+		if f.expireOnVerify {
+			f.otp.ExpiresAt = time.Now().Add(-time.Hour)
+		}
+		if f.verifiedAt != nil || !f.otp.ExpiresAt.After(time.Now()) {
+			return nil, nil
+		}
+		now := time.Now()
+		f.verifiedAt = &now
+		return []string{"verified_at"}, [][]driver.Value{{now}}
+	case strings.Contains(query, "FROM otps"):
+		var verifiedAt driver.Value
+		if f.verifiedAt != nil {
+			verifiedAt = *f.verifiedAt
+		}
+		return []string{"id", "merchant_id", "user_id", "purpose", "code", "key_id", "recipient",
+				"delivery_method", "expires_at", "verified_at", "locked_at", "attempts",
+				"max_attempts", "reference_id", "locale", "metadata", "created_at"},
+			[][]driver.Value{{f.otp.ID, f.otp.MerchantID, nil, string(f.otp.Purpose), "digest", "k1", f.otp.Recipient,
+				string(f.otp.DeliveryMethod), f.otp.ExpiresAt, verifiedAt, nil, int64(f.otp.Attempts),
+				int64(f.otp.MaxAttempts), nil, nil, nil, f.otp.CreatedAt}}
+	}
+	return nil, nil
+}
+
+type otpLinkFixture struct {
+	db    *fakeDB
+	row   *fakeOTPRow
+	svc   *OTPService
+	token string
+}
+
+func newOTPLinkFixture(t *testing.T) *otpLinkFixture {
+	t.Helper()
+
+	row := &fakeOTPRow{otp: &models.OTP{
+		ID:             "5d0e8f3c-2b8a-4d7e-9a51-0f6c1e2d3b4a",
+		MerchantID:     "merchant-1",
+		Purpose:        models.PurposeLogin,
+		Recipient:      "ada@example.com",
+		DeliveryMethod: models.DeliveryEmail,
+		ExpiresAt:      time.Now().Add(10 * time.Minute),
+		MaxAttempts:    3,
+		CreatedAt:      time.Now(),
+	}}
+	fake, db := newFakeDB(t)
+	fake.query = row.query
+
+	hasher, err := NewOTPHasher(config.OTPConfig{HashKeys: map[string]string{"k1": strings.Repeat("p", 32)}})
+	if err != nil {
+		t.Fatalf("NewOTPHasher: %v", err)
+	}
+	return &otpLinkFixture{
+		db:  fake,
+		row: row,
+		svc: &OTPService{
+			otpRepo: repositories.NewOTPRepository(db),
+			hasher:  hasher,
+			limiter: &OTPLimiter{},
+			proofs:  newTestProofSigner(t, "k1", map[string]string{"k1": proofSeed(1)}),
+		},
+		token: hasher.SignLink(row.otp),
+	}
+}
+
+func (f *otpLinkFixture) verifies() int {
+	return len(f.db.execsMatching("verified_at = NOW()"))
+}
+
+func TestCheckLinkDoesNotConsumeLink(t *testing.T) {
+	f := newOTPLinkFixture(t)
+
+	for i := 0; i < 2; i++ {
+		if err := f.svc.CheckLink(context.Background(), f.token); err != nil {
+			t.Fatalf("CheckLink: %v", err)
+		}
+	}
+	if n := f.verifies(); n != 0 {
+		t.Fatalf("CheckLink marked the OTP verified %d times", n)
+	}
+
+	if _, err := f.svc.VerifyLink(context.Background(), f.token); err != nil {
+		t.Fatalf("VerifyLink after CheckLink: %v", err)
+	}
+}
+
+func TestVerifyLinkIsSingleUse(t *testing.T) {
+	f := newOTPLinkFixture(t)
+
+	resp, err := f.svc.VerifyLink(context.Background(), f.token)
+	if err != nil {
+		t.Fatalf("VerifyLink: %v", err)
+	}
+	if !resp.Verified || resp.OTPID != f.row.otp.ID || resp.ProofToken == "" {
+		t.Errorf("response = %+v", resp)
+	}
+	updates := f.db.execsMatching("verified_at = NOW()")
+	if len(updates) != 1 || !strings.Contains(updates[0].query, "expires_at > NOW()") {
+		t.Fatalf("updates = %+v, want one guarded by expires_at", updates)
+	}
+
+	if _, err := f.svc.VerifyLink(context.Background(), f.token); !errors.Is(err, models.ErrOTPAlreadyVerified) {
+		t.Errorf("second use: err = %v, want ErrOTPAlreadyVerified", err)
+	}
+	if err := f.svc.CheckLink(context.Background(), f.token); !errors.Is(err, models.ErrOTPAlreadyVerified) {
+		t.Errorf("check after use: err = %v, want ErrOTPAlreadyVerified", err)
+	}
+}
+
+func TestVerifyLinkRejectsExpiredOTP(t *testing.T) {
+	t.Run("before the link is followed", func(t *testing.T) {
+		f := newOTPLinkFixture(t)
+		f.row.otp.ExpiresAt = time.Now().Add(-time.Minute)
+
+		if _, err := f.svc.VerifyLink(context.Background(), f.token); !errors.Is(err, models.ErrOTPExpired) {
+			t.Errorf("err = %v, want ErrOTPExpired", err)
+		}
+		if n := f.verifies(); n != 0 {
+			t.Errorf("tried to mark an expired OTP verified %d times", n)
+		}
+	})
+
+	t.Run("between the read and the update", func(t *testing.T) {
+		f := newOTPLinkFixture(t)
+		f.row.expireOnVerify = true
+
+		if _, err := f.svc.VerifyLink(context.Background(), f.token); !errors.Is(err, models.ErrOTPExpired) {
+			t.Errorf("err = %v, want ErrOTPExpired", err)
+		}
+		if f.row.verifiedAt != nil {
+			t.Error("an OTP that expired before the update was marked verified")
+		}
+	})
+}
+
+func TestVerifyLinkRejectsForgedTokens(t *testing.T) {
+	f := newOTPLinkFixture(t)
+	otpID, rest, _ := strings.Cut(f.token, ".")
+	keyID, signature, _ := strings.Cut(rest, ".")
+
+	otherHasher, err := NewOTPHasher(config.OTPConfig{HashKeys: map[string]string{"k1": strings.Repeat("q", 32)}})
+	if err != nil {
+		t.Fatalf("NewOTPHasher: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"malformed", "not-a-token"},
+		{"ID is not a UUID", "otp-1." + keyID + "." + signature},
+		{"unknown key", otpID + ".k9." + signature},
+		{"altered signature", otpID + "." + keyID + "." + strings.Repeat("A", len(signature))},
+		{"signed with another pepper", otherHasher.SignLink(f.row.otp)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.svc.VerifyLink(context.Background(), tt.token); !errors.Is(err, models.ErrOTPInvalidLink) {
+				t.Errorf("err = %v, want ErrOTPInvalidLink", err)
+			}
+		})
+	}
+	if n := f.verifies(); n != 0 {
+		t.Errorf("forged tokens marked the OTP verified %d times", n)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// OTPPolicy is the code format and limits applied to OTPs for one purpose
+type OTPPolicy struct {
+	Length          int
+	Alphabet        string
+	Expiry          time.Duration
+	MaxAttempts     int
+	DeliveryMethods []models.OTPDeliveryMethod
+}
+
+// OTPPolicies holds the policy for every OTP purpose
+type OTPPolicies map[models.OTPPurpose]OTPPolicy
+
+// NewOTPPolicies validates the configured policies, requiring one for every purpose
+func NewOTPPolicies(cfg map[string]config.OTPPolicyConfig) (OTPPolicies, error) {
+	policies := make(OTPPolicies, len(cfg))
+	for name, c := range cfg {
+		purpose := models.OTPPurpose(name)
+		if !models.IsValidPurpose(purpose) {
+			return nil, fmt.Errorf("OTP policy for unknown purpose %q", name)
+		}
+
+		policy, err := newOTPPolicy(purpose, c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTP policy for %s: %w", name, err)
+		}
+		policies[purpose] = policy
+	}
+
+	for _, purpose := range models.Purposes {
+		if _, ok := policies[purpose]; !ok {
+			return nil, fmt.Errorf("no OTP policy configured for purpose %s", purpose)
+		}
+	}
+
+	return policies, nil
+}
+
+func newOTPPolicy(purpose models.OTPPurpose, c config.OTPPolicyConfig) (OTPPolicy, error) {
+	policy := OTPPolicy{Length: c.Length, Expiry: c.Expiry, MaxAttempts: c.MaxAttempts}
+
+	switch c.Alphabet {
+	case "numeric":
+		policy.Alphabet = models.AlphabetNumeric
+		if c.Length < 4 || c.Length > 10 {
+			return OTPPolicy{}, fmt.Errorf("numeric length must be between 4 and 10")
+		}
+	case "alphanumeric":
+		policy.Alphabet = models.AlphabetAlphanumeric
+		if c.Length < 4 || c.Length > 16 {
+			return OTPPolicy{}, fmt.Errorf("alphanumeric length must be between 4 and 16")
+		}
+	default:
+		return OTPPolicy{}, fmt.Errorf("alphabet must be numeric or alphanumeric, got %q", c.Alphabet)
+	}
+
+	if c.Expiry < time.Minute || c.Expiry > time.Hour {
+		return OTPPolicy{}, fmt.Errorf("expiry must be between 1m and 1h")
+	}
+	if c.MaxAttempts < 1 || c.MaxAttempts > 10 {
+		return OTPPolicy{}, fmt.Errorf("max attempts must be between 1 and 10")
+	}
+
+	if len(c.DeliveryMethods) == 0 {
+		return OTPPolicy{}, fmt.Errorf("at least one delivery method is required")
+	}
+	for _, m := range c.DeliveryMethods {
+		method := models.OTPDeliveryMethod(m)
+		if !models.IsValidDeliveryMethod(method) {
+			return OTPPolicy{}, fmt.Errorf("unsupported delivery method %q", m)
+		}
+		if method == models.DeliveryTOTP && purpose != models.Purpose2FA && purpose != models.PurposeLogin {
+			return OTPPolicy{}, fmt.Errorf("totp is only available for 2fa and login")
+		}
+		policy.DeliveryMethods = append(policy.DeliveryMethods, method)
+	}
+
+	return policy, nil
+}
+
+// Apply enforces the purpose's policy on a validated request, filling in the
+// default expiry and attempts, and returns the policy for generating the code
+func (p OTPPolicies) Apply(req *models.CreateOTPRequest) (OTPPolicy, error) {
+	policy := p[req.Purpose]
+
+	if !policy.Allows(req.DeliveryMethod) {
+		return OTPPolicy{}, validationErrorf("delivery_method %s is not allowed for %s", req.DeliveryMethod, req.Purpose)
+	}
+
+	maxExpiry := int(policy.Expiry / time.Minute)
+	if req.ExpiryMinutes == 0 {
+		req.ExpiryMinutes = maxExpiry
+	}
+	if req.ExpiryMinutes < 1 || req.ExpiryMinutes > maxExpiry {
+		return OTPPolicy{}, validationErrorf("expiry_minutes must be between 1 and %d for %s", maxExpiry, req.Purpose)
+	}
+
+	if req.MaxAttempts == 0 {
+		req.MaxAttempts = policy.MaxAttempts
+	}
+	if req.MaxAttempts < 1 || req.MaxAttempts > policy.MaxAttempts {
+		return OTPPolicy{}, validationErrorf("max_attempts must be between 1 and %d for %s", policy.MaxAttempts, req.Purpose)
+	}
+
+	return policy, nil
+}
+
+// Allows reports whether the policy permits a delivery method
+func (p OTPPolicy) Allows(method models.OTPDeliveryMethod) bool {
+	for _, m := range p.DeliveryMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
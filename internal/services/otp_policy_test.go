@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// validPolicyConfig configures every purpose with a six-digit code
+func validPolicyConfig() map[string]config.OTPPolicyConfig {
+	cfg := map[string]config.OTPPolicyConfig{}
+	for _, purpose := range models.Purposes {
+		cfg[string(purpose)] = config.OTPPolicyConfig{
+			Length:          6,
+			Alphabet:        "numeric",
+			Expiry:          10 * time.Minute,
+			MaxAttempts:     5,
+			DeliveryMethods: []string{"email", "sms"},
+		}
+	}
+	return cfg
+}
+
+func TestNewOTPPoliciesRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(cfg map[string]config.OTPPolicyConfig)
+	}{
+		{"numeric too short", setPolicy("payout", func(c *config.OTPPolicyConfig) { c.Length = 3 })},
+		{"numeric too long", setPolicy("payout", func(c *config.OTPPolicyConfig) { c.Length = 11 })},
+		{"alphanumeric too long", setPolicy("payout", func(c *config.OTPPolicyConfig) { c.Alphabet, c.Length = "alphanumeric", 17 })},
+		{"unknown alphabet", setPolicy("payout", func(c *config.OTPPolicyConfig) { c.Alphabet = "hex" })},
+		{"expiry under a minute", setPolicy("login", func(c *config.OTPPolicyConfig) { c.Expiry = 30 * time.Second })},
+		{"expiry over an hour", setPolicy("login", func(c *config.OTPPolicyConfig) { c.Expiry = 2 * time.Hour })},
+		{"no attempts", setPolicy("login", func(c *config.OTPPolicyConfig) { c.MaxAttempts = 0 })},
+		{"too many attempts", setPolicy("login", func(c *config.OTPPolicyConfig) { c.MaxAttempts = 11 })},
+		{"no delivery methods", setPolicy("2fa", func(c *config.OTPPolicyConfig) { c.DeliveryMethods = nil })},
+		{"unknown delivery method", setPolicy("2fa", func(c *config.OTPPolicyConfig) { c.DeliveryMethods = []string{"fax"} })},
+		{"totp for payouts", setPolicy("payout", func(c *config.OTPPolicyConfig) { c.DeliveryMethods = []string{"totp"} })},
+		{"unknown purpose", func(cfg map[string]config.OTPPolicyConfig) { cfg["refund"] = cfg["payout"] }},
+		{"missing purpose", func(cfg map[string]config.OTPPolicyConfig) { delete(cfg, "withdrawal") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validPolicyConfig()
+			tt.mutate(cfg)
+			if _, err := NewOTPPolicies(cfg); err == nil {
+				t.Error("NewOTPPolicies accepted an invalid config")
+			}
+		})
+	}
+
+	cfg := validPolicyConfig()
+	setPolicy("login", func(c *config.OTPPolicyConfig) {
+		c.Alphabet, c.Length, c.DeliveryMethods = "alphanumeric", 16, []string{"totp", "sms"}
+	})(cfg)
+	policies, err := NewOTPPolicies(cfg)
+	if err != nil {
+		t.Fatalf("NewOTPPolicies: %v", err)
+	}
+	if login := policies[models.PurposeLogin]; login.Alphabet != models.AlphabetAlphanumeric || !login.Allows(models.DeliveryTOTP) {
+		t.Errorf("login policy = %+v", login)
+	}
+}
+
+func setPolicy(purpose string, edit func(c *config.OTPPolicyConfig)) func(map[string]config.OTPPolicyConfig) {
+	return func(cfg map[string]config.OTPPolicyConfig) {
+		c := cfg[purpose]
+		edit(&c)
+		cfg[purpose] = c
+	}
+}
+
+func TestOTPPoliciesApply(t *testing.T) {
+	policies, err := NewOTPPolicies(validPolicyConfig())
+	if err != nil {
+		t.Fatalf("NewOTPPolicies: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		req          models.CreateOTPRequest
+		wantExpiry   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "defaults from the policy", req: models.CreateOTPRequest{DeliveryMethod: models.DeliverySMS},
+			wantExpiry: 10, wantAttempts: 5},
+		{name: "stricter request kept", req: models.CreateOTPRequest{DeliveryMethod: models.DeliveryEmail, ExpiryMinutes: 2, MaxAttempts: 3},
+			wantExpiry: 2, wantAttempts: 3},
+		{name: "policy maximums allowed", req: models.CreateOTPRequest{DeliveryMethod: models.DeliverySMS, ExpiryMinutes: 10, MaxAttempts: 5},
+			wantExpiry: 10, wantAttempts: 5},
+		{name: "longer expiry", req: models.CreateOTPRequest{DeliveryMethod: models.DeliverySMS, ExpiryMinutes: 11}, wantErr: true},
+		{name: "negative expiry", req: models.CreateOTPRequest{DeliveryMethod: models.DeliverySMS, ExpiryMinutes: -1}, wantErr: true},
+		{name: "more attempts", req: models.CreateOTPRequest{DeliveryMethod: models.DeliverySMS, MaxAttempts: 6}, wantErr: true},
+		{name: "disallowed delivery method", req: models.CreateOTPRequest{DeliveryMethod: models.DeliveryTOTP}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.Purpose = models.PurposePayout
+
+			policy, err := policies.Apply(&req)
+			if tt.wantErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("err = %v, want a ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if req.ExpiryMinutes != tt.wantExpiry || req.MaxAttempts != tt.wantAttempts {
+				t.Errorf("expiry, attempts = %d, %d; want %d, %d", req.ExpiryMinutes, req.MaxAttempts, tt.wantExpiry, tt.wantAttempts)
+			}
+			if policy.Length != 6 || policy.Alphabet != models.AlphabetNumeric {
+				t.Errorf("policy = %+v", policy)
+			}
+		})
+	}
+}
+
+func TestCheckCodeIgnoresCase(t *testing.T) {
+	hasher, err := NewOTPHasher(config.OTPConfig{HashKeys: map[string]string{"k1": strings.Repeat("p", 32)}})
+	if err != nil {
+		t.Fatalf("NewOTPHasher: %v", err)
+	}
+	s := &OTPService{hasher: hasher}
+
+	code, err := models.GenerateCodeFrom(models.AlphabetAlphanumeric, 8)
+	if err != nil {
+		t.Fatalf("GenerateCodeFrom: %v", err)
+	}
+	if code != strings.ToUpper(code) {
+		t.Fatalf("alphanumeric code %q is not upper case", code)
+	}
+
+	otp := &models.OTP{MerchantID: "merchant-1", Purpose: models.PurposeLogin, DeliveryMethod: models.DeliveryEmail}
+	digest, keyID := hasher.Hash(otp.MerchantID, otp.Purpose, code)
+	otp.Code, otp.KeyID = digest, &keyID
+
+	for _, typed := range []string{code, strings.ToLower(code), " " + strings.ToLower(code) + "\n"} {
+		if err := s.checkCode(context.Background(), otp, typed); err != nil {
+			t.Errorf("checkCode(%q) = %v, want a match for %q", typed, err, code)
+		}
+	}
+	if err := s.checkCode(context.Background(), otp, code[1:]); !errors.Is(err, models.ErrOTPInvalidCode) {
+		t.Errorf("checkCode with a wrong code = %v, want ErrOTPInvalidCode", err)
+	}
+}
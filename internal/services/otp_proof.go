@@ -0,0 +1,157 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// ProofSigner issues verification proof tokens: compact EdDSA JWS asserting that
+// an OTP was verified, which downstream services validate offline against the
+// published JWKS. Several keys may be configured so they can be rotated; all of
+// them are published.
+type ProofSigner struct {
+	keys     map[string]ed25519.PrivateKey
+	activeID string
+	issuer   string
+	ttl      time.Duration
+}
+
+func NewProofSigner(cfg config.OTPProofConfig) (*ProofSigner, error) {
+	if len(cfg.SigningKeys) == 0 {
+		return nil, fmt.Errorf("at least one OTP proof signing key is required (OTP_PROOF_SIGNING_KEYS)")
+	}
+
+	activeID := cfg.ActiveKeyID
+	if activeID == "" && len(cfg.SigningKeys) == 1 {
+		for id := range cfg.SigningKeys {
+			activeID = id
+		}
+	}
+	if _, ok := cfg.SigningKeys[activeID]; !ok {
+		return nil, fmt.Errorf("active OTP proof signing key %q is not configured", activeID)
+	}
+
+	keys := make(map[string]ed25519.PrivateKey, len(cfg.SigningKeys))
+	for id, encoded := range cfg.SigningKeys {
+		seed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("OTP proof signing key %q must be a 32-byte Ed25519 seed, base64-encoded", id)
+		}
+		keys[id] = ed25519.NewKeyFromSeed(seed)
+	}
+
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("OTP proof TTL must be positive")
+	}
+
+	return &ProofSigner{keys: keys, activeID: activeID, issuer: cfg.Issuer, ttl: cfg.TTL}, nil
+}
+
+// Sign returns a proof token for a verified OTP and when it expires
+func (p *ProofSigner) Sign(otp *models.OTP, verifiedAt time.Time) (string, time.Time, error) {
+	expiresAt := verifiedAt.Add(p.ttl)
+	claims := dto.OTPProofClaims{
+		Issuer:      p.issuer,
+		IssuedAt:    verifiedAt.Unix(),
+		ExpiresAt:   expiresAt.Unix(),
+		MerchantID:  otp.MerchantID,
+		Purpose:     string(otp.Purpose),
+		ReferenceID: otp.ReferenceID,
+		OTPID:       otp.ID,
+		Method:      string(otp.DeliveryMethod),
+	}
+	if otp.UserID != nil {
+		claims.Subject = *otp.UserID
+	}
+
+	header := map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": p.activeID}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("encode proof header: %w", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("encode proof claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+	signature := ed25519.Sign(p.keys[p.activeID], []byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), expiresAt, nil
+}
+
+// Parse validates a proof token's signature, issuer and expiry and returns its
+// claims. Any failure is reported as ErrOTPInvalidProof.
+func (p *ProofSigner) Parse(token string) (*dto.OTPProofClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, models.ErrOTPInvalidProof
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, models.ErrOTPInvalidProof
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "EdDSA" {
+		return nil, models.ErrOTPInvalidProof
+	}
+	key, ok := p.keys[header.Kid]
+	if !ok {
+		return nil, models.ErrOTPInvalidProof
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(key.Public().(ed25519.PublicKey), []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, models.ErrOTPInvalidProof
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, models.ErrOTPInvalidProof
+	}
+	var claims dto.OTPProofClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, models.ErrOTPInvalidProof
+	}
+	if claims.Issuer != p.issuer || time.Now().Unix() >= claims.ExpiresAt {
+		return nil, models.ErrOTPInvalidProof
+	}
+
+	return &claims, nil
+}
+
+// JWKS returns the public halves of all configured signing keys
+func (p *ProofSigner) JWKS() dto.JWKSResponse {
+	ids := make([]string, 0, len(p.keys))
+	for id := range p.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	keys := make([]dto.JWK, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, dto.JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(p.keys[id].Public().(ed25519.PublicKey)),
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+		})
+	}
+	return dto.JWKSResponse{Keys: keys}
+}
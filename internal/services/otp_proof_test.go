@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+func proofSeed(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, ed25519.SeedSize))
+}
+
+func newTestProofSigner(t *testing.T, activeID string, keys map[string]string) *ProofSigner {
+	t.Helper()
+	p, err := NewProofSigner(config.OTPProofConfig{
+		SigningKeys: keys,
+		ActiveKeyID: activeID,
+		Issuer:      "notification-service",
+		TTL:         5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewProofSigner: %v", err)
+	}
+	return p
+}
+
+func testProofOTP() *models.OTP {
+	userID, ref := "user-1", "payout-9"
+	return &models.OTP{
+		ID:             "6f1c2d3e-0000-4000-8000-000000000001",
+		MerchantID:     "merchant-1",
+		UserID:         &userID,
+		Purpose:        models.PurposePayout,
+		DeliveryMethod: models.DeliverySMS,
+		ReferenceID:    &ref,
+	}
+}
+
+func TestProofSignParseRoundTrip(t *testing.T) {
+	p := newTestProofSigner(t, "k1", map[string]string{"k1": proofSeed(1)})
+	otp := testProofOTP()
+	verifiedAt := time.Now()
+
+	token, expiresAt, err := p.Sign(otp, verifiedAt)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if !expiresAt.Equal(verifiedAt.Add(5 * time.Minute)) {
+		t.Errorf("expiresAt = %v, want %v", expiresAt, verifiedAt.Add(5*time.Minute))
+	}
+
+	claims, err := p.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if claims.Issuer != "notification-service" || claims.Subject != "user-1" ||
+		claims.MerchantID != otp.MerchantID || claims.OTPID != otp.ID ||
+		claims.Purpose != "payout" || claims.Method != "sms" ||
+		claims.ReferenceID == nil || *claims.ReferenceID != "payout-9" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if claims.ExpiresAt != expiresAt.Unix() {
+		t.Errorf("exp = %d, want %d", claims.ExpiresAt, expiresAt.Unix())
+	}
+}
+
+func TestProofVerifiesAgainstJWKS(t *testing.T) {
+	p := newTestProofSigner(t, "k2", map[string]string{"k1": proofSeed(1), "k2": proofSeed(2)})
+	token, _, err := p.Sign(testProofOTP(), time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	jwks := p.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "k1" || jwks.Keys[1].KeyID != "k2" {
+		t.Fatalf("JWKS keys = %+v, want k1 and k2", jwks.Keys)
+	}
+
+	parts := strings.Split(token, ".")
+	x, err := base64.RawURLEncoding.DecodeString(jwks.Keys[1].X)
+	if err != nil {
+		t.Fatalf("decode x: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("decode signature: %v", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(x), []byte(parts[0]+"."+parts[1]), signature) {
+		t.Error("token does not verify against the published k2 key")
+	}
+}
+
+func TestProofKeyRotation(t *testing.T) {
+	before := newTestProofSigner(t, "k1", map[string]string{"k1": proofSeed(1)})
+	oldToken, _, err := before.Sign(testProofOTP(), time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// k2 becomes active while k1 stays listed until its tokens expire
+	during := newTestProofSigner(t, "k2", map[string]string{"k1": proofSeed(1), "k2": proofSeed(2)})
+	if _, err := during.Parse(oldToken); err != nil {
+		t.Errorf("token signed with retired k1: %v", err)
+	}
+	newToken, _, err := during.Sign(testProofOTP(), time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if kid := proofHeader(t, newToken)["kid"]; kid != "k2" {
+		t.Errorf("kid = %q, want k2", kid)
+	}
+
+	// Once k1 is dropped its tokens are no longer accepted
+	after := newTestProofSigner(t, "k2", map[string]string{"k2": proofSeed(2)})
+	if _, err := after.Parse(oldToken); !errors.Is(err, models.ErrOTPInvalidProof) {
+		t.Errorf("token signed with removed k1: err = %v, want ErrOTPInvalidProof", err)
+	}
+	if _, err := after.Parse(newToken); err != nil {
+		t.Errorf("token signed with k2: %v", err)
+	}
+}
+
+func TestProofParseRejectsTamperedTokens(t *testing.T) {
+	p := newTestProofSigner(t, "k1", map[string]string{"k1": proofSeed(1), "k2": proofSeed(2)})
+	token, _, err := p.Sign(testProofOTP(), time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	claims := proofClaims(t, token)
+	claims["merchant_id"] = "merchant-2"
+	forgedClaims := encodeProofPart(t, claims)
+
+	expired := newTestProofSigner(t, "k1", map[string]string{"k1": proofSeed(1)})
+	expiredToken, _, err := expired.Sign(testProofOTP(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	otherIssuer, err := NewProofSigner(config.OTPProofConfig{
+		SigningKeys: map[string]string{"k1": proofSeed(1)},
+		Issuer:      "someone-else",
+		TTL:         5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewProofSigner: %v", err)
+	}
+	otherIssuerToken, _, err := otherIssuer.Sign(testProofOTP(), time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"modified claims", parts[0] + "." + forgedClaims + "." + parts[2]},
+		{"swapped kid", encodeProofPart(t, map[string]interface{}{"alg": "EdDSA", "typ": "JWT", "kid": "k2"}) + "." + parts[1] + "." + parts[2]},
+		{"unknown kid", encodeProofPart(t, map[string]interface{}{"alg": "EdDSA", "typ": "JWT", "kid": "k9"}) + "." + parts[1] + "." + parts[2]},
+		{"alg none", encodeProofPart(t, map[string]interface{}{"alg": "none", "typ": "JWT", "kid": "k1"}) + "." + parts[1] + "."},
+		{"truncated signature", parts[0] + "." + parts[1] + "." + parts[2][:len(parts[2])-4]},
+		{"missing signature", parts[0] + "." + parts[1]},
+		{"expired", expiredToken},
+		{"wrong issuer", otherIssuerToken},
+		{"garbage", "not-a-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.Parse(tt.token); !errors.Is(err, models.ErrOTPInvalidProof) {
+				t.Errorf("err = %v, want ErrOTPInvalidProof", err)
+			}
+		})
+	}
+}
+
+func proofHeader(t *testing.T, token string) map[string]string {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+	var header map[string]string
+	if err := json.Unmarshal(raw, &header); err != nil {
+		t.Fatalf("unmarshal header: %v", err)
+	}
+	return header
+}
+
+func proofClaims(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatalf("decode claims: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatalf("unmarshal claims: %v", err)
+	}
+	return claims
+}
+
+func encodeProofPart(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+func TestValidateVerifyOTPRequestOTPID(t *testing.T) {
+	ref := "order-1"
+	empty := ""
+	malformed := "not-a-uuid"
+	valid := "5d0e8f3c-2b8a-4d7e-9a51-0f6c1e2d3b4a"
+
+	tests := []struct {
+		name    string
+		otpID   *string
+		ref     *string
+		wantErr bool
+	}{
+		{name: "valid ID", otpID: &valid},
+		{name: "empty ID with reference", otpID: &empty, ref: &ref},
+		{name: "empty ID alone", otpID: &empty, wantErr: true},
+		{name: "malformed ID", otpID: &malformed, ref: &ref, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVerifyOTPRequest(&models.VerifyOTPRequest{
+				MerchantID:  "merchant-1",
+				Purpose:     models.PurposeLogin,
+				Code:        "123456",
+				OTPID:       tt.otpID,
+				ReferenceID: tt.ref,
+			})
+			var validationErr *ValidationError
+			if tt.wantErr != errors.As(err, &validationErr) {
+				t.Errorf("err = %v, want validation error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLookupTreatsEmptyOTPIDAsAbsent(t *testing.T) {
+	fake, db := newFakeDB(t)
+	s := &OTPService{otpRepo: repositories.NewOTPRepository(db)}
+
+	empty := ""
+	ref := "order-1"
+	_, err := s.lookup(context.Background(), &models.VerifyOTPRequest{
+		MerchantID: "merchant-1", Purpose: models.PurposeLogin, OTPID: &empty, ReferenceID: &ref,
+	})
+	if !errors.Is(err, repositories.ErrOTPNotFound) {
+		t.Fatalf("err = %v, want ErrOTPNotFound", err)
+	}
+
+	queries := fake.execsMatching("FROM otps")
+	if len(queries) != 1 || !strings.Contains(queries[0].query, "reference_id = $3") {
+		t.Fatalf("queries = %+v, want a lookup by reference", queries)
+	}
+	if got := queries[0].args[2]; got != ref {
+		t.Errorf("reference = %v, want %q", got, ref)
+	}
+}
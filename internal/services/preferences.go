@@ -0,0 +1,619 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/templates"
+)
+
+// preferenceCategoryPattern limits the categories that can be stored in the matrix
+var preferenceCategoryPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// PreferenceUpdate carries the precondition and audit details of a preferences update
+type PreferenceUpdate struct {
+	// IfMatch is the client's If-Match header, if any
+	IfMatch string
+	// ChangedBy is recorded in the audit trail as given. It is not authenticated
+	// here; callers are trusted to name the actor, e.g. the API gateway.
+	ChangedBy *string
+	RequestID *string
+}
+
+// PreferencesService manages merchants' notification preferences and their users'
+// overrides. Updates are conditional on the version the caller last read and
+// every change is audited.
+type PreferencesService struct {
+	repo        *repositories.NotificationPreferencesRepository
+	userRepo    *repositories.UserNotificationPreferencesRepository
+	unsubscribe *UnsubscribeTokens
+}
+
+func NewPreferencesService(
+	repo *repositories.NotificationPreferencesRepository,
+	userRepo *repositories.UserNotificationPreferencesRepository,
+	unsubscribe *UnsubscribeTokens,
+) *PreferencesService {
+	return &PreferencesService{repo: repo, userRepo: userRepo, unsubscribe: unsubscribe}
+}
+
+// Get returns a merchant's preferences, creating the defaults on first access
+func (s *PreferencesService) Get(ctx context.Context, merchantID string) (dto.NotificationPreferencesResponse, error) {
+	prefs, err := s.repo.GetByMerchantID(ctx, merchantID)
+	if err != nil {
+		return dto.NotificationPreferencesResponse{}, err
+	}
+	return toPreferencesResponse(prefs), nil
+}
+
+// Replace sets the whole preference matrix and contact details from req. Cells
+// and contact details left out are reset to their defaults.
+func (s *PreferencesService) Replace(
+	ctx context.Context,
+	merchantID string,
+	req dto.NotificationPreferencesRequest,
+	update PreferenceUpdate,
+) (dto.NotificationPreferencesResponse, error) {
+	// Contact details left out of a replacement are cleared
+	empty := ""
+	if req.EmailAddress == nil {
+		req.EmailAddress = &empty
+	}
+	if req.PhoneNumber == nil {
+		req.PhoneNumber = &empty
+	}
+	if req.Locale == nil {
+		req.Locale = &empty
+	}
+	if req.Timezone == nil {
+		req.Timezone = &empty
+	}
+	if req.QuietHoursStart == nil && req.QuietHoursEnd == nil {
+		req.QuietHoursStart, req.QuietHoursEnd = &empty, &empty
+	}
+
+	return s.update(ctx, merchantID, req, update, true)
+}
+
+// Patch changes only the preference cells and contact details present in req
+func (s *PreferencesService) Patch(
+	ctx context.Context,
+	merchantID string,
+	req dto.NotificationPreferencesRequest,
+	update PreferenceUpdate,
+) (dto.NotificationPreferencesResponse, error) {
+	return s.update(ctx, merchantID, req, update, false)
+}
+
+func (s *PreferencesService) update(
+	ctx context.Context,
+	merchantID string,
+	req dto.NotificationPreferencesRequest,
+	update PreferenceUpdate,
+	replace bool,
+) (dto.NotificationPreferencesResponse, error) {
+	if err := validatePreferencesRequest(&req); err != nil {
+		return dto.NotificationPreferencesResponse{}, err
+	}
+
+	current, err := s.repo.GetByMerchantID(ctx, merchantID)
+	if err != nil {
+		return dto.NotificationPreferencesResponse{}, err
+	}
+	if err := checkPreferencesPrecondition(current.UpdatedAt, req.UpdatedAt, update.IfMatch); err != nil {
+		return dto.NotificationPreferencesResponse{}, err
+	}
+
+	updated := *current
+	applyPreferencesRequest(&updated, req, replace)
+
+	changes := diffPreferences(current, &updated)
+	if len(changes) == 0 {
+		return toPreferencesResponse(current), nil
+	}
+
+	change := &models.NotificationPreferenceChange{
+		Changes:   changes,
+		ChangedBy: update.ChangedBy,
+		RequestID: update.RequestID,
+	}
+	if err := s.repo.UpdateIfUnmodified(ctx, &updated, current.UpdatedAt, change); err != nil {
+		return dto.NotificationPreferencesResponse{}, err
+	}
+
+	return toPreferencesResponse(&updated), nil
+}
+
+// GetUser returns a user's overrides together with their effective preferences
+func (s *PreferencesService) GetUser(ctx context.Context, merchantID, userID string) (dto.UserNotificationPreferencesResponse, error) {
+	merchant, err := s.repo.GetByMerchantID(ctx, merchantID)
+	if err != nil {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+	user, err := s.userRepo.GetByUser(ctx, merchantID, userID)
+	if err != nil && !errors.Is(err, repositories.ErrUserPreferencesNotFound) {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+	return toUserPreferencesResponse(merchant, user, merchantID, userID), nil
+}
+
+// ReplaceUser sets a user's overrides from req; fields left out inherit from the merchant
+func (s *PreferencesService) ReplaceUser(
+	ctx context.Context,
+	merchantID, userID string,
+	req dto.UserNotificationPreferencesRequest,
+	update PreferenceUpdate,
+) (dto.UserNotificationPreferencesResponse, error) {
+	if err := validatePreferenceCells(req.Preferences); err != nil {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+	if err := validateContactDetails(&req.EmailAddress, &req.PhoneNumber, &req.Locale); err != nil {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+	if err := validateQuietHours(req.Timezone, req.QuietHoursStart, req.QuietHoursEnd); err != nil {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+
+	merchant, err := s.repo.GetByMerchantID(ctx, merchantID)
+	if err != nil {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+	current, err := s.userRepo.GetByUser(ctx, merchantID, userID)
+	if err != nil && !errors.Is(err, repositories.ErrUserPreferencesNotFound) {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+
+	// Overrides are created unconditionally but updated only against the version read
+	var expectedUpdatedAt *time.Time
+	if current != nil {
+		if err := checkPreferencesPrecondition(current.UpdatedAt, req.UpdatedAt, update.IfMatch); err != nil {
+			return dto.UserNotificationPreferencesResponse{}, err
+		}
+		expectedUpdatedAt = &current.UpdatedAt
+	} else if update.IfMatch != "" || req.UpdatedAt != nil {
+		return dto.UserNotificationPreferencesResponse{}, repositories.ErrPreferencesModified
+	}
+
+	updated := toUserPreferences(merchantID, userID, req.UserPreferenceOverrides)
+	changes := diffUserPreferences(current, updated)
+	if len(changes) == 0 {
+		return toUserPreferencesResponse(merchant, current, merchantID, userID), nil
+	}
+
+	change := &models.NotificationPreferenceChange{
+		Changes:   changes,
+		ChangedBy: update.ChangedBy,
+		RequestID: update.RequestID,
+	}
+	if err := s.userRepo.Save(ctx, updated, expectedUpdatedAt, change); err != nil {
+		return dto.UserNotificationPreferencesResponse{}, err
+	}
+
+	return toUserPreferencesResponse(merchant, updated, merchantID, userID), nil
+}
+
+// unsubscribeAttempts bounds the retries of an opt-out that races another update
+const unsubscribeAttempts = 3
+
+// CheckUnsubscribe validates an unsubscribe token without applying it
+func (s *PreferencesService) CheckUnsubscribe(token string) (dto.UnsubscribeResponse, error) {
+	claims, err := s.unsubscribeClaims(token)
+	if err != nil {
+		return dto.UnsubscribeResponse{}, err
+	}
+	return dto.UnsubscribeResponse{Category: string(claims.Category), Type: string(claims.Type)}, nil
+}
+
+// Unsubscribe turns off the preference cell an unsubscribe token names, on the
+// user's overrides if it was issued to a user and otherwise on the merchant's
+// preferences. Opting out again is a no-op. The update is not conditional on a
+// version the recipient read, so it is retried if another update races it.
+func (s *PreferencesService) Unsubscribe(ctx context.Context, token string, requestID *string) (dto.UnsubscribeResponse, error) {
+	claims, err := s.unsubscribeClaims(token)
+	if err != nil {
+		return dto.UnsubscribeResponse{}, err
+	}
+
+	off := false
+	cells := dto.PreferenceCells{string(claims.Category): {string(claims.Type): &off}}
+	changedBy := "unsubscribe_link"
+	update := PreferenceUpdate{IfMatch: "*", ChangedBy: &changedBy, RequestID: requestID}
+
+	for attempt := 1; ; attempt++ {
+		if claims.UserID == "" {
+			_, err = s.update(ctx, claims.MerchantID, dto.NotificationPreferencesRequest{Preferences: cells}, update, false)
+		} else {
+			err = s.unsubscribeUser(ctx, claims, cells, update)
+		}
+		if !errors.Is(err, repositories.ErrPreferencesModified) || attempt == unsubscribeAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return dto.UnsubscribeResponse{}, err
+	}
+
+	return dto.UnsubscribeResponse{Category: string(claims.Category), Type: string(claims.Type)}, nil
+}
+
+// unsubscribeUser adds cells to a user's overrides, keeping the rest
+func (s *PreferencesService) unsubscribeUser(
+	ctx context.Context,
+	claims *UnsubscribeClaims,
+	cells dto.PreferenceCells,
+	update PreferenceUpdate,
+) error {
+	current, err := s.userRepo.GetByUser(ctx, claims.MerchantID, claims.UserID)
+	if err != nil && !errors.Is(err, repositories.ErrUserPreferencesNotFound) {
+		return err
+	}
+
+	updated := &models.UserNotificationPreferences{
+		MerchantID: claims.MerchantID,
+		UserID:     claims.UserID,
+		Matrix:     models.PreferenceMatrix{},
+	}
+	var expectedUpdatedAt *time.Time
+	if current != nil {
+		*updated = *current
+		updated.Matrix = current.Matrix.Clone()
+		expectedUpdatedAt = &current.UpdatedAt
+	}
+	applyPreferenceCells(updated.Matrix, cells)
+
+	changes := diffUserPreferences(current, updated)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	change := &models.NotificationPreferenceChange{
+		Changes:   changes,
+		ChangedBy: update.ChangedBy,
+		RequestID: update.RequestID,
+	}
+	return s.userRepo.Save(ctx, updated, expectedUpdatedAt, change)
+}
+
+// unsubscribeClaims parses a token and checks it names a cell that can be turned off
+func (s *PreferencesService) unsubscribeClaims(token string) (*UnsubscribeClaims, error) {
+	claims, err := s.unsubscribe.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if models.IsMandatoryCategory(claims.Category) || !models.IsValidNotificationType(claims.Type) ||
+		!preferenceCategoryPattern.MatchString(string(claims.Category)) {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	return claims, nil
+}
+
+// PreferencesETag is the entity tag of a preferences version
+func PreferencesETag(updatedAt time.Time) string {
+	return `"` + strconv.FormatInt(updatedAt.UnixMicro(), 36) + `"`
+}
+
+// checkPreferencesPrecondition requires the caller to name the version it read,
+// by If-Match or updated_at, and that version to still be current
+func checkPreferencesPrecondition(current time.Time, updatedAt *string, ifMatch string) error {
+	ifMatch = strings.TrimSpace(ifMatch)
+	switch {
+	case ifMatch != "":
+		if ifMatch == "*" {
+			return nil
+		}
+		// Weak tags are compared by value; the version is the same either way
+		for _, tag := range strings.Split(ifMatch, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == PreferencesETag(current) {
+				return nil
+			}
+		}
+		return repositories.ErrPreferencesModified
+	case updatedAt != nil:
+		t, err := time.Parse(time.RFC3339Nano, *updatedAt)
+		if err != nil {
+			return validationErrorf("updated_at must be an RFC 3339 timestamp")
+		}
+		if !t.Equal(current.Truncate(time.Microsecond)) {
+			return repositories.ErrPreferencesModified
+		}
+		return nil
+	default:
+		return ErrPreconditionRequired
+	}
+}
+
+func validatePreferencesRequest(req *dto.NotificationPreferencesRequest) error {
+	if err := validatePreferenceCells(req.Preferences); err != nil {
+		return err
+	}
+	if err := validateContactDetails(&req.EmailAddress, &req.PhoneNumber, &req.Locale); err != nil {
+		return err
+	}
+	return validateQuietHours(req.Timezone, req.QuietHoursStart, req.QuietHoursEnd)
+}
+
+// validateQuietHours checks the optional timezone and quiet hours. The window's
+// start and end are given or cleared together, and must differ.
+func validateQuietHours(timezone, start, end *string) error {
+	if timezone != nil && *timezone != "" {
+		if _, err := time.LoadLocation(*timezone); err != nil {
+			return validationErrorf("unknown timezone %q", *timezone)
+		}
+	}
+	if (start == nil) != (end == nil) || start != nil && (*start == "") != (*end == "") {
+		return validationErrorf("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	if start == nil || *start == "" {
+		return nil
+	}
+	from, ok := models.ParseClock(*start)
+	if !ok {
+		return validationErrorf("quiet_hours_start must be an HH:MM time")
+	}
+	to, ok := models.ParseClock(*end)
+	if !ok {
+		return validationErrorf("quiet_hours_end must be an HH:MM time")
+	}
+	if from == to {
+		return validationErrorf("quiet hours must not start and end at the same time")
+	}
+	return nil
+}
+
+// validatePreferenceCells checks the categories and delivery types named in cells
+// and that no mandatory category is being disabled
+func validatePreferenceCells(cells dto.PreferenceCells) error {
+	for category, types := range cells {
+		if !preferenceCategoryPattern.MatchString(category) {
+			return validationErrorf("invalid preference category %q", category)
+		}
+		for notifType, enabled := range types {
+			if !models.IsValidNotificationType(models.NotificationType(notifType)) {
+				return validationErrorf("invalid delivery type %q for category %q", notifType, category)
+			}
+			if enabled != nil && !*enabled && models.IsMandatoryCategory(models.NotificationChannel(category)) {
+				return validationErrorf("%s notifications are mandatory and cannot be disabled", category)
+			}
+		}
+	}
+	return nil
+}
+
+// validateContactDetails checks and normalises the optional contact fields in place
+func validateContactDetails(email, phone, locale **string) error {
+	if *email != nil && **email != "" {
+		v := strings.TrimSpace(**email)
+		if addr, err := mail.ParseAddress(v); err != nil || addr.Address != v {
+			return validationErrorf("email_address must be a valid email address")
+		}
+		*email = &v
+	}
+	if *phone != nil && **phone != "" {
+		v := strings.TrimSpace(**phone)
+		if !e164Pattern.MatchString(v) {
+			return validationErrorf("phone_number must be an E.164 phone number")
+		}
+		*phone = &v
+	}
+	if *locale != nil && **locale != "" {
+		v := templates.NormalizeLocale(**locale)
+		if !localePattern.MatchString(v) {
+			return validationErrorf("invalid locale %q", **locale)
+		}
+		*locale = &v
+	}
+	return nil
+}
+
+// applyPreferencesRequest copies the cells and fields present in req onto prefs.
+// When replace is set the matrix starts from the defaults instead of prefs.
+func applyPreferencesRequest(prefs *models.NotificationPreferences, req dto.NotificationPreferencesRequest, replace bool) {
+	if replace {
+		prefs.Matrix = models.PreferenceMatrix{}
+	} else {
+		prefs.Matrix = prefs.Matrix.Clone()
+	}
+	applyPreferenceCells(prefs.Matrix, req.Preferences)
+
+	setString := func(dst **string, v *string) {
+		if v == nil {
+			return
+		}
+		if *v == "" {
+			*dst = nil
+			return
+		}
+		s := *v
+		*dst = &s
+	}
+	setString(&prefs.EmailAddress, req.EmailAddress)
+	setString(&prefs.PhoneNumber, req.PhoneNumber)
+	setString(&prefs.Locale, req.Locale)
+	setString(&prefs.Timezone, req.Timezone)
+	setString(&prefs.QuietHoursStart, req.QuietHoursStart)
+	setString(&prefs.QuietHoursEnd, req.QuietHoursEnd)
+}
+
+// applyPreferenceCells sets or, for null cells, unsets cells of matrix. Mandatory
+// categories are never stored since they cannot be turned off.
+func applyPreferenceCells(matrix models.PreferenceMatrix, cells dto.PreferenceCells) {
+	for category, types := range cells {
+		c := models.NotificationChannel(category)
+		if models.IsMandatoryCategory(c) {
+			continue
+		}
+		for notifType, enabled := range types {
+			t := models.NotificationType(notifType)
+			if enabled == nil {
+				matrix.Unset(c, t)
+			} else {
+				matrix.Set(c, t, *enabled)
+			}
+		}
+	}
+}
+
+// diffPreferences lists the fields that differ between two versions, keyed by JSON
+// name, with matrix cells keyed as preferences.<category>.<type>
+func diffPreferences(old, updated *models.NotificationPreferences) map[string]models.PreferenceFieldChange {
+	changes := map[string]models.PreferenceFieldChange{}
+	diffPreferenceMatrix(changes, old.Matrix, updated.Matrix)
+	diffString(changes, "email_address", old.EmailAddress, updated.EmailAddress)
+	diffString(changes, "phone_number", old.PhoneNumber, updated.PhoneNumber)
+	diffString(changes, "locale", old.Locale, updated.Locale)
+	diffString(changes, "timezone", old.Timezone, updated.Timezone)
+	diffString(changes, "quiet_hours_start", old.QuietHoursStart, updated.QuietHoursStart)
+	diffString(changes, "quiet_hours_end", old.QuietHoursEnd, updated.QuietHoursEnd)
+	return changes
+}
+
+// diffPreferenceMatrix adds the cells that differ to changes; a nil old or new
+// value means the cell is unset
+func diffPreferenceMatrix(changes map[string]models.PreferenceFieldChange, old, updated models.PreferenceMatrix) {
+	cell := func(m models.PreferenceMatrix, category models.NotificationChannel, notifType models.NotificationType) *bool {
+		if enabled, ok := m.Get(category, notifType); ok {
+			return &enabled
+		}
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, m := range []models.PreferenceMatrix{old, updated} {
+		for category, types := range m {
+			for notifType := range types {
+				name := "preferences." + string(category) + "." + string(notifType)
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				o, n := cell(old, category, notifType), cell(updated, category, notifType)
+				if o == nil && n == nil || o != nil && n != nil && *o == *n {
+					continue
+				}
+				changes[name] = models.PreferenceFieldChange{Old: o, New: n}
+			}
+		}
+	}
+}
+
+func diffString(changes map[string]models.PreferenceFieldChange, name string, o, n *string) {
+	if o == nil && n == nil || o != nil && n != nil && *o == *n {
+		return
+	}
+	changes[name] = models.PreferenceFieldChange{Old: o, New: n}
+}
+
+// toUserPreferences builds a user's overrides from a request, treating empty strings as inherit
+func toUserPreferences(merchantID, userID string, o dto.UserPreferenceOverrides) *models.UserNotificationPreferences {
+	nonEmpty := func(v *string) *string {
+		if v == nil || *v == "" {
+			return nil
+		}
+		return v
+	}
+	prefs := &models.UserNotificationPreferences{
+		MerchantID:      merchantID,
+		UserID:          userID,
+		Matrix:          models.PreferenceMatrix{},
+		EmailAddress:    nonEmpty(o.EmailAddress),
+		PhoneNumber:     nonEmpty(o.PhoneNumber),
+		Locale:          nonEmpty(o.Locale),
+		Timezone:        nonEmpty(o.Timezone),
+		QuietHoursStart: nonEmpty(o.QuietHoursStart),
+		QuietHoursEnd:   nonEmpty(o.QuietHoursEnd),
+	}
+	applyPreferenceCells(prefs.Matrix, o.Preferences)
+	return prefs
+}
+
+// diffUserPreferences lists the overrides that differ, keyed as in diffPreferences;
+// a nil old or new value means the field inherits
+func diffUserPreferences(old, updated *models.UserNotificationPreferences) map[string]models.PreferenceFieldChange {
+	if old == nil {
+		old = &models.UserNotificationPreferences{}
+	}
+
+	changes := map[string]models.PreferenceFieldChange{}
+	diffPreferenceMatrix(changes, old.Matrix, updated.Matrix)
+	diffString(changes, "email_address", old.EmailAddress, updated.EmailAddress)
+	diffString(changes, "phone_number", old.PhoneNumber, updated.PhoneNumber)
+	diffString(changes, "locale", old.Locale, updated.Locale)
+	diffString(changes, "timezone", old.Timezone, updated.Timezone)
+	diffString(changes, "quiet_hours_start", old.QuietHoursStart, updated.QuietHoursStart)
+	diffString(changes, "quiet_hours_end", old.QuietHoursEnd, updated.QuietHoursEnd)
+	return changes
+}
+
+func toUserPreferencesResponse(
+	merchant *models.NotificationPreferences,
+	user *models.UserNotificationPreferences,
+	merchantID, userID string,
+) dto.UserNotificationPreferencesResponse {
+	resp := dto.UserNotificationPreferencesResponse{
+		MerchantID: merchantID,
+		UserID:     userID,
+		Effective:  toPreferencesResponse(merchant.WithUserOverrides(user)),
+	}
+	if user != nil {
+		cells := dto.PreferenceCells{}
+		for category, types := range user.Matrix {
+			cells[string(category)] = map[string]*bool{}
+			for notifType, enabled := range types {
+				enabled := enabled
+				cells[string(category)][string(notifType)] = &enabled
+			}
+		}
+		resp.Overrides = dto.UserPreferenceOverrides{
+			Preferences:     cells,
+			EmailAddress:    user.EmailAddress,
+			PhoneNumber:     user.PhoneNumber,
+			Locale:          user.Locale,
+			Timezone:        user.Timezone,
+			QuietHoursStart: user.QuietHoursStart,
+			QuietHoursEnd:   user.QuietHoursEnd,
+		}
+		updatedAt := user.UpdatedAt.Format(time.RFC3339Nano)
+		resp.UpdatedAt = &updatedAt
+		resp.ETag = PreferencesETag(user.UpdatedAt)
+	}
+	return resp
+}
+
+// toPreferencesResponse resolves every cell of the matrix, so clients see the
+// defaults and mandatory categories as well as the explicit settings
+func toPreferencesResponse(prefs *models.NotificationPreferences) dto.NotificationPreferencesResponse {
+	matrix := make(map[string]map[string]bool)
+	for _, category := range prefs.Matrix.Categories() {
+		cells := make(map[string]bool, len(models.NotificationTypes))
+		for _, notifType := range models.NotificationTypes {
+			cells[string(notifType)] = prefs.ShouldSend(notifType, category)
+		}
+		matrix[string(category)] = cells
+	}
+
+	mandatory := make([]string, len(models.MandatoryCategories))
+	for i, category := range models.MandatoryCategories {
+		mandatory[i] = string(category)
+	}
+
+	return dto.NotificationPreferencesResponse{
+		MerchantID:          prefs.MerchantID,
+		Preferences:         matrix,
+		MandatoryCategories: mandatory,
+		EmailAddress:        prefs.EmailAddress,
+		PhoneNumber:         prefs.PhoneNumber,
+		Locale:              prefs.Locale,
+		Timezone:            prefs.Timezone,
+		QuietHoursStart:     prefs.QuietHoursStart,
+		QuietHoursEnd:       prefs.QuietHoursEnd,
+		UpdatedAt:           prefs.UpdatedAt.Format(time.RFC3339Nano),
+		ETag:                PreferencesETag(prefs.UpdatedAt),
+	}
+}
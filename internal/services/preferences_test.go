@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+func TestCheckPreferencesPrecondition(t *testing.T) {
+	current := time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	etag := PreferencesETag(current)
+	stale := PreferencesETag(current.Add(-time.Second))
+	strPtr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		ifMatch   string
+		updatedAt *string
+		wantErr   error
+	}{
+		{name: "matching tag", ifMatch: etag},
+		{name: "weak tag", ifMatch: "W/" + etag},
+		{name: "one of several tags", ifMatch: stale + ", " + etag},
+		{name: "any version", ifMatch: "*"},
+		{name: "stale tag", ifMatch: stale, wantErr: repositories.ErrPreferencesModified},
+		{name: "weak stale tag", ifMatch: "W/" + stale, wantErr: repositories.ErrPreferencesModified},
+		{name: "unquoted tag", ifMatch: strings.Trim(etag, `"`), wantErr: repositories.ErrPreferencesModified},
+		{name: "updated_at read", updatedAt: strPtr(current.Format(time.RFC3339Nano))},
+		{name: "stale updated_at", updatedAt: strPtr(current.Add(-time.Second).Format(time.RFC3339Nano)), wantErr: repositories.ErrPreferencesModified},
+		{name: "If-Match wins over updated_at", ifMatch: stale, updatedAt: strPtr(current.Format(time.RFC3339Nano)), wantErr: repositories.ErrPreferencesModified},
+		{name: "no precondition", wantErr: ErrPreconditionRequired},
+		{name: "blank If-Match", ifMatch: "  ", wantErr: ErrPreconditionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPreferencesPrecondition(current, tt.updatedAt, tt.ifMatch)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var validationErr *ValidationError
+	if err := checkPreferencesPrecondition(current, strPtr("yesterday"), ""); !errors.As(err, &validationErr) {
+		t.Errorf("malformed updated_at: err = %v, want a ValidationError", err)
+	}
+}
+
+// fakeMerchantPreferences answers the queries for one merchant's preferences,
+// applying UpdateIfUnmodified's updated_at condition the way Postgres would
+type fakeMerchantPreferences struct {
+	mu        sync.Mutex
+	updatedAt time.Time
+}
+
+func (f *fakeMerchantPreferences) query(query string, args []driver.Value) ([]string, [][]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.Contains(query, "AND updated_at = $8"):
+		if !args[7].(time.Time).Equal(f.updatedAt) {
+			return nil, nil
+		}
+		f.updatedAt = f.updatedAt.Add(time.Minute)
+		return []string{"updated_at"}, [][]driver.Value{{f.updatedAt}}
+	case strings.Contains(query, "INSERT INTO notification_preference_changes"):
+		return []string{"id", "created_at"}, [][]driver.Value{{"change-1", time.Now()}}
+	case strings.Contains(query, "FROM notification_preferences"):
+		return []string{"id", "merchant_id", "email_address", "phone_number", "locale", "timezone",
+				"quiet_hours_start", "quiet_hours_end", "created_at", "updated_at"},
+			[][]driver.Value{{"prefs-1", "merchant-1", nil, nil, nil, nil, nil, nil, f.updatedAt, f.updatedAt}}
+	}
+	return nil, nil
+}
+
+func newPreferencesFixture(t *testing.T) (*PreferencesService, *fakeMerchantPreferences, *fakeDB) {
+	t.Helper()
+	prefs := &fakeMerchantPreferences{updatedAt: time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)}
+	fake, db := newFakeDB(t)
+	fake.query = prefs.query
+	s := NewPreferencesService(repositories.NewNotificationPreferencesRepository(db), nil, nil)
+	return s, prefs, fake
+}
+
+func marketingEmailOff() dto.NotificationPreferencesRequest {
+	off := false
+	return dto.NotificationPreferencesRequest{Preferences: dto.PreferenceCells{"marketing": {"email": &off}}}
+}
+
+func TestPatchPreferencesIsConditionalAndAudited(t *testing.T) {
+	s, prefs, fake := newPreferencesFixture(t)
+	ctx := context.Background()
+	read := PreferencesETag(prefs.updatedAt)
+	actor, requestID := "admin-7", "req-42"
+
+	resp, err := s.Patch(ctx, "merchant-1", marketingEmailOff(), PreferenceUpdate{IfMatch: read, ChangedBy: &actor, RequestID: &requestID})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if resp.ETag == read || resp.ETag != PreferencesETag(prefs.updatedAt) {
+		t.Errorf("ETag = %s, want the new version's %s", resp.ETag, PreferencesETag(prefs.updatedAt))
+	}
+	if resp.Preferences["marketing"]["email"] {
+		t.Error("marketing email still enabled")
+	}
+
+	audits := fake.execsMatching("INSERT INTO notification_preference_changes")
+	if len(audits) != 1 {
+		t.Fatalf("audit rows = %d, want 1", len(audits))
+	}
+	audit := audits[0].args
+	if audit[0] != "merchant-1" || audit[1] != nil || audit[3] != actor || audit[4] != requestID {
+		t.Errorf("audit row = (%v, %v, %v, %v), want the merchant, actor and request", audit[0], audit[1], audit[3], audit[4])
+	}
+	var changes map[string]models.PreferenceFieldChange
+	if err := json.Unmarshal(audit[2].([]byte), &changes); err != nil {
+		t.Fatalf("audit changes: %v", err)
+	}
+	if c, ok := changes["preferences.marketing.email"]; !ok || c.Old != nil || c.New != false || len(changes) != 1 {
+		t.Errorf("audit changes = %+v, want only marketing email set off", changes)
+	}
+	if fake.commits != 1 {
+		t.Errorf("commits = %d, want the update and its audit row committed together", fake.commits)
+	}
+
+	// The tag read before the update is now stale
+	_, err = s.Patch(ctx, "merchant-1", marketingEmailOff(), PreferenceUpdate{IfMatch: read})
+	if !errors.Is(err, repositories.ErrPreferencesModified) {
+		t.Fatalf("stale If-Match: err = %v, want ErrPreferencesModified", err)
+	}
+	if n := len(fake.execsMatching("INSERT INTO notification_preference_changes")); n != 1 {
+		t.Errorf("audit rows = %d after a refused update, want 1", n)
+	}
+}
+
+func TestPatchPreferencesRequiresPrecondition(t *testing.T) {
+	s, _, fake := newPreferencesFixture(t)
+
+	_, err := s.Patch(context.Background(), "merchant-1", marketingEmailOff(), PreferenceUpdate{})
+	if !errors.Is(err, ErrPreconditionRequired) {
+		t.Fatalf("err = %v, want ErrPreconditionRequired", err)
+	}
+	if n := len(fake.execsMatching("UPDATE notification_preferences")); n != 0 {
+		t.Errorf("%d updates without a precondition, want none", n)
+	}
+}
+
+func TestPatchPreferencesLostUpdate(t *testing.T) {
+	s, prefs, fake := newPreferencesFixture(t)
+	read := PreferencesETag(prefs.updatedAt)
+
+	// Another writer commits between this request's read and its update
+	fake.query = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "AND updated_at = $8") {
+			prefs.mu.Lock()
+			prefs.updatedAt = prefs.updatedAt.Add(time.Second)
+			prefs.mu.Unlock()
+		}
+		return prefs.query(query, args)
+	}
+
+	_, err := s.Patch(context.Background(), "merchant-1", marketingEmailOff(), PreferenceUpdate{IfMatch: read})
+	if !errors.Is(err, repositories.ErrPreferencesModified) {
+		t.Fatalf("err = %v, want ErrPreferencesModified", err)
+	}
+	if n := len(fake.execsMatching("INSERT INTO notification_preference_changes")); n != 0 {
+		t.Errorf("audit rows = %d for a lost update, want none", n)
+	}
+	if fake.commits != 0 {
+		t.Errorf("commits = %d, want the update rolled back", fake.commits)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// RetryPolicy bounds delivery attempts and computes exponential backoff with jitter
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+}
+
+// RetryPolicies holds the retry policy for each notification type
+type RetryPolicies map[models.NotificationType]RetryPolicy
+
+var defaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute, Jitter: 0.2}
+
+func NewRetryPolicies(cfg config.RetryConfig) RetryPolicies {
+	jitter := math.Min(math.Max(cfg.Jitter, 0), 1)
+	toPolicy := func(c config.RetryPolicyConfig) RetryPolicy {
+		p := RetryPolicy{MaxAttempts: c.MaxAttempts, BaseDelay: c.BaseDelay, MaxDelay: c.MaxDelay, Jitter: jitter}
+		if p.MaxAttempts <= 0 {
+			p.MaxAttempts = 1
+		}
+		if p.BaseDelay <= 0 {
+			p.BaseDelay = defaultRetryPolicy.BaseDelay
+		}
+		if p.MaxDelay < p.BaseDelay {
+			p.MaxDelay = p.BaseDelay
+		}
+		return p
+	}
+
+	return RetryPolicies{
+		models.TypeEmail: toPolicy(cfg.Email),
+		models.TypeSMS:   toPolicy(cfg.SMS),
+		models.TypeVoice: toPolicy(cfg.Voice),
+		models.TypePush:  toPolicy(cfg.Push),
+	}
+}
+
+// For returns the policy for a notification type, falling back to a conservative default
+func (p RetryPolicies) For(notifType models.NotificationType) RetryPolicy {
+	if policy, ok := p[notifType]; ok {
+		return policy
+	}
+	return defaultRetryPolicy
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts
+func (p RetryPolicy) Backoff(failedAttempts int) time.Duration {
+	if failedAttempts < 1 {
+		failedAttempts = 1
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(failedAttempts-1))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	// Randomise the top Jitter fraction of the delay so retries from a provider
+	// outage do not all land at the same instant
+	delay -= delay * p.Jitter * rand.Float64()
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+// ScheduledJob is a housekeeping task run on an interval. Run returns the number
+// of rows it affected.
+type ScheduledJob struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (int64, error)
+}
+
+// Scheduler runs housekeeping jobs in the background. Every replica runs a
+// scheduler, but each job run takes a Postgres advisory lock and is skipped
+// unless its interval has passed since the last run on any replica, so a job
+// runs once per interval across the deployment.
+type Scheduler struct {
+	repo   *repositories.JobRepository
+	cfg    config.SchedulerConfig
+	jobs   []ScheduledJob
+	runner string
+}
+
+func NewScheduler(repo *repositories.JobRepository, cfg config.SchedulerConfig, jobs ...ScheduledJob) *Scheduler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = 30 * time.Second
+	}
+	if cfg.JobTimeout <= 0 {
+		cfg.JobTimeout = 5 * time.Minute
+	}
+
+	// Jobs without an interval are disabled
+	enabled := make([]ScheduledJob, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Interval > 0 {
+			enabled = append(enabled, job)
+		}
+	}
+
+	runner, err := os.Hostname()
+	if err != nil {
+		runner = "unknown"
+	}
+
+	return &Scheduler{repo: repo, cfg: cfg, jobs: enabled, runner: runner}
+}
+
+// Run checks for due jobs every tick until ctx is cancelled. A job in progress
+// is allowed to finish before Run returns.
+func (s *Scheduler) Run(ctx context.Context) {
+	if !s.cfg.Enabled {
+		log.Printf("Job scheduler disabled")
+		return
+	}
+
+	log.Printf("Job scheduler started (%d jobs, tick=%s)", len(s.jobs), s.cfg.Tick)
+	ticker := time.NewTicker(s.cfg.Tick)
+	defer ticker.Stop()
+
+	for {
+		for _, job := range s.jobs {
+			if ctx.Err() != nil {
+				break
+			}
+			s.runJob(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Job scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob runs one job if this replica wins its lock and the job is due
+func (s *Scheduler) runJob(ctx context.Context, job ScheduledJob) {
+	// Once started, a job is not cancelled on shutdown so it can record its
+	// outcome; cancelling would also roll back the lock's transaction
+	runCtx := context.WithoutCancel(ctx)
+
+	lease, err := s.repo.Acquire(runCtx, job.Name, job.Interval)
+	if err != nil {
+		log.Printf("Failed to acquire job %s: %v", job.Name, err)
+		return
+	}
+	if lease == nil {
+		return
+	}
+
+	run := &models.JobRun{Runner: s.runner, StartedAt: time.Now()}
+
+	jobCtx, cancel := context.WithTimeout(runCtx, s.cfg.JobTimeout)
+	affected, err := job.Run(jobCtx)
+	cancel()
+
+	run.FinishedAt = time.Now()
+	run.Affected = affected
+	run.Status = models.JobSucceeded
+	if err != nil {
+		msg := err.Error()
+		run.Status = models.JobFailed
+		run.Error = &msg
+		log.Printf("Job %s failed after %s: %v", job.Name, run.FinishedAt.Sub(run.StartedAt), err)
+	} else {
+		log.Printf("Job %s finished in %s (%d affected)", job.Name, run.FinishedAt.Sub(run.StartedAt), affected)
+	}
+
+	if err := lease.Finish(runCtx, run); err != nil {
+		log.Printf("Failed to record run of job %s: %v", job.Name, err)
+	}
+}
+
+// Status lists the scheduled jobs with their latest run on any replica
+func (s *Scheduler) Status(ctx context.Context) (dto.JobStatusListResponse, error) {
+	runs, err := s.repo.List(ctx)
+	if err != nil {
+		return dto.JobStatusListResponse{}, err
+	}
+
+	latest := make(map[string]*models.JobRun, len(runs))
+	for _, run := range runs {
+		latest[run.Name] = run
+	}
+
+	jobs := make([]dto.JobStatusResponse, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := dto.JobStatusResponse{Name: job.Name, Interval: job.Interval.String()}
+		if run, ok := latest[job.Name]; ok {
+			status.LastRun = toJobRunResponse(run)
+		}
+		jobs = append(jobs, status)
+	}
+
+	return dto.JobStatusListResponse{Enabled: s.cfg.Enabled, Jobs: jobs}, nil
+}
+
+func toJobRunResponse(run *models.JobRun) *dto.JobRunResponse {
+	resp := &dto.JobRunResponse{
+		Status:     string(run.Status),
+		Affected:   run.Affected,
+		Error:      run.Error,
+		Runner:     run.Runner,
+		StartedAt:  run.StartedAt.Format(time.RFC3339),
+		FinishedAt: run.FinishedAt.Format(time.RFC3339),
+	}
+	if run.LastSucceededAt != nil {
+		succeededAt := run.LastSucceededAt.Format(time.RFC3339)
+		resp.LastSucceededAt = &succeededAt
+	}
+	return resp
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+// fakeJobStore answers the JobRepository's statements: the advisory lock is
+// held elsewhere while lockedElsewhere is set, and runs records each job's
+// latest start like scheduled_job_runs
+type fakeJobStore struct {
+	mu              sync.Mutex
+	lockedElsewhere bool
+	runs            map[string]time.Time
+}
+
+func (f *fakeJobStore) query(query string, args []driver.Value) ([]string, [][]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "pg_try_advisory_xact_lock"):
+		return []string{"locked"}, [][]driver.Value{{!f.lockedElsewhere}}
+	case strings.Contains(query, "FROM scheduled_job_runs"):
+		started, ok := f.runs[args[0].(string)]
+		window := time.Duration(args[1].(float64) * float64(time.Second))
+		due := !ok || !started.After(time.Now().Add(-window))
+		return []string{"due"}, [][]driver.Value{{due}}
+	}
+	return nil, nil
+}
+
+func (f *fakeJobStore) affected(query string, args []driver.Value) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.Contains(query, "INSERT INTO scheduled_job_runs") {
+		f.runs[args[0].(string)] = args[5].(time.Time)
+	}
+	return 1
+}
+
+func newSchedulerFixture(t *testing.T, job ScheduledJob) (*Scheduler, *fakeJobStore, *fakeDB) {
+	t.Helper()
+	store := &fakeJobStore{runs: map[string]time.Time{}}
+	fake, db := newFakeDB(t)
+	fake.query = store.query
+	fake.affected = store.affected
+	s := NewScheduler(repositories.NewJobRepository(db), config.SchedulerConfig{Enabled: true}, job)
+	return s, store, fake
+}
+
+func TestSchedulerRunsDueJobsOncePerInterval(t *testing.T) {
+	runs := 0
+	job := ScheduledJob{Name: "cleanup", Interval: time.Hour, Run: func(context.Context) (int64, error) {
+		runs++
+		return 3, nil
+	}}
+	s, store, fake := newSchedulerFixture(t, job)
+	ctx := context.Background()
+
+	s.runJob(ctx, job)
+	if runs != 1 {
+		t.Fatalf("job ran %d times, want 1", runs)
+	}
+	recorded := fake.execsMatching("INSERT INTO scheduled_job_runs")
+	if len(recorded) != 1 || recorded[0].args[1] != string(models.JobSucceeded) || recorded[0].args[2] != int64(3) {
+		t.Fatalf("recorded runs = %+v, want one succeeded run affecting 3", recorded)
+	}
+	if fake.commits != 1 {
+		t.Errorf("commits = %d, want the run recorded with the lock", fake.commits)
+	}
+
+	// Another replica, or the next tick, finds the job's interval not yet passed
+	s.runJob(ctx, job)
+	if runs != 1 {
+		t.Errorf("job ran again within its interval")
+	}
+
+	// Once the interval has passed since the last start, the job is due again
+	store.runs["cleanup"] = time.Now().Add(-2 * time.Hour)
+	s.runJob(ctx, job)
+	if runs != 2 {
+		t.Errorf("job ran %d times after its interval passed, want 2", runs)
+	}
+}
+
+func TestSchedulerSkipsJobLockedElsewhere(t *testing.T) {
+	runs := 0
+	job := ScheduledJob{Name: "cleanup", Interval: time.Hour, Run: func(context.Context) (int64, error) {
+		runs++
+		return 0, nil
+	}}
+	s, store, fake := newSchedulerFixture(t, job)
+	store.lockedElsewhere = true
+
+	s.runJob(context.Background(), job)
+
+	if runs != 0 {
+		t.Errorf("job ran while another replica held its lock")
+	}
+	if n := len(fake.execsMatching("scheduled_job_runs")); n != 0 {
+		t.Errorf("%d scheduled_job_runs statements without the lock, want none", n)
+	}
+	if fake.rollbacks != 1 || fake.commits != 0 {
+		t.Errorf("commits = %d, rollbacks = %d; want the lock's transaction rolled back", fake.commits, fake.rollbacks)
+	}
+}
+
+func TestSchedulerRecordsFailedRuns(t *testing.T) {
+	job := ScheduledJob{Name: "cleanup", Interval: time.Hour, Run: func(context.Context) (int64, error) {
+		return 0, context.DeadlineExceeded
+	}}
+	s, _, fake := newSchedulerFixture(t, job)
+
+	s.runJob(context.Background(), job)
+
+	recorded := fake.execsMatching("INSERT INTO scheduled_job_runs")
+	if len(recorded) != 1 || recorded[0].args[1] != string(models.JobFailed) {
+		t.Fatalf("recorded runs = %+v, want one failed run", recorded)
+	}
+	if msg, _ := recorded[0].args[3].(string); msg != context.DeadlineExceeded.Error() {
+		t.Errorf("recorded error = %q", msg)
+	}
+	if recorded[0].args[7] != nil {
+		t.Errorf("last_succeeded_at = %v for a failed run, want NULL", recorded[0].args[7])
+	}
+}
@@ -0,0 +1,441 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/templates"
+)
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,99}$`)
+	localePattern       = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+)
+
+// TemplateService manages stored templates: draft editing, immutable versions and publishing
+type TemplateService struct {
+	repo          *repositories.TemplateRepository
+	engine        *templates.Engine
+	notifService  *NotificationServiceV2
+	testAllowlist []string
+}
+
+func NewTemplateService(
+	repo *repositories.TemplateRepository,
+	engine *templates.Engine,
+	notifService *NotificationServiceV2,
+	testAllowlist []string,
+) *TemplateService {
+	return &TemplateService{
+		repo:          repo,
+		engine:        engine,
+		notifService:  notifService,
+		testAllowlist: testAllowlist,
+	}
+}
+
+func (s *TemplateService) Create(ctx context.Context, req dto.CreateTemplateRequest) (dto.TemplateResponse, error) {
+	if !templateNamePattern.MatchString(req.Name) {
+		return dto.TemplateResponse{}, validationErrorf("name must be lowercase letters, digits, '.', '_' or '-'")
+	}
+	locale, err := resolveLocale(req.Locale)
+	if err != nil {
+		return dto.TemplateResponse{}, err
+	}
+
+	tmpl := &models.NotificationTemplate{
+		Name:              req.Name,
+		Locale:            locale,
+		Description:       req.Description,
+		DraftSubject:      req.Subject,
+		DraftText:         req.TextBody,
+		DraftHTML:         req.HTMLBody,
+		DraftRequiredVars: nonNilStrings(req.RequiredVars),
+	}
+	if err := s.repo.Create(ctx, tmpl); err != nil {
+		return dto.TemplateResponse{}, err
+	}
+
+	return toTemplateResponse(tmpl), nil
+}
+
+func (s *TemplateService) Get(ctx context.Context, name, locale string) (dto.TemplateResponse, error) {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.TemplateResponse{}, err
+	}
+
+	tmpl, err := s.repo.GetByName(ctx, name, locale)
+	if err != nil {
+		return dto.TemplateResponse{}, err
+	}
+	return toTemplateResponse(tmpl), nil
+}
+
+func (s *TemplateService) List(ctx context.Context) (dto.TemplateListResponse, error) {
+	tmpls, err := s.repo.List(ctx)
+	if err != nil {
+		return dto.TemplateListResponse{}, err
+	}
+
+	resp := dto.TemplateListResponse{Templates: []dto.TemplateResponse{}}
+	for _, tmpl := range tmpls {
+		resp.Templates = append(resp.Templates, toTemplateResponse(tmpl))
+	}
+	return resp, nil
+}
+
+// UpdateDraft replaces the draft; published versions are unaffected
+func (s *TemplateService) UpdateDraft(ctx context.Context, name, locale string, req dto.UpdateTemplateDraftRequest) (dto.TemplateResponse, error) {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.TemplateResponse{}, err
+	}
+
+	tmpl := &models.NotificationTemplate{
+		Name:              name,
+		Locale:            locale,
+		Description:       req.Description,
+		DraftSubject:      req.Subject,
+		DraftText:         req.TextBody,
+		DraftHTML:         req.HTMLBody,
+		DraftRequiredVars: nonNilStrings(req.RequiredVars),
+	}
+	if err := s.repo.UpdateDraft(ctx, tmpl); err != nil {
+		return dto.TemplateResponse{}, err
+	}
+	return toTemplateResponse(tmpl), nil
+}
+
+func (s *TemplateService) Delete(ctx context.Context, name, locale string) error {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, name, locale)
+}
+
+// CreateVersion validates the current draft and snapshots it as the next immutable version
+func (s *TemplateService) CreateVersion(ctx context.Context, name, locale string) (dto.TemplateVersionResponse, error) {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.TemplateVersionResponse{}, err
+	}
+
+	tmpl, err := s.repo.GetByName(ctx, name, locale)
+	if err != nil {
+		return dto.TemplateVersionResponse{}, err
+	}
+
+	draft := &templates.Template{
+		Name:     tmpl.Name,
+		Locale:   tmpl.Locale,
+		Subject:  tmpl.DraftSubject,
+		Text:     tmpl.DraftText,
+		HTML:     tmpl.DraftHTML,
+		Required: tmpl.DraftRequiredVars,
+	}
+	if err := s.engine.Validate(draft); err != nil {
+		return dto.TemplateVersionResponse{}, validationErrorf("%v", err)
+	}
+
+	version, err := s.repo.CreateVersion(ctx, name, locale)
+	if err != nil {
+		return dto.TemplateVersionResponse{}, err
+	}
+
+	return toTemplateVersionResponse(version, tmpl.PublishedVersion), nil
+}
+
+func (s *TemplateService) GetVersion(ctx context.Context, name, locale string, version int) (dto.TemplateVersionResponse, error) {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.TemplateVersionResponse{}, err
+	}
+
+	tmpl, err := s.repo.GetByName(ctx, name, locale)
+	if err != nil {
+		return dto.TemplateVersionResponse{}, err
+	}
+
+	v, err := s.repo.GetVersion(ctx, name, locale, version)
+	if err != nil {
+		return dto.TemplateVersionResponse{}, err
+	}
+
+	return toTemplateVersionResponse(v, tmpl.PublishedVersion), nil
+}
+
+func (s *TemplateService) ListVersions(ctx context.Context, name, locale string) (dto.TemplateVersionListResponse, error) {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.TemplateVersionListResponse{}, err
+	}
+
+	tmpl, err := s.repo.GetByName(ctx, name, locale)
+	if err != nil {
+		return dto.TemplateVersionListResponse{}, err
+	}
+
+	versions, err := s.repo.ListVersions(ctx, name, locale)
+	if err != nil {
+		return dto.TemplateVersionListResponse{}, err
+	}
+
+	resp := dto.TemplateVersionListResponse{Versions: []dto.TemplateVersionResponse{}}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, toTemplateVersionResponse(v, tmpl.PublishedVersion))
+	}
+	return resp, nil
+}
+
+// Publish makes a version the one used by notifications that do not pin a version
+func (s *TemplateService) Publish(ctx context.Context, name, locale string, req dto.PublishTemplateRequest) (dto.TemplateResponse, error) {
+	if req.Version <= 0 {
+		return dto.TemplateResponse{}, validationErrorf("version is required")
+	}
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.TemplateResponse{}, err
+	}
+
+	if err := s.repo.Publish(ctx, name, locale, req.Version); err != nil {
+		return dto.TemplateResponse{}, err
+	}
+
+	return s.Get(ctx, name, locale)
+}
+
+// Preview renders a template version, or the current draft, with sample data without persisting anything
+func (s *TemplateService) Preview(ctx context.Context, name, locale string, req dto.PreviewTemplateRequest) (dto.TemplatePreviewResponse, error) {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.TemplatePreviewResponse{}, err
+	}
+
+	rendered, err := s.renderSample(ctx, name, locale, req)
+	if err != nil {
+		return dto.TemplatePreviewResponse{}, err
+	}
+
+	return dto.TemplatePreviewResponse{
+		Name:     name,
+		Locale:   locale,
+		Version:  req.Version,
+		Subject:  rendered.Subject,
+		TextBody: rendered.Text,
+		HTMLBody: rendered.HTML,
+	}, nil
+}
+
+// TestSend renders a template version, or the current draft, and queues it to an
+// allowlisted internal recipient. The notification is marked test=true so it is
+// excluded from stats.
+func (s *TemplateService) TestSend(ctx context.Context, name, locale string, req dto.TestSendTemplateRequest) (dto.NotificationResponse, error) {
+	locale, err := resolveLocale(locale)
+	if err != nil {
+		return dto.NotificationResponse{}, err
+	}
+
+	notifType := models.NotificationType(req.Type)
+	if notifType == "" {
+		notifType = models.TypeEmail
+	}
+	if notifType != models.TypeEmail && notifType != models.TypeSMS {
+		return dto.NotificationResponse{}, validationErrorf("test sends support email and sms only")
+	}
+	if req.To == "" {
+		return dto.NotificationResponse{}, validationErrorf("to is required")
+	}
+	if !s.testRecipientAllowed(req.To) {
+		return dto.NotificationResponse{}, ErrRecipientNotAllowed
+	}
+
+	rendered, err := s.renderSample(ctx, name, locale, req.PreviewTemplateRequest)
+	if err != nil {
+		return dto.NotificationResponse{}, err
+	}
+
+	notif := &models.Notification{
+		Type:            notifType,
+		Channel:         models.ChannelSystem,
+		Recipient:       req.To,
+		Message:         rendered.Text,
+		TemplateName:    &name,
+		TemplateVersion: req.Version,
+		TemplateData:    req.TemplateData,
+		Locale:          &locale,
+		Metadata: map[string]interface{}{
+			"test":            true,
+			"template_locale": rendered.Locale,
+		},
+	}
+	if rendered.Subject != "" {
+		notif.Subject = &rendered.Subject
+	}
+	if rendered.HTML != "" {
+		notif.HTMLMessage = &rendered.HTML
+	}
+
+	if err := s.notifService.Enqueue(ctx, notif); err != nil {
+		return dto.NotificationResponse{}, err
+	}
+
+	return dto.NotificationResponse{
+		ID:              notif.ID,
+		Status:          string(notif.Status),
+		TemplateName:    notif.TemplateName,
+		TemplateVersion: notif.TemplateVersion,
+	}, nil
+}
+
+// renderSample renders the requested version of a template variant, or its draft when no version is given
+func (s *TemplateService) renderSample(ctx context.Context, name, locale string, req dto.PreviewTemplateRequest) (*templates.Rendered, error) {
+	var tmpl *templates.Template
+	if req.Version != nil {
+		v, err := s.repo.GetVersion(ctx, name, locale, *req.Version)
+		if err != nil {
+			return nil, err
+		}
+		tmpl = &templates.Template{
+			Name:     v.Name,
+			Locale:   v.Locale,
+			Version:  v.Version,
+			Subject:  v.Subject,
+			Text:     v.TextBody,
+			HTML:     v.HTMLBody,
+			Required: v.RequiredVars,
+		}
+	} else {
+		t, err := s.repo.GetByName(ctx, name, locale)
+		if err != nil {
+			return nil, err
+		}
+		tmpl = &templates.Template{
+			Name:     t.Name,
+			Locale:   t.Locale,
+			Subject:  t.DraftSubject,
+			Text:     t.DraftText,
+			HTML:     t.DraftHTML,
+			Required: t.DraftRequiredVars,
+		}
+	}
+
+	// Parse and execution failures here come from the draft or the sample data
+	rendered, err := s.engine.RenderTemplate(tmpl, locale, req.TemplateData)
+	if err != nil {
+		return nil, validationErrorf("%v", err)
+	}
+	return rendered, nil
+}
+
+// testRecipientAllowed matches an address or phone number against the test-send allowlist
+func (s *TemplateService) testRecipientAllowed(to string) bool {
+	to = strings.ToLower(strings.TrimSpace(to))
+	for _, entry := range s.testAllowlist {
+		entry = strings.ToLower(entry)
+		if strings.HasPrefix(entry, "@") {
+			if strings.HasSuffix(to, entry) {
+				return true
+			}
+		} else if to == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateSource serves published or pinned template versions from Postgres to the template engine
+type TemplateSource struct {
+	repo *repositories.TemplateRepository
+}
+
+func NewTemplateSource(repo *repositories.TemplateRepository) *TemplateSource {
+	return &TemplateSource{repo: repo}
+}
+
+func (s *TemplateSource) Get(ctx context.Context, name, locale string, version *int) (*templates.Template, error) {
+	var v *models.TemplateVersion
+	var err error
+	if version != nil {
+		v, err = s.repo.GetVersion(ctx, name, locale, *version)
+	} else {
+		v, err = s.repo.GetPublished(ctx, name, locale)
+	}
+
+	if errors.Is(err, repositories.ErrTemplateVersionNotFound) {
+		return nil, templates.ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &templates.Template{
+		Name:     v.Name,
+		Locale:   v.Locale,
+		Version:  v.Version,
+		Subject:  v.Subject,
+		Text:     v.TextBody,
+		HTML:     v.HTMLBody,
+		Required: v.RequiredVars,
+	}, nil
+}
+
+func toTemplateResponse(tmpl *models.NotificationTemplate) dto.TemplateResponse {
+	return dto.TemplateResponse{
+		ID:          tmpl.ID,
+		Name:        tmpl.Name,
+		Locale:      tmpl.Locale,
+		Description: tmpl.Description,
+		Draft: dto.TemplateContent{
+			Subject:      tmpl.DraftSubject,
+			TextBody:     tmpl.DraftText,
+			HTMLBody:     tmpl.DraftHTML,
+			RequiredVars: nonNilStrings(tmpl.DraftRequiredVars),
+		},
+		PublishedVersion: tmpl.PublishedVersion,
+		CreatedAt:        tmpl.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        tmpl.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toTemplateVersionResponse(v *models.TemplateVersion, publishedVersion *int) dto.TemplateVersionResponse {
+	return dto.TemplateVersionResponse{
+		Name:      v.Name,
+		Locale:    v.Locale,
+		Version:   v.Version,
+		Published: publishedVersion != nil && *publishedVersion == v.Version,
+		TemplateContent: dto.TemplateContent{
+			Subject:      v.Subject,
+			TextBody:     v.TextBody,
+			HTMLBody:     v.HTMLBody,
+			RequiredVars: nonNilStrings(v.RequiredVars),
+		},
+		CreatedAt: v.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// resolveLocale normalises a requested locale, defaulting to the template fallback locale
+func resolveLocale(locale string) (string, error) {
+	locale = templates.NormalizeLocale(locale)
+	if locale == "" {
+		return templates.DefaultLocale, nil
+	}
+	if !localePattern.MatchString(locale) {
+		return "", validationErrorf("invalid locale %q", locale)
+	}
+	return locale, nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+	"github.com/kodra-pay/notification-service/internal/templates"
+)
+
+// fakeTemplateStore keeps one template variant and its versions in memory and
+// answers the TemplateRepository's statements against them
+type fakeTemplateStore struct {
+	mu        sync.Mutex
+	created   bool
+	draft     [4]string // subject, text, html, required vars
+	published *int64
+	versions  [][4]string
+}
+
+func (f *fakeTemplateStore) query(query string, args []driver.Value) ([]string, [][]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+
+	switch {
+	case strings.Contains(query, "INSERT INTO notification_templates"):
+		f.created = true
+		f.draft = [4]string{args[3].(string), args[4].(string), args[5].(string), requiredVars(args[6])}
+		return []string{"id", "created_at", "updated_at"}, [][]driver.Value{{"tmpl-1", now, now}}
+	case !f.created:
+		return nil, nil
+	case strings.Contains(query, "UPDATE notification_templates SET\n\t\t\tdescription"):
+		f.draft = [4]string{args[3].(string), args[4].(string), args[5].(string), requiredVars(args[6])}
+		return []string{"id", "published_version", "created_at", "updated_at"},
+			[][]driver.Value{{"tmpl-1", f.publishedValue(), now, now}}
+	case strings.Contains(query, "FOR UPDATE"):
+		return []string{"id"}, [][]driver.Value{{"tmpl-1"}}
+	case strings.Contains(query, "INSERT INTO notification_template_versions"):
+		// CreateVersion reads back the snapshot without its name and locale
+		f.versions = append(f.versions, f.draft)
+		columns, rows := f.versionRow(int64(len(f.versions)))
+		row := append(rows[0][:2:2], rows[0][4:]...)
+		return append(columns[:2:2], columns[4:]...), [][]driver.Value{row}
+	case strings.Contains(query, "v.version = t.published_version"):
+		if f.published == nil {
+			return nil, nil
+		}
+		return f.versionRow(*f.published)
+	case strings.Contains(query, "AND v.version = $3"):
+		return f.versionRow(args[2].(int64))
+	case strings.Contains(query, "FROM notification_templates"):
+		return []string{"id", "name", "locale", "description", "draft_subject", "draft_text", "draft_html",
+				"draft_required_vars", "published_version", "created_at", "updated_at"},
+			[][]driver.Value{{"tmpl-1", "receipt", "en", nil, f.draft[0], f.draft[1], f.draft[2],
+				f.draft[3], f.publishedValue(), now, now}}
+	}
+	return nil, nil
+}
+
+func (f *fakeTemplateStore) affected(query string, args []driver.Value) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.Contains(query, "published_version = $3") {
+		return 1
+	}
+	version := args[2].(int64)
+	if version < 1 || int(version) > len(f.versions) {
+		return 0
+	}
+	f.published = &version
+	return 1
+}
+
+var versionColumns = []string{"id", "template_id", "name", "locale", "version", "subject", "text_body",
+	"html_body", "required_vars", "created_at"}
+
+func (f *fakeTemplateStore) versionRow(version int64) ([]string, [][]driver.Value) {
+	if version < 1 || int(version) > len(f.versions) {
+		return nil, nil
+	}
+	v := f.versions[version-1]
+	return versionColumns, [][]driver.Value{{fmt.Sprintf("ver-%d", version), "tmpl-1", "receipt", "en",
+		version, v[0], v[1], v[2], v[3], time.Now()}}
+}
+
+func (f *fakeTemplateStore) publishedValue() driver.Value {
+	if f.published == nil {
+		return nil
+	}
+	return *f.published
+}
+
+// requiredVars renders a pq.Array argument in Postgres' text form
+func requiredVars(arg driver.Value) string {
+	if s, ok := arg.(string); ok {
+		return s
+	}
+	return string(arg.([]byte))
+}
+
+func newTemplateFixture(t *testing.T) (*TemplateService, *templates.Engine) {
+	t.Helper()
+
+	store := &fakeTemplateStore{}
+	fake, db := newFakeDB(t)
+	fake.affected = store.affected
+	fake.query = store.query
+
+	repo := repositories.NewTemplateRepository(db)
+	engine, err := templates.NewEngine(NewTemplateSource(repo))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return NewTemplateService(repo, engine, nil, nil), engine
+}
+
+func createTemplate(t *testing.T, s *TemplateService, subject string) {
+	t.Helper()
+	_, err := s.Create(context.Background(), dto.CreateTemplateRequest{
+		Name:            "receipt",
+		TemplateContent: dto.TemplateContent{Subject: subject, TextBody: "Paid {{.amount}}", RequiredVars: []string{"amount"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
+
+func updateDraft(t *testing.T, s *TemplateService, subject string) {
+	t.Helper()
+	_, err := s.UpdateDraft(context.Background(), "receipt", "en", dto.UpdateTemplateDraftRequest{
+		TemplateContent: dto.TemplateContent{Subject: subject, TextBody: "Paid {{.amount}}", RequiredVars: []string{"amount"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateDraft: %v", err)
+	}
+}
+
+func createVersion(t *testing.T, s *TemplateService) int {
+	t.Helper()
+	v, err := s.CreateVersion(context.Background(), "receipt", "en")
+	if err != nil {
+		t.Fatalf("CreateVersion: %v", err)
+	}
+	return v.Version
+}
+
+func TestTemplateVersionsAreImmutable(t *testing.T) {
+	s, _ := newTemplateFixture(t)
+	ctx := context.Background()
+
+	createTemplate(t, s, "Receipt one")
+	if v := createVersion(t, s); v != 1 {
+		t.Fatalf("first version = %d, want 1", v)
+	}
+	updateDraft(t, s, "Receipt two")
+
+	v1, err := s.GetVersion(ctx, "receipt", "en", 1)
+	if err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if v1.Subject != "Receipt one" {
+		t.Errorf("version 1 subject = %q after a draft edit, want %q", v1.Subject, "Receipt one")
+	}
+
+	if v := createVersion(t, s); v != 2 {
+		t.Fatalf("second version = %d, want 2", v)
+	}
+	v2, err := s.GetVersion(ctx, "receipt", "en", 2)
+	if err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if v2.Subject != "Receipt two" {
+		t.Errorf("version 2 subject = %q, want %q", v2.Subject, "Receipt two")
+	}
+}
+
+func TestCreateVersionRejectsBrokenDraft(t *testing.T) {
+	s, _ := newTemplateFixture(t)
+	createTemplate(t, s, "Receipt {{.order")
+
+	_, err := s.CreateVersion(context.Background(), "receipt", "en")
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("err = %v, want a ValidationError", err)
+	}
+}
+
+func TestPublishSwitchesResolvedVersion(t *testing.T) {
+	s, engine := newTemplateFixture(t)
+	ctx := context.Background()
+	data := map[string]interface{}{"amount": 5}
+
+	createTemplate(t, s, "Receipt one")
+	createVersion(t, s)
+	updateDraft(t, s, "Receipt two")
+	createVersion(t, s)
+
+	subject := func(version *int) string {
+		t.Helper()
+		out, err := engine.Render(ctx, "receipt", "en", version, data)
+		if err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+		return out.Subject
+	}
+
+	if _, err := engine.Render(ctx, "receipt", "en", nil, data); !errors.Is(err, templates.ErrTemplateNotFound) {
+		t.Fatalf("unpublished: err = %v, want ErrTemplateNotFound", err)
+	}
+
+	for _, tt := range []struct {
+		publish int
+		want    string
+	}{{1, "Receipt one"}, {2, "Receipt two"}, {1, "Receipt one"}} {
+		resp, err := s.Publish(ctx, "receipt", "en", dto.PublishTemplateRequest{Version: tt.publish})
+		if err != nil {
+			t.Fatalf("Publish %d: %v", tt.publish, err)
+		}
+		if resp.PublishedVersion == nil || *resp.PublishedVersion != tt.publish {
+			t.Errorf("published_version = %v, want %d", resp.PublishedVersion, tt.publish)
+		}
+		if got := subject(nil); got != tt.want {
+			t.Errorf("after publishing %d: subject = %q, want %q", tt.publish, got, tt.want)
+		}
+	}
+
+	if _, err := s.Publish(ctx, "receipt", "en", dto.PublishTemplateRequest{Version: 3}); !errors.Is(err, repositories.ErrTemplateVersionNotFound) {
+		t.Errorf("publishing a missing version: err = %v, want ErrTemplateVersionNotFound", err)
+	}
+}
+
+func TestPinnedVersionWinsOverPublished(t *testing.T) {
+	s, engine := newTemplateFixture(t)
+	ctx := context.Background()
+
+	createTemplate(t, s, "Receipt one")
+	createVersion(t, s)
+	updateDraft(t, s, "Receipt two")
+	createVersion(t, s)
+	if _, err := s.Publish(ctx, "receipt", "en", dto.PublishTemplateRequest{Version: 2}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	pinned := 1
+	out, err := engine.Render(ctx, "receipt", "en", &pinned, map[string]interface{}{"amount": 5})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if out.Subject != "Receipt one" || out.Version != 1 {
+		t.Errorf("rendered %q from version %d, want %q from version 1", out.Subject, out.Version, "Receipt one")
+	}
+
+	missing := 7
+	if _, err := engine.Render(ctx, "receipt", "en", &missing, nil); !errors.Is(err, templates.ErrTemplateNotFound) {
+		t.Errorf("missing pinned version: err = %v, want ErrTemplateNotFound", err)
+	}
+}
+
+// newTestSendFixture wires a template service whose test sends are queued
+// through a notification repository on the same fake database
+func newTestSendFixture(t *testing.T, allowlist ...string) (*TemplateService, *fakeDB) {
+	t.Helper()
+
+	store := &fakeTemplateStore{}
+	fake, db := newFakeDB(t)
+	fake.affected = store.affected
+	fake.query = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "INSERT INTO notifications") {
+			return []string{"id", "created_at"}, [][]driver.Value{{"notif-1", time.Now()}}
+		}
+		return store.query(query, args)
+	}
+
+	repo := repositories.NewTemplateRepository(db)
+	engine, err := templates.NewEngine(NewTemplateSource(repo))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	notifService := NewNotificationServiceV2(repositories.NewNotificationRepositoryWithDB(db),
+		nil, nil, nil, nil, nil, nil, nil, RetryPolicies{}, engine, nil, nil)
+
+	s := NewTemplateService(repo, engine, notifService, allowlist)
+	createTemplate(t, s, "Receipt one")
+	return s, fake
+}
+
+func TestTestSendAllowlist(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist []string
+		to        string
+		allowed   bool
+	}{
+		{"exact address", []string{"qa@kodrapay.com"}, "qa@kodrapay.com", true},
+		{"exact address ignores case", []string{"QA@kodrapay.com"}, "qa@KodraPay.com", true},
+		{"other address", []string{"qa@kodrapay.com"}, "ops@kodrapay.com", false},
+		{"domain suffix", []string{"@kodrapay.com"}, "ops@kodrapay.com", true},
+		{"lookalike domain", []string{"@kodrapay.com"}, "ops@evil-kodrapay.com", false},
+		{"domain entry is not an address", []string{"@kodrapay.com"}, "kodrapay.com", false},
+		{"phone number", []string{"+2348000000000"}, "+2348000000000", true},
+		{"empty allowlist", nil, "qa@kodrapay.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake := newTestSendFixture(t, tt.allowlist...)
+
+			resp, err := s.TestSend(context.Background(), "receipt", "en", dto.TestSendTemplateRequest{
+				PreviewTemplateRequest: dto.PreviewTemplateRequest{TemplateData: map[string]interface{}{"amount": 5}},
+				To:                     tt.to,
+			})
+			queued := fake.execsMatching("INSERT INTO notifications")
+
+			if !tt.allowed {
+				if !errors.Is(err, ErrRecipientNotAllowed) {
+					t.Errorf("err = %v, want ErrRecipientNotAllowed", err)
+				}
+				if len(queued) != 0 {
+					t.Errorf("refused test send queued %d notifications", len(queued))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestSend: %v", err)
+			}
+			if resp.ID != "notif-1" || len(queued) != 1 {
+				t.Fatalf("queued %d notifications, response ID %q", len(queued), resp.ID)
+			}
+		})
+	}
+}
+
+func TestTestSendIsMarkedAsTest(t *testing.T) {
+	s, fake := newTestSendFixture(t, "qa@kodrapay.com")
+
+	_, err := s.TestSend(context.Background(), "receipt", "en", dto.TestSendTemplateRequest{
+		PreviewTemplateRequest: dto.PreviewTemplateRequest{TemplateData: map[string]interface{}{"amount": 5}},
+		To:                     "qa@kodrapay.com",
+	})
+	if err != nil {
+		t.Fatalf("TestSend: %v", err)
+	}
+
+	queued := fake.execsMatching("INSERT INTO notifications")
+	if len(queued) != 1 {
+		t.Fatalf("queued %d notifications, want 1", len(queued))
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(queued[0].args[13].([]byte), &metadata); err != nil {
+		t.Fatalf("metadata: %v", err)
+	}
+	if metadata["test"] != true {
+		t.Errorf("metadata = %v, want test=true", metadata)
+	}
+}
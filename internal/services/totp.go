@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/dto"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+// Authenticator apps widely support only the RFC 6238 defaults
+const (
+	totpDigits     = 6
+	totpPeriod     = 30 * time.Second
+	totpSecretSize = 20 // 160 bits, as recommended by RFC 4226
+
+	recoveryCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+	recoveryCodeLength   = 10
+)
+
+var totpSecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPService manages authenticator app enrollments and checks their codes.
+// Verification for 2FA and login goes through OTPService with the totp
+// delivery method.
+type TOTPService struct {
+	repo    *repositories.TOTPRepository
+	box     *TOTPSecretBox
+	hasher  *OTPHasher
+	limiter *OTPLimiter
+	cfg     config.TOTPConfig
+}
+
+func NewTOTPService(
+	repo *repositories.TOTPRepository,
+	box *TOTPSecretBox,
+	hasher *OTPHasher,
+	limiter *OTPLimiter,
+	cfg config.TOTPConfig,
+) *TOTPService {
+	return &TOTPService{
+		repo:    repo,
+		box:     box,
+		hasher:  hasher,
+		limiter: limiter,
+		cfg:     cfg,
+	}
+}
+
+// Enroll generates a new secret for the user, replacing any unconfirmed enrollment.
+// The enrollment must be confirmed with a code from the app before it is used.
+func (s *TOTPService) Enroll(ctx context.Context, req *models.EnrollTOTPRequest) (dto.TOTPEnrollmentResponse, error) {
+	if req.MerchantID == "" {
+		return dto.TOTPEnrollmentResponse{}, validationErrorf("merchant_id is required")
+	}
+	if req.UserID == "" {
+		return dto.TOTPEnrollmentResponse{}, validationErrorf("user_id is required")
+	}
+
+	accountName := req.UserID
+	if req.AccountName != nil && strings.TrimSpace(*req.AccountName) != "" {
+		accountName = strings.TrimSpace(*req.AccountName)
+	}
+
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return dto.TOTPEnrollmentResponse{}, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+
+	sealed, keyID, err := s.box.Seal(req.MerchantID, req.UserID, secret)
+	if err != nil {
+		return dto.TOTPEnrollmentResponse{}, err
+	}
+
+	enrollment := &models.TOTPEnrollment{
+		MerchantID:  req.MerchantID,
+		UserID:      req.UserID,
+		AccountName: accountName,
+		Secret:      sealed,
+		KeyID:       keyID,
+	}
+	if err := s.repo.UpsertPending(ctx, enrollment); err != nil {
+		return dto.TOTPEnrollmentResponse{}, err
+	}
+
+	encoded := totpSecretEncoding.EncodeToString(secret)
+	uri := s.otpauthURI(accountName, encoded)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return dto.TOTPEnrollmentResponse{}, fmt.Errorf("failed to render TOTP QR code: %w", err)
+	}
+
+	return dto.TOTPEnrollmentResponse{
+		EnrollmentID: enrollment.ID,
+		Secret:       encoded,
+		OTPAuthURI:   uri,
+		QRCodePNG:    base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// Confirm activates a pending enrollment once the user proves the app produces
+// valid codes, and issues the enrollment's recovery codes
+func (s *TOTPService) Confirm(ctx context.Context, req *models.TOTPCodeRequest) (dto.TOTPRecoveryCodesResponse, error) {
+	if err := validateTOTPCodeRequest(req); err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	enrollment, err := s.repo.GetByUser(ctx, req.MerchantID, req.UserID)
+	if err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+	if enrollment.IsConfirmed() {
+		return dto.TOTPRecoveryCodesResponse{}, models.ErrTOTPAlreadyEnrolled
+	}
+
+	if err := s.checkWithLockout(ctx, enrollment, req.Code); err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	codes, digests, err := s.newRecoveryCodes(enrollment.ID)
+	if err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	confirmedAt, err := s.repo.Confirm(ctx, enrollment.ID, digests)
+	if err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	return dto.TOTPRecoveryCodesResponse{
+		EnrollmentID:  enrollment.ID,
+		ConfirmedAt:   confirmedAt.Format(time.RFC3339),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// RegenerateRecoveryCodes replaces a confirmed enrollment's recovery codes. It
+// requires a current code from the app, not a recovery code.
+func (s *TOTPService) RegenerateRecoveryCodes(ctx context.Context, req *models.TOTPCodeRequest) (dto.TOTPRecoveryCodesResponse, error) {
+	if err := validateTOTPCodeRequest(req); err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	enrollment, err := s.confirmedEnrollment(ctx, req.MerchantID, req.UserID)
+	if err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	if err := s.checkWithLockout(ctx, enrollment, req.Code); err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	codes, digests, err := s.newRecoveryCodes(enrollment.ID)
+	if err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, enrollment.ID, digests); err != nil {
+		return dto.TOTPRecoveryCodesResponse{}, err
+	}
+
+	return dto.TOTPRecoveryCodesResponse{
+		EnrollmentID:  enrollment.ID,
+		ConfirmedAt:   enrollment.ConfirmedAt.Format(time.RFC3339),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// CleanupPending removes enrollments that were never confirmed within retention
+func (s *TOTPService) CleanupPending(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.DeletePendingBefore(ctx, time.Now().Add(-retention))
+}
+
+// CheckEnrolled returns ErrTOTPNotEnrolled unless the user has a confirmed enrollment
+func (s *TOTPService) CheckEnrolled(ctx context.Context, merchantID, userID string) error {
+	_, err := s.confirmedEnrollment(ctx, merchantID, userID)
+	return err
+}
+
+// Check verifies a code from the user's authenticator app or one of their unused
+// recovery codes, consuming it so it cannot be replayed. It returns
+// ErrOTPInvalidCode or ErrTOTPCodeReused on mismatch.
+func (s *TOTPService) Check(ctx context.Context, merchantID, userID, code string) error {
+	enrollment, err := s.confirmedEnrollment(ctx, merchantID, userID)
+	if err != nil {
+		return err
+	}
+
+	if isTOTPCode(code) {
+		return s.checkTOTP(ctx, enrollment, code)
+	}
+
+	digests := s.hasher.RecoveryCodeDigests(enrollment.ID, normalizeRecoveryCode(code))
+	used, err := s.repo.ConsumeRecoveryCode(ctx, enrollment.ID, digests)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return models.ErrOTPInvalidCode
+	}
+
+	log.Printf("Recovery code used for TOTP enrollment %s", enrollment.ID)
+	return nil
+}
+
+func (s *TOTPService) confirmedEnrollment(ctx context.Context, merchantID, userID string) (*models.TOTPEnrollment, error) {
+	enrollment, err := s.repo.GetByUser(ctx, merchantID, userID)
+	if errors.Is(err, repositories.ErrTOTPEnrollmentNotFound) {
+		return nil, models.ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !enrollment.IsConfirmed() {
+		return nil, models.ErrTOTPNotEnrolled
+	}
+	return enrollment, nil
+}
+
+// checkWithLockout checks an app code for the enrollment endpoints, counting
+// failures towards the same lockout as OTP verification
+func (s *TOTPService) checkWithLockout(ctx context.Context, enrollment *models.TOTPEnrollment, code string) error {
+	if err := s.limiter.CheckLockout(ctx, enrollment.MerchantID, enrollment.UserID); err != nil {
+		return err
+	}
+
+	err := s.checkTOTP(ctx, enrollment, code)
+	if errors.Is(err, models.ErrOTPInvalidCode) || errors.Is(err, models.ErrTOTPCodeReused) {
+		if lockErr := s.limiter.RecordFailure(ctx, enrollment.MerchantID, enrollment.UserID); lockErr != nil {
+			return lockErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.limiter.Reset(ctx, enrollment.MerchantID, enrollment.UserID); err != nil {
+		log.Printf("Failed to reset OTP verification failures: %v", err)
+	}
+	return nil
+}
+
+// checkTOTP accepts a code for any time step within the configured skew of now,
+// provided no code for that or a later step has been accepted before
+func (s *TOTPService) checkTOTP(ctx context.Context, enrollment *models.TOTPEnrollment, code string) error {
+	if !isTOTPCode(code) {
+		return models.ErrOTPInvalidCode
+	}
+
+	secret, err := s.box.Open(enrollment.MerchantID, enrollment.UserID, enrollment.Secret, enrollment.KeyID)
+	if err != nil {
+		return err
+	}
+
+	step, ok := matchTOTPStep(secret, normalizeTOTPCode(code), time.Now(), s.cfg.Skew)
+	if !ok {
+		return models.ErrOTPInvalidCode
+	}
+
+	used, err := s.repo.UseStep(ctx, enrollment.ID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return models.ErrTOTPCodeReused
+	}
+	return nil
+}
+
+func (s *TOTPService) otpauthURI(accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", s.cfg.Issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(int(totpPeriod.Seconds())))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + s.cfg.Issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// newRecoveryCodes returns fresh recovery codes formatted for display, with
+// their digests for storage
+func (s *TOTPService) newRecoveryCodes(enrollmentID string) ([]string, []string, error) {
+	count := s.cfg.RecoveryCodes
+	if count <= 0 {
+		count = 10
+	}
+
+	codes := make([]string, count)
+	digests := make([]string, count)
+	for i := range codes {
+		var b strings.Builder
+		for j := 0; j < recoveryCodeLength; j++ {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+			}
+			b.WriteByte(recoveryCodeAlphabet[n.Int64()])
+		}
+		code := b.String()
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		digests[i] = s.hasher.HashRecoveryCode(enrollmentID, code)
+	}
+	return codes, digests, nil
+}
+
+// totpCode computes the RFC 6238 code for a time step (HOTP with SHA-1)
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTPStep returns the time step within skew steps of now whose code matches
+func matchTOTPStep(secret []byte, code string, now time.Time, skew int) (int64, bool) {
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for offset := -skew; offset <= skew; offset++ {
+		step := current + int64(offset)
+		if hmac.Equal([]byte(totpCode(secret, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// isTOTPCode reports whether code looks like an app code rather than a recovery code
+func isTOTPCode(code string) bool {
+	code = normalizeTOTPCode(code)
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeTOTPCode drops the spaces apps show between digit groups
+func normalizeTOTPCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+// normalizeRecoveryCode drops separators and case so codes match however they are typed
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func validateTOTPCodeRequest(req *models.TOTPCodeRequest) error {
+	if req.MerchantID == "" {
+		return validationErrorf("merchant_id is required")
+	}
+	if req.UserID == "" {
+		return validationErrorf("user_id is required")
+	}
+	if req.Code == "" {
+		return validationErrorf("code is required")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+)
+
+// TOTPSecretBox encrypts TOTP shared secrets at rest with AES-256-GCM. The
+// ciphertext is bound to the enrollment's merchant and user, and several keys may
+// be configured so they can be rotated without re-enrolling users.
+type TOTPSecretBox struct {
+	keys     map[string]cipher.AEAD
+	activeID string
+}
+
+func NewTOTPSecretBox(cfg config.TOTPConfig) (*TOTPSecretBox, error) {
+	if len(cfg.EncryptionKeys) == 0 {
+		return nil, fmt.Errorf("at least one TOTP encryption key is required (TOTP_ENCRYPTION_KEYS)")
+	}
+
+	activeID := cfg.ActiveKeyID
+	if activeID == "" && len(cfg.EncryptionKeys) == 1 {
+		for id := range cfg.EncryptionKeys {
+			activeID = id
+		}
+	}
+	if _, ok := cfg.EncryptionKeys[activeID]; !ok {
+		return nil, fmt.Errorf("active TOTP encryption key %q is not configured", activeID)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(cfg.EncryptionKeys))
+	for id, encoded := range cfg.EncryptionKeys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("TOTP encryption key %q must be 32 bytes, base64-encoded", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOTP encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOTP encryption key %q: %w", id, err)
+		}
+		keys[id] = aead
+	}
+
+	return &TOTPSecretBox{keys: keys, activeID: activeID}, nil
+}
+
+// Seal encrypts secret under the active key, returning the nonce-prefixed
+// ciphertext and the key's ID
+func (b *TOTPSecretBox) Seal(merchantID, userID string, secret []byte) (ciphertext []byte, keyID string, err error) {
+	aead := b.keys[b.activeID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, secret, secretAAD(merchantID, userID)), b.activeID, nil
+}
+
+// Open decrypts a secret sealed for the same merchant and user
+func (b *TOTPSecretBox) Open(merchantID, userID string, ciphertext []byte, keyID string) ([]byte, error) {
+	aead, ok := b.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("TOTP encryption key %q is not configured", keyID)
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("TOTP secret ciphertext is truncated")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, sealed, secretAAD(merchantID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	return secret, nil
+}
+
+func secretAAD(merchantID, userID string) []byte {
+	return []byte(merchantID + "\x00" + userID)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+	"github.com/kodra-pay/notification-service/internal/repositories"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 appendix B test vectors
+var rfc6238Secret = []byte("12345678901234567890")
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; a 6-digit code is their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		step := tt.unix / int64(totpPeriod.Seconds())
+		if got := totpCode(rfc6238Secret, step); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTPStepSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / int64(totpPeriod.Seconds())
+
+	tests := []struct {
+		name   string
+		offset int64
+		skew   int
+		want   bool
+	}{
+		{name: "current step", offset: 0, skew: 0, want: true},
+		{name: "previous step within skew", offset: -1, skew: 1, want: true},
+		{name: "next step within skew", offset: 1, skew: 1, want: true},
+		{name: "previous step without skew", offset: -1, skew: 0},
+		{name: "two steps behind", offset: -2, skew: 1},
+		{name: "two steps ahead", offset: 2, skew: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := totpCode(rfc6238Secret, current+tt.offset)
+			step, ok := matchTOTPStep(rfc6238Secret, code, now, tt.skew)
+			if ok != tt.want {
+				t.Fatalf("matched = %v, want %v", ok, tt.want)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestMatchTOTPStepRejectsWrongCode(t *testing.T) {
+	if _, ok := matchTOTPStep(rfc6238Secret, "000000", time.Unix(59, 0), 1); ok {
+		t.Error("matched a wrong code")
+	}
+}
+
+// fakeTOTPStore answers the enrollment queries TOTPService.Check makes and
+// applies the last_used_step guard the way Postgres would
+type fakeTOTPStore struct {
+	mu           sync.Mutex
+	secret       []byte
+	lastUsedStep int64
+}
+
+func (f *fakeTOTPStore) query(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.Contains(query, "FROM totp_enrollments") {
+		return nil, nil
+	}
+	now := time.Now()
+	return []string{"id", "merchant_id", "user_id", "account_name", "secret", "key_id",
+			"last_used_step", "confirmed_at", "created_at", "updated_at"},
+		[][]driver.Value{{"enrollment-1", "merchant-1", "user-1", "ada@example.com", f.secret, "k1",
+			f.lastUsedStep, now, now, now}}
+}
+
+func (f *fakeTOTPStore) affected(query string, args []driver.Value) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.Contains(query, "last_used_step < $2") {
+		return 1
+	}
+	if step := args[1].(int64); f.lastUsedStep < step {
+		f.lastUsedStep = step
+		return 1
+	}
+	return 0
+}
+
+func TestTOTPCheckRejectsReplay(t *testing.T) {
+	box, err := NewTOTPSecretBox(config.TOTPConfig{
+		EncryptionKeys: map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 32))},
+	})
+	if err != nil {
+		t.Fatalf("NewTOTPSecretBox: %v", err)
+	}
+	sealed, _, err := box.Seal("merchant-1", "user-1", rfc6238Secret)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	store := &fakeTOTPStore{secret: sealed}
+	fake, db := newFakeDB(t)
+	fake.query = store.query
+	fake.affected = store.affected
+	s := NewTOTPService(repositories.NewTOTPRepository(db), box, nil, nil, config.TOTPConfig{Skew: 1})
+
+	current := time.Now().Unix() / int64(totpPeriod.Seconds())
+	previous := totpCode(rfc6238Secret, current-1)
+	code := totpCode(rfc6238Secret, current)
+	ctx := context.Background()
+
+	if err := s.Check(ctx, "merchant-1", "user-1", code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := s.Check(ctx, "merchant-1", "user-1", code); !errors.Is(err, models.ErrTOTPCodeReused) {
+		t.Errorf("replay: err = %v, want ErrTOTPCodeReused", err)
+	}
+	// A code for an earlier step is refused once a later one has been accepted
+	if previous != code {
+		if err := s.Check(ctx, "merchant-1", "user-1", previous); !errors.Is(err, models.ErrTOTPCodeReused) {
+			t.Errorf("earlier step: err = %v, want ErrTOTPCodeReused", err)
+		}
+	}
+	if store.lastUsedStep != current {
+		t.Errorf("last_used_step = %d, want %d", store.lastUsedStep, current)
+	}
+}
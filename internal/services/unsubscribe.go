@@ -0,0 +1,145 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+// ErrInvalidUnsubscribeToken is returned for unsubscribe tokens that are malformed,
+// signed with an unknown key or tampered with
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+
+// unsubscribeScope keeps unsubscribe signatures apart from any other use of the keys
+const unsubscribeScope = "unsubscribe"
+
+// UnsubscribeClaims name the preference cell an unsubscribe link turns off, and
+// whose: the merchant's, or one of their users' when UserID is set
+type UnsubscribeClaims struct {
+	MerchantID string                     `json:"m"`
+	UserID     string                     `json:"u,omitempty"`
+	Category   models.NotificationChannel `json:"c"`
+	Type       models.NotificationType    `json:"t"`
+}
+
+// UnsubscribeTokens signs and checks one-click unsubscribe links. Tokens are
+// stateless: the payload names the preference cell and an HMAC under one of the
+// configured keys authenticates it, so recipients need no session to opt out.
+// They do not expire; retiring a key revokes the links it signed.
+type UnsubscribeTokens struct {
+	baseURL  string
+	keys     map[string][]byte
+	activeID string
+}
+
+// NewUnsubscribeTokens builds the link signer. Without a base URL links are
+// disabled and no keys are needed.
+func NewUnsubscribeTokens(cfg config.UnsubscribeConfig) (*UnsubscribeTokens, error) {
+	if cfg.BaseURL == "" {
+		return &UnsubscribeTokens{}, nil
+	}
+	if len(cfg.SigningKeys) == 0 {
+		return nil, fmt.Errorf("at least one unsubscribe signing key is required (UNSUBSCRIBE_SIGNING_KEYS)")
+	}
+
+	activeID := cfg.ActiveKeyID
+	if activeID == "" && len(cfg.SigningKeys) == 1 {
+		for id := range cfg.SigningKeys {
+			activeID = id
+		}
+	}
+	if _, ok := cfg.SigningKeys[activeID]; !ok {
+		return nil, fmt.Errorf("active unsubscribe signing key %q is not configured", activeID)
+	}
+
+	keys := make(map[string][]byte, len(cfg.SigningKeys))
+	for id, secret := range cfg.SigningKeys {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("unsubscribe signing key %q must be at least 32 bytes", id)
+		}
+		keys[id] = []byte(secret)
+	}
+
+	return &UnsubscribeTokens{baseURL: cfg.BaseURL, keys: keys, activeID: activeID}, nil
+}
+
+// Enabled reports whether unsubscribe links are configured
+func (u *UnsubscribeTokens) Enabled() bool {
+	return u.baseURL != ""
+}
+
+// URL returns the unsubscribe link for claims
+func (u *UnsubscribeTokens) URL(claims UnsubscribeClaims) string {
+	return u.baseURL + "?token=" + url.QueryEscape(u.Sign(claims))
+}
+
+// Sign returns a token for claims: the JSON payload, base64url-encoded so no ID
+// can spill into another field, the signing key's ID and an HMAC over both
+func (u *UnsubscribeTokens) Sign(claims UnsubscribeClaims) string {
+	// Marshalling a struct of strings cannot fail
+	raw, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + u.activeID + "." + u.signature(u.keys[u.activeID], payload)
+}
+
+// Parse checks a token and returns its claims
+func (u *UnsubscribeTokens) Parse(token string) (*UnsubscribeClaims, error) {
+	payload, rest, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	sep := strings.LastIndex(rest, ".")
+	if sep <= 0 {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	key, ok := u.keys[rest[:sep]]
+	if !ok || !hmac.Equal([]byte(u.signature(key, payload)), []byte(rest[sep+1:])) {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	if !strings.HasPrefix(string(raw), "{") {
+		return parseLegacyUnsubscribePayload(raw)
+	}
+
+	var claims UnsubscribeClaims
+	if err := json.Unmarshal(raw, &claims); err != nil || claims.MerchantID == "" {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	return &claims, nil
+}
+
+// parseLegacyUnsubscribePayload reads the NUL-separated payload of links sent
+// before payloads were JSON, so opt-outs already in inboxes keep working
+func parseLegacyUnsubscribePayload(raw []byte) (*UnsubscribeClaims, error) {
+	parts := strings.Split(string(raw), "\x00")
+	if len(parts) != 4 || parts[0] == "" {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	return &UnsubscribeClaims{
+		MerchantID: parts[0],
+		UserID:     parts[1],
+		Category:   models.NotificationChannel(parts[2]),
+		Type:       models.NotificationType(parts[3]),
+	}, nil
+}
+
+func (u *UnsubscribeTokens) signature(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsubscribeScope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
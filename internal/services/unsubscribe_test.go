@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kodra-pay/notification-service/internal/config"
+	"github.com/kodra-pay/notification-service/internal/models"
+)
+
+const (
+	unsubscribeKey1 = "k1-secret-0123456789abcdefghijklmnop"
+	unsubscribeKey2 = "k2-secret-0123456789abcdefghijklmnop"
+)
+
+func newTestUnsubscribeTokens(t *testing.T, activeID string, keys map[string]string) *UnsubscribeTokens {
+	t.Helper()
+	u, err := NewUnsubscribeTokens(config.UnsubscribeConfig{
+		BaseURL:     "https://notifications.example.com/unsubscribe",
+		SigningKeys: keys,
+		ActiveKeyID: activeID,
+	})
+	if err != nil {
+		t.Fatalf("NewUnsubscribeTokens: %v", err)
+	}
+	return u
+}
+
+func TestUnsubscribeTokenRoundTrip(t *testing.T) {
+	u := newTestUnsubscribeTokens(t, "k1", map[string]string{"k1": unsubscribeKey1})
+
+	tests := []struct {
+		name   string
+		claims UnsubscribeClaims
+	}{
+		{"merchant", UnsubscribeClaims{MerchantID: "merchant-1", Category: models.ChannelMarketing, Type: models.TypeEmail}},
+		{"user", UnsubscribeClaims{MerchantID: "merchant-1", UserID: "user-1", Category: models.ChannelMarketing, Type: models.TypeEmail}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.Parse(u.Sign(tt.claims))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if *got != tt.claims {
+				t.Errorf("claims = %+v, want %+v", *got, tt.claims)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeTokenKeyIDs(t *testing.T) {
+	claims := UnsubscribeClaims{MerchantID: "merchant-1", Category: models.ChannelMarketing, Type: models.TypeEmail}
+	rotated := newTestUnsubscribeTokens(t, "k2", map[string]string{"k1": unsubscribeKey1, "k2": unsubscribeKey2})
+	oldToken := newTestUnsubscribeTokens(t, "k1", map[string]string{"k1": unsubscribeKey1}).Sign(claims)
+
+	if _, err := rotated.Parse(oldToken); err != nil {
+		t.Errorf("token signed with retired k1: %v", err)
+	}
+
+	// Naming another configured key does not make the signature check out
+	payload, _, _ := strings.Cut(oldToken, ".")
+	swapped := payload + ".k2." + oldToken[strings.LastIndex(oldToken, ".")+1:]
+	if _, err := rotated.Parse(swapped); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+		t.Errorf("swapped key ID: err = %v, want ErrInvalidUnsubscribeToken", err)
+	}
+
+	// Dropping a key revokes the links it signed
+	revoked := newTestUnsubscribeTokens(t, "k2", map[string]string{"k2": unsubscribeKey2})
+	if _, err := revoked.Parse(oldToken); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+		t.Errorf("unknown key ID: err = %v, want ErrInvalidUnsubscribeToken", err)
+	}
+}
+
+func TestUnsubscribeTokenRejectsTampering(t *testing.T) {
+	u := newTestUnsubscribeTokens(t, "k1", map[string]string{"k1": unsubscribeKey1})
+	token := u.Sign(UnsubscribeClaims{MerchantID: "merchant-1", Category: models.ChannelMarketing, Type: models.TypeEmail})
+	_, rest, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"m":"merchant-2","c":"marketing","t":"email"}`))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"replaced payload", forged + "." + rest},
+		{"truncated signature", token[:len(token)-2]},
+		{"missing key ID", strings.Replace(token, ".k1.", "..", 1)},
+		{"no separators", "garbage"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := u.Parse(tt.token); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+				t.Errorf("err = %v, want ErrInvalidUnsubscribeToken", err)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeTokenNULInIDs(t *testing.T) {
+	u := newTestUnsubscribeTokens(t, "k1", map[string]string{"k1": unsubscribeKey1})
+
+	// With NUL-separated payloads these two claims encoded the same cells
+	injected := UnsubscribeClaims{MerchantID: "merchant-1\x00user-2", Category: models.ChannelMarketing, Type: models.TypeEmail}
+	target := UnsubscribeClaims{MerchantID: "merchant-1", UserID: "user-2", Category: models.ChannelMarketing, Type: models.TypeEmail}
+
+	got, err := u.Parse(u.Sign(injected))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if *got != injected {
+		t.Errorf("claims = %+v, want %+v", *got, injected)
+	}
+	if *got == target {
+		t.Error("an ID containing NUL aliased another merchant's user")
+	}
+}
+
+func TestUnsubscribeTokenLegacyPayload(t *testing.T) {
+	u := newTestUnsubscribeTokens(t, "k1", map[string]string{"k1": unsubscribeKey1})
+	payload := base64.RawURLEncoding.EncodeToString([]byte("merchant-1\x00user-1\x00marketing\x00email"))
+	token := payload + ".k1." + u.signature(u.keys["k1"], payload)
+
+	got, err := u.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := UnsubscribeClaims{MerchantID: "merchant-1", UserID: "user-1", Category: models.ChannelMarketing, Type: models.TypeEmail}
+	if *got != want {
+		t.Errorf("claims = %+v, want %+v", *got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package templates
+
+import "context"
+
+// Names of the built-in templates used by the service's own notification builders
+const (
+	TransactionNotification = "transaction_notification"
+	PayoutNotification      = "payout_notification"
+	OTPCodePrefix           = "otp_"
+	OTPCodeDefault          = "otp_default"
+	// OTPVoice is the script read out in OTP voice calls
+	OTPVoice = "otp_voice"
+)
+
+// OTPTemplateName returns the built-in template name for an OTP purpose
+func OTPTemplateName(purpose string) string {
+	name := OTPCodePrefix + purpose
+	if _, ok := builtinTemplates[name]; ok {
+		return name
+	}
+	return OTPCodeDefault
+}
+
+// builtinTemplates holds the built-in templates by name and locale
+var builtinTemplates = map[string]map[string]*Template{
+	TransactionNotification: {
+		"en": {
+			Subject:  "Transaction Notification",
+			Text:     "Transaction of {{money .amount .currency}} has been {{.status}}",
+			HTML:     `<p>Transaction of <strong>{{money .amount .currency}}</strong> has been {{.status}}.</p>`,
+			Required: []string{"amount", "currency", "status"},
+		},
+		"fr": {
+			Subject:  "Notification de transaction",
+			Text:     "La transaction de {{money .amount .currency}} a le statut : {{.status}}",
+			HTML:     `<p>La transaction de <strong>{{money .amount .currency}}</strong> a le statut : {{.status}}.</p>`,
+			Required: []string{"amount", "currency", "status"},
+		},
+	},
+	PayoutNotification: {
+		"en": {
+			Subject:  "Payout Notification",
+			Text:     "Payout of {{money .amount .currency}} has been {{.status}}",
+			HTML:     `<p>Payout of <strong>{{money .amount .currency}}</strong> has been {{.status}}.</p>`,
+			Required: []string{"amount", "currency", "status"},
+		},
+		"fr": {
+			Subject:  "Notification de versement",
+			Text:     "Le versement de {{money .amount .currency}} a le statut : {{.status}}",
+			HTML:     `<p>Le versement de <strong>{{money .amount .currency}}</strong> a le statut : {{.status}}.</p>`,
+			Required: []string{"amount", "currency", "status"},
+		},
+	},
+	OTPCodePrefix + "payout": otpTemplates(
+		"Your KodraPay payout verification code is: {{.code}}. Valid for {{.expiry_minutes}} minutes. Do not share this code with anyone.",
+		"Votre code de vérification de versement KodraPay est : {{.code}}. Valable {{.expiry_minutes}} minutes. Ne partagez ce code avec personne.",
+	),
+	OTPCodePrefix + "withdrawal": otpTemplates(
+		"Your KodraPay withdrawal verification code is: {{.code}}. Valid for {{.expiry_minutes}} minutes. Do not share this code with anyone.",
+		"Votre code de vérification de retrait KodraPay est : {{.code}}. Valable {{.expiry_minutes}} minutes. Ne partagez ce code avec personne.",
+	),
+	OTPCodePrefix + "settings_change": otpTemplates(
+		"Your KodraPay settings change verification code is: {{.code}}. Valid for {{.expiry_minutes}} minutes.",
+		"Votre code de vérification pour la modification des paramètres KodraPay est : {{.code}}. Valable {{.expiry_minutes}} minutes.",
+	),
+	OTPCodePrefix + "login": otpTemplates(
+		"Your KodraPay login verification code is: {{.code}}. Valid for {{.expiry_minutes}} minutes.",
+		"Votre code de connexion KodraPay est : {{.code}}. Valable {{.expiry_minutes}} minutes.",
+	),
+	OTPCodePrefix + "2fa": otpTemplates(
+		"Your KodraPay 2FA code is: {{.code}}. Valid for {{.expiry_minutes}} minutes.",
+		"Votre code d'authentification à deux facteurs KodraPay est : {{.code}}. Valable {{.expiry_minutes}} minutes.",
+	),
+	OTPVoice: {
+		"en": {
+			Text:     "Hello, this is KodraPay. Your verification code is: {{spell .code}}. Again, your code is: {{spell .code}}. It is valid for {{.expiry_minutes}} minutes. Do not share this code with anyone.",
+			Required: []string{"code", "expiry_minutes"},
+		},
+		"fr": {
+			Text:     "Bonjour, ici KodraPay. Votre code de vérification est : {{spell .code}}. Je répète, votre code est : {{spell .code}}. Il est valable {{.expiry_minutes}} minutes. Ne partagez ce code avec personne.",
+			Required: []string{"code", "expiry_minutes"},
+		},
+	},
+	OTPCodeDefault: otpTemplates(
+		"Your KodraPay verification code is: {{.code}}. Valid for {{.expiry_minutes}} minutes.",
+		"Votre code de vérification KodraPay est : {{.code}}. Valable {{.expiry_minutes}} minutes.",
+	),
+}
+
+func init() {
+	for name, variants := range builtinTemplates {
+		for locale, t := range variants {
+			t.Name = name
+			t.Locale = locale
+		}
+	}
+}
+
+func otpTemplates(en, fr string) map[string]*Template {
+	// link is always passed, empty unless a magic link was requested
+	required := []string{"code", "expiry_minutes"}
+	return map[string]*Template{
+		"en": {
+			Subject:  "KodraPay Verification Code",
+			Text:     en + "{{with .link}} Or verify with this link: {{.}}{{end}}",
+			Required: required,
+		},
+		"fr": {
+			Subject:  "Code de vérification KodraPay",
+			Text:     fr + "{{with .link}} Ou vérifiez avec ce lien : {{.}}{{end}}",
+			Required: required,
+		},
+	}
+}
+
+// builtinSource serves the templates compiled into the service
+type builtinSource struct{}
+
+func (builtinSource) Get(ctx context.Context, name, locale string, version *int) (*Template, error) {
+	// Built-in templates are unversioned
+	if version != nil {
+		return nil, ErrTemplateNotFound
+	}
+	if t, ok := builtinTemplates[name][locale]; ok {
+		return t, nil
+	}
+	return nil, ErrTemplateNotFound
+}
@@ -0,0 +1,141 @@
+package templates
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTemplateNotFound is returned when no source knows the requested template
+var ErrTemplateNotFound = errors.New("template not found")
+
+// Source resolves template definitions by name and exact locale. A nil version asks
+// for the latest published version; sources return ErrTemplateNotFound when they have none.
+type Source interface {
+	Get(ctx context.Context, name, locale string, version *int) (*Template, error)
+}
+
+// maxCachedTemplates bounds the compiled templates kept between renders
+const maxCachedTemplates = 256
+
+// Engine resolves named templates from its sources in order and renders them.
+// Resolved templates are compiled once and kept in a bounded LRU cache; drafts
+// and other ad-hoc definitions are compiled afresh each time.
+type Engine struct {
+	sources []Source
+	funcs   map[string]interface{}
+
+	mu sync.Mutex
+	// cache maps a template's cacheKey to its element in lru, most recent first
+	cache map[string]*list.Element
+	lru   *list.List
+}
+
+// NewEngine builds an engine that consults sources in order, falling back to the built-in templates
+func NewEngine(sources ...Source) (*Engine, error) {
+	e := &Engine{
+		sources: append(sources, builtinSource{}),
+		funcs:   baseFuncs(),
+		cache:   map[string]*list.Element{},
+		lru:     list.New(),
+	}
+
+	// Fail fast on a broken built-in template rather than at send time
+	for _, variants := range builtinTemplates {
+		for _, t := range variants {
+			if _, err := e.compile(t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// Resolve finds the template definition for name, walking the locale fallback
+// chain (e.g. fr-CI → fr → en) and pinning to version when it is not nil
+func (e *Engine) Resolve(ctx context.Context, name, locale string, version *int) (*Template, error) {
+	for _, l := range LocaleChain(locale) {
+		for _, src := range e.sources {
+			t, err := src.Get(ctx, name, l, version)
+			if errors.Is(err, ErrTemplateNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			return t, nil
+		}
+	}
+	if version != nil {
+		return nil, fmt.Errorf("%w: %s version %d", ErrTemplateNotFound, name, *version)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+// Render resolves the named template for the recipient's locale and renders its
+// subject and bodies from data, formatting numbers and dates for that locale
+func (e *Engine) Render(ctx context.Context, name, locale string, version *int, data map[string]interface{}) (*Rendered, error) {
+	t, err := e.Resolve(ctx, name, locale, version)
+	if err != nil {
+		return nil, err
+	}
+	c, err := e.compile(t)
+	if err != nil {
+		return nil, err
+	}
+	return c.render(data, renderLocale(locale))
+}
+
+// RenderTemplate renders a template definition, such as a draft being
+// previewed, from data for a locale. The definition is not cached.
+func (e *Engine) RenderTemplate(t *Template, locale string, data map[string]interface{}) (*Rendered, error) {
+	c, err := compile(t, e.funcs)
+	if err != nil {
+		return nil, err
+	}
+	return c.render(data, renderLocale(locale))
+}
+
+// Validate checks that a template definition parses
+func (e *Engine) Validate(t *Template) error {
+	_, err := compile(t, e.funcs)
+	return err
+}
+
+// compile returns the parsed forms of a resolved template, reusing them while
+// the template stays among the most recently rendered
+func (e *Engine) compile(t *Template) (*compiled, error) {
+	key := t.cacheKey()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if el, ok := e.cache[key]; ok {
+		e.lru.MoveToFront(el)
+		return el.Value.(*compiled), nil
+	}
+
+	c, err := compile(t, e.funcs)
+	if err != nil {
+		return nil, err
+	}
+	c.key = key
+	e.cache[key] = e.lru.PushFront(c)
+	if e.lru.Len() > maxCachedTemplates {
+		oldest := e.lru.Back()
+		e.lru.Remove(oldest)
+		delete(e.cache, oldest.Value.(*compiled).key)
+	}
+	return c, nil
+}
+
+// renderLocale is the locale output is formatted for, the default when unset
+func renderLocale(locale string) string {
+	if locale = NormalizeLocale(locale); locale == "" {
+		return DefaultLocale
+	}
+	return locale
+}
@@ -0,0 +1,93 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// versionedSource serves a distinct template for every pinned version, as a
+// merchant creating many versions would
+type versionedSource struct{}
+
+func (versionedSource) Get(_ context.Context, name, locale string, version *int) (*Template, error) {
+	if name != "receipt" || locale != "en" || version == nil {
+		return nil, ErrTemplateNotFound
+	}
+	return &Template{
+		Name:    name,
+		Locale:  locale,
+		Version: *version,
+		Subject: fmt.Sprintf("Receipt v%d", *version),
+		Text:    "Thanks, {{.name}}",
+	}, nil
+}
+
+func (e *Engine) cachedTemplates() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.cache) != e.lru.Len() {
+		panic("engine cache and LRU list disagree")
+	}
+	return len(e.cache)
+}
+
+func TestEngineDoesNotCacheAdHocTemplates(t *testing.T) {
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	before := e.cachedTemplates()
+
+	for i := 0; i < 10; i++ {
+		draft := &Template{Name: "draft", Subject: fmt.Sprintf("Draft %d", i), Text: "Hello {{.name}}"}
+		if err := e.Validate(draft); err != nil {
+			t.Fatalf("Validate: %v", err)
+		}
+		if _, err := e.RenderTemplate(draft, "en", map[string]interface{}{"name": "Ada"}); err != nil {
+			t.Fatalf("RenderTemplate: %v", err)
+		}
+	}
+
+	if after := e.cachedTemplates(); after != before {
+		t.Errorf("cache grew from %d to %d templates", before, after)
+	}
+}
+
+func TestEngineCacheIsBounded(t *testing.T) {
+	e, err := NewEngine(versionedSource{})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	data := map[string]interface{}{"name": "Ada"}
+	render := func(version int) {
+		t.Helper()
+		out, err := e.Render(context.Background(), "receipt", "en", &version, data)
+		if err != nil {
+			t.Fatalf("Render v%d: %v", version, err)
+		}
+		if want := fmt.Sprintf("Receipt v%d", version); out.Subject != want {
+			t.Fatalf("subject = %q, want %q", out.Subject, want)
+		}
+	}
+
+	for v := 1; v <= 2*maxCachedTemplates; v++ {
+		render(v)
+		// Version 1 stays cached while it keeps being used
+		render(1)
+	}
+
+	if n := e.cachedTemplates(); n != maxCachedTemplates {
+		t.Errorf("cached %d templates, want %d", n, maxCachedTemplates)
+	}
+	first, _ := versionedSource{}.Get(context.Background(), "receipt", "en", intPtr(1))
+	if _, ok := e.cache[first.cacheKey()]; !ok {
+		t.Error("the most recently used template was evicted")
+	}
+	evicted, _ := versionedSource{}.Get(context.Background(), "receipt", "en", intPtr(2))
+	if _, ok := e.cache[evicted.cacheKey()]; ok {
+		t.Error("the least recently used template is still cached")
+	}
+}
+
+func intPtr(v int) *int { return &v }
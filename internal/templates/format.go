@@ -0,0 +1,195 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numberFormat describes how a locale writes numbers and dates
+type numberFormat struct {
+	decimal string
+	group   string
+	months  [12]string
+	// date and dateTime are fmt patterns taking day, month name, year (and hour, minute)
+	date     string
+	dateTime string
+	// currency is a fmt pattern taking the formatted amount and the currency symbol
+	currency string
+}
+
+var englishMonths = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+var localeFormats = map[string]numberFormat{
+	"en": {
+		decimal: ".", group: ",", months: englishMonths,
+		date: "%[1]d %[2]s %[3]d", dateTime: "%[1]d %[2]s %[3]d, %02[4]d:%02[5]d",
+		currency: "%[2]s%[1]s",
+	},
+	"en-US": {
+		decimal: ".", group: ",", months: englishMonths,
+		date: "%[2]s %[1]d, %[3]d", dateTime: "%[2]s %[1]d, %[3]d %02[4]d:%02[5]d",
+		currency: "%[2]s%[1]s",
+	},
+	"fr": {
+		// French groups digits with a narrow no-break space
+		decimal: ",", group: " ",
+		months: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		date:   "%[1]d %[2]s %[3]d", dateTime: "%[1]d %[2]s %[3]d à %02[4]d:%02[5]d",
+		currency: "%[1]s\u00a0%[2]s",
+	},
+	"pt": {
+		decimal: ",", group: ".",
+		months: [12]string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		date:   "%[1]d de %[2]s de %[3]d", dateTime: "%[1]d de %[2]s de %[3]d, %02[4]d:%02[5]d",
+		currency: "%[2]s\u00a0%[1]s",
+	},
+	"es": {
+		decimal: ",", group: ".",
+		months: [12]string{"ene.", "feb.", "mar.", "abr.", "may.", "jun.", "jul.", "ago.", "sept.", "oct.", "nov.", "dic."},
+		date:   "%[1]d de %[2]s de %[3]d", dateTime: "%[1]d de %[2]s de %[3]d, %02[4]d:%02[5]d",
+		currency: "%[1]s\u00a0%[2]s",
+	},
+}
+
+// formatFor returns the most specific number format for a locale
+func formatFor(locale string) numberFormat {
+	for _, l := range LocaleChain(locale) {
+		if f, ok := localeFormats[l]; ok {
+			return f
+		}
+	}
+	return localeFormats[DefaultLocale]
+}
+
+// localeFuncs are the template helpers whose output depends on the recipient's locale
+func localeFuncs(locale string) map[string]interface{} {
+	f := formatFor(locale)
+	return map[string]interface{}{
+		"number": func(v interface{}) (string, error) {
+			n, err := toFloat(v)
+			if err != nil {
+				return "", err
+			}
+			if n == math.Trunc(n) {
+				return f.formatDecimal(n, 0), nil
+			}
+			return f.formatDecimal(n, 2), nil
+		},
+		"decimal": func(v interface{}, places int) (string, error) {
+			n, err := toFloat(v)
+			if err != nil {
+				return "", err
+			}
+			return f.formatDecimal(n, places), nil
+		},
+		"date": func(v interface{}) (string, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf(f.date, t.Day(), f.months[t.Month()-1], t.Year()), nil
+		},
+		"datetime": func(v interface{}) (string, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf(f.dateTime, t.Day(), f.months[t.Month()-1], t.Year(), t.Hour(), t.Minute()), nil
+		},
+		"money": func(v interface{}, code string) (string, error) {
+			minor, err := toMinorUnits(v)
+			if err != nil {
+				return "", err
+			}
+			return FormatMoney(minor, code, locale)
+		},
+		"locale": func() string {
+			return locale
+		},
+	}
+}
+
+// formatDecimal renders n with the given number of decimal places and locale separators
+func (f numberFormat) formatDecimal(n float64, places int) string {
+	s := strconv.FormatFloat(math.Abs(n), 'f', places, 64)
+	intPart, fracPart, _ := strings.Cut(s, ".")
+
+	out := groupDigits(intPart, f.group, standardGrouping...)
+	if fracPart != "" {
+		out += f.decimal + fracPart
+	}
+	if n < 0 && strings.Trim(s, "0.") != "" {
+		out = "-" + out
+	}
+	return out
+}
+
+// groupDigits inserts sep between digit groups of an unsigned integer string.
+// sizes are taken from the right and the last one repeats, so {3} gives
+// 1,234,567 and {3, 2} gives 12,34,567.
+func groupDigits(digits, sep string, sizes ...int) string {
+	var groups []string
+	for i := 0; len(digits) > 0; i++ {
+		size := sizes[len(sizes)-1]
+		if i < len(sizes) {
+			size = sizes[i]
+		}
+		if size <= 0 || size >= len(digits) {
+			groups = append(groups, digits)
+			break
+		}
+		groups = append(groups, digits[len(digits)-size:])
+		digits = digits[:len(digits)-size]
+	}
+
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+	return strings.Join(groups, sep)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	default:
+		return 0, fmt.Errorf("cannot format %T as a number", v)
+	}
+}
+
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, fmt.Errorf("cannot format nil time")
+		}
+		return *t, nil
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as a date", t)
+	default:
+		return time.Time{}, fmt.Errorf("cannot format %T as a date", v)
+	}
+}
@@ -0,0 +1,30 @@
+package templates
+
+import (
+	"strings"
+)
+
+// baseFuncs are the helper functions available to every template. Locale-aware
+// helpers are registered with default-locale implementations so templates parse;
+// they are rebound to the recipient's locale at render time.
+func baseFuncs() map[string]interface{} {
+	funcs := map[string]interface{}{
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"spell": spell,
+	}
+	for name, fn := range localeFuncs(DefaultLocale) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// spell separates the characters of a code so text-to-speech reads them out one
+// by one rather than as a number
+func spell(s string) string {
+	chars := make([]string, 0, len(s))
+	for _, r := range s {
+		chars = append(chars, string(r))
+	}
+	return strings.Join(chars, ", ")
+}
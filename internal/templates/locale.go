@@ -0,0 +1,50 @@
+package templates
+
+import (
+	"strings"
+)
+
+// DefaultLocale terminates every locale fallback chain
+const DefaultLocale = "en"
+
+// NormalizeLocale canonicalises a BCP 47-style tag, e.g. "fr_ci" becomes "fr-CI"
+func NormalizeLocale(locale string) string {
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "_", "-"))
+	if locale == "" {
+		return ""
+	}
+
+	parts := strings.Split(locale, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		} else {
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// LocaleChain returns the lookup order for a locale, most specific first and
+// always ending in DefaultLocale, e.g. fr-CI → fr → en
+func LocaleChain(locale string) []string {
+	locale = NormalizeLocale(locale)
+
+	var chain []string
+	seen := map[string]bool{}
+	add := func(l string) {
+		if l != "" && !seen[l] {
+			seen[l] = true
+			chain = append(chain, l)
+		}
+	}
+
+	parts := strings.Split(locale, "-")
+	for i := len(parts); i > 0; i-- {
+		add(strings.Join(parts[:i], "-"))
+	}
+	add(DefaultLocale)
+
+	return chain
+}
@@ -0,0 +1,147 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"  ", ""},
+		{"en", "en"},
+		{"FR", "fr"},
+		{"fr_ci", "fr-CI"},
+		{"pt-br", "pt-BR"},
+		{" zh-hant-tw ", "zh-hant-TW"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeLocale(tt.in); got != tt.want {
+			t.Errorf("NormalizeLocale(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleChain(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{"en"}},
+		{"en", []string{"en"}},
+		{"en-GB", []string{"en-GB", "en"}},
+		{"fr_ci", []string{"fr-CI", "fr", "en"}},
+		{"zh-Hant-TW", []string{"zh-hant-TW", "zh-hant", "zh", "en"}},
+	}
+	for _, tt := range tests {
+		if got := LocaleChain(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LocaleChain(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// localeSource serves templates by name and exact locale
+type localeSource map[string]map[string]*Template
+
+func (s localeSource) Get(_ context.Context, name, locale string, _ *int) (*Template, error) {
+	if t, ok := s[name][locale]; ok {
+		return t, nil
+	}
+	return nil, ErrTemplateNotFound
+}
+
+func TestResolveLocaleFallback(t *testing.T) {
+	variant := func(locale, subject string) *Template {
+		return &Template{Name: "payout", Locale: locale, Subject: subject, Text: subject}
+	}
+	e, err := NewEngine(localeSource{
+		"payout": {
+			"en":    variant("en", "Payout sent"),
+			"fr":    variant("fr", "Versement envoyé"),
+			"fr-CI": variant("fr-CI", "Versement envoyé (CI)"),
+		},
+		"fr_only": {"fr": {Name: "fr_only", Locale: "fr", Text: "Bonjour"}},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		template   string
+		locale     string
+		wantLocale string
+		wantErr    error
+	}{
+		{name: "exact variant", template: "payout", locale: "fr-CI", wantLocale: "fr-CI"},
+		{name: "region falls back to language", template: "payout", locale: "fr-SN", wantLocale: "fr"},
+		{name: "unnormalised tag", template: "payout", locale: "FR_ci", wantLocale: "fr-CI"},
+		{name: "unknown language falls back to default", template: "payout", locale: "sw-KE", wantLocale: "en"},
+		{name: "no locale uses default", template: "payout", locale: "", wantLocale: "en"},
+		{name: "no variant in the chain", template: "fr_only", locale: "pt-BR", wantErr: ErrTemplateNotFound},
+		{name: "built-in variant", template: TransactionNotification, locale: "fr-CI", wantLocale: "fr"},
+		{name: "built-in default", template: TransactionNotification, locale: "de", wantLocale: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Resolve(context.Background(), tt.template, tt.locale, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if got.Locale != tt.wantLocale {
+				t.Errorf("locale = %q, want %q", got.Locale, tt.wantLocale)
+			}
+		})
+	}
+}
+
+func TestRenderFormatsForLocale(t *testing.T) {
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	tmpl := &Template{
+		Name: "formats",
+		Text: "{{number .count}}|{{number .ratio}}|{{decimal .ratio 1}}|{{date .at}}|{{datetime .at}}|{{locale}}",
+	}
+	data := map[string]interface{}{
+		"count": 1234567,
+		"ratio": -1234.5,
+		"at":    time.Date(2026, time.February, 3, 14, 5, 0, 0, time.UTC),
+	}
+
+	// French groups digits with a narrow no-break space, U+202F
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", "1,234,567|-1,234.50|-1,234.5|3 Feb 2026|3 Feb 2026, 14:05|en"},
+		{"en-US", "1,234,567|-1,234.50|-1,234.5|Feb 3, 2026|Feb 3, 2026 14:05|en-US"},
+		{"en-NG", "1,234,567|-1,234.50|-1,234.5|3 Feb 2026|3 Feb 2026, 14:05|en-NG"},
+		{"fr-CI", "1\u202f234\u202f567|-1\u202f234,50|-1\u202f234,5|3 févr. 2026|3 févr. 2026 à 14:05|fr-CI"},
+		{"pt-BR", "1.234.567|-1.234,50|-1.234,5|3 de fev. de 2026|3 de fev. de 2026, 14:05|pt-BR"},
+		{"sw", "1,234,567|-1,234.50|-1,234.5|3 Feb 2026|3 Feb 2026, 14:05|sw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got, err := e.RenderTemplate(tmpl, tt.locale, data)
+			if err != nil {
+				t.Fatalf("RenderTemplate: %v", err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("text = %q\nwant   %q", got.Text, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency describes how an ISO 4217 currency's minor units are written
+type Currency struct {
+	Code string
+	// Exponent is the number of minor-unit digits, e.g. 2 for NGN kobo, 0 for XOF, 3 for KWD
+	Exponent int
+	Symbol   string
+	// Grouping lists digit group sizes from the right; the last size repeats (e.g. 3,2 for INR lakh/crore)
+	Grouping []int
+}
+
+var standardGrouping = []int{3}
+
+// currencies is the ISO 4217 table for the currencies KodraPay settles or displays
+var currencies = map[string]Currency{
+	// Africa
+	"NGN": {Code: "NGN", Exponent: 2, Symbol: "₦"},
+	"GHS": {Code: "GHS", Exponent: 2, Symbol: "GH₵"},
+	"KES": {Code: "KES", Exponent: 2, Symbol: "KSh"},
+	"UGX": {Code: "UGX", Exponent: 0, Symbol: "USh"},
+	"TZS": {Code: "TZS", Exponent: 2, Symbol: "TSh"},
+	"RWF": {Code: "RWF", Exponent: 0, Symbol: "RF"},
+	"ZAR": {Code: "ZAR", Exponent: 2, Symbol: "R"},
+	"EGP": {Code: "EGP", Exponent: 2, Symbol: "E£"},
+	"MAD": {Code: "MAD", Exponent: 2, Symbol: "MAD"},
+	"TND": {Code: "TND", Exponent: 3, Symbol: "DT"},
+	"LYD": {Code: "LYD", Exponent: 3, Symbol: "LD"},
+	"XOF": {Code: "XOF", Exponent: 0, Symbol: "F CFA"},
+	"XAF": {Code: "XAF", Exponent: 0, Symbol: "FCFA"},
+	"GNF": {Code: "GNF", Exponent: 0, Symbol: "FG"},
+	"ZMW": {Code: "ZMW", Exponent: 2, Symbol: "ZK"},
+	// Middle East
+	"AED": {Code: "AED", Exponent: 2, Symbol: "AED"},
+	"SAR": {Code: "SAR", Exponent: 2, Symbol: "SAR"},
+	"BHD": {Code: "BHD", Exponent: 3, Symbol: "BD"},
+	"IQD": {Code: "IQD", Exponent: 3, Symbol: "IQD"},
+	"JOD": {Code: "JOD", Exponent: 3, Symbol: "JD"},
+	"KWD": {Code: "KWD", Exponent: 3, Symbol: "KD"},
+	"OMR": {Code: "OMR", Exponent: 3, Symbol: "OMR"},
+	// Europe and the Americas
+	"EUR": {Code: "EUR", Exponent: 2, Symbol: "€"},
+	"GBP": {Code: "GBP", Exponent: 2, Symbol: "£"},
+	"CHF": {Code: "CHF", Exponent: 2, Symbol: "CHF"},
+	"ISK": {Code: "ISK", Exponent: 0, Symbol: "kr"},
+	"USD": {Code: "USD", Exponent: 2, Symbol: "$"},
+	"CAD": {Code: "CAD", Exponent: 2, Symbol: "CA$"},
+	"BRL": {Code: "BRL", Exponent: 2, Symbol: "R$"},
+	"CLP": {Code: "CLP", Exponent: 0, Symbol: "CLP$"},
+	// Asia-Pacific
+	"INR": {Code: "INR", Exponent: 2, Symbol: "₹", Grouping: []int{3, 2}},
+	"CNY": {Code: "CNY", Exponent: 2, Symbol: "CN¥"},
+	"JPY": {Code: "JPY", Exponent: 0, Symbol: "¥"},
+	"KRW": {Code: "KRW", Exponent: 0, Symbol: "₩"},
+	"VND": {Code: "VND", Exponent: 0, Symbol: "₫"},
+	"AUD": {Code: "AUD", Exponent: 2, Symbol: "A$"},
+}
+
+// LookupCurrency returns the ISO 4217 definition for a currency code
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return Currency{}, false
+	}
+	if c.Grouping == nil {
+		c.Grouping = standardGrouping
+	}
+	return c, true
+}
+
+// FormatMoney renders an amount in minor units (kobo, cents, ...) for a locale,
+// e.g. 123450 NGN is "₦1,234.50" in en and "1 234,50 ₦" in fr
+func FormatMoney(minor int64, code, locale string) (string, error) {
+	c, ok := LookupCurrency(code)
+	if !ok {
+		return "", fmt.Errorf("unknown currency %q", code)
+	}
+	return formatFor(locale).formatMoney(minor, c), nil
+}
+
+func (f numberFormat) formatMoney(minor int64, c Currency) string {
+	// Work on the digit string so large amounts never pass through a float
+	digits := strconv.FormatUint(absInt64(minor), 10)
+	if len(digits) <= c.Exponent {
+		digits = strings.Repeat("0", c.Exponent-len(digits)+1) + digits
+	}
+
+	split := len(digits) - c.Exponent
+	amount := groupDigits(digits[:split], f.group, c.Grouping...)
+	if c.Exponent > 0 {
+		amount += f.decimal + digits[split:]
+	}
+
+	out := fmt.Sprintf(f.currency, amount, c.Symbol)
+	if minor < 0 {
+		out = "-" + out
+	}
+	return out
+}
+
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// toMinorUnits accepts the integer types builders pass and the float64/json.Number
+// values decoded from API requests, rejecting fractional minor units
+func toMinorUnits(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	case float64:
+		// From 2^53 on, float64 no longer holds every integer, so the amount may
+		// already have been rounded when it was decoded
+		if n != math.Trunc(n) || math.Abs(n) >= 1<<53 {
+			return 0, fmt.Errorf("amount %v is not an exact whole number of minor units", n)
+		}
+		return int64(n), nil
+	default:
+		return 0, fmt.Errorf("cannot format %T as money", v)
+	}
+}
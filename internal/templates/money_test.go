@@ -0,0 +1,107 @@
+package templates
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFormatMoney(t *testing.T) {
+	// fr groups digits with a narrow no-break space, U+202F, and puts the symbol
+	// after a no-break space, U+00A0
+	tests := []struct {
+		name   string
+		minor  int64
+		code   string
+		locale string
+		want   string
+	}{
+		{name: "two-digit exponent", minor: 123450, code: "NGN", locale: "en", want: "₦1,234.50"},
+		{name: "zero-digit exponent", minor: 1500000, code: "XOF", locale: "en", want: "F CFA1,500,000"},
+		{name: "three-digit exponent", minor: 1234567, code: "KWD", locale: "en", want: "KD1,234.567"},
+		{name: "less than one major unit", minor: 5, code: "NGN", locale: "en", want: "₦0.05"},
+		{name: "less than one major unit, three digits", minor: 7, code: "KWD", locale: "en", want: "KD0.007"},
+		{name: "zero", minor: 0, code: "NGN", locale: "en", want: "₦0.00"},
+		{name: "negative", minor: -123450, code: "NGN", locale: "en", want: "-₦1,234.50"},
+		{name: "negative less than one major unit", minor: -5, code: "NGN", locale: "en", want: "-₦0.05"},
+		{name: "smallest int64", minor: math.MinInt64, code: "NGN", locale: "en", want: "-₦92,233,720,368,547,758.08"},
+		{name: "largest int64", minor: math.MaxInt64, code: "XOF", locale: "en", want: "F CFA9,223,372,036,854,775,807"},
+		{name: "lakh grouping", minor: 1234567801, code: "INR", locale: "en", want: "₹1,23,45,678.01"},
+		{name: "lakh grouping below a lakh", minor: 9999900, code: "INR", locale: "en", want: "₹99,999.00"},
+		{name: "french separators", minor: 123450, code: "NGN", locale: "fr", want: "1\u202f234,50\u00a0₦"},
+		{name: "french zero exponent", minor: 1500000, code: "XOF", locale: "fr-CI", want: "1\u202f500\u202f000\u00a0F CFA"},
+		{name: "french negative", minor: -1234567, code: "KWD", locale: "fr", want: "-1\u202f234,567\u00a0KD"},
+		{name: "portuguese", minor: 123450, code: "BRL", locale: "pt-BR", want: "R$\u00a01.234,50"},
+		{name: "lowercase code", minor: 100, code: " usd ", locale: "en-US", want: "$1.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatMoney(tt.minor, tt.code, tt.locale)
+			if err != nil {
+				t.Fatalf("FormatMoney: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FormatMoney(%d, %s, %s) = %q, want %q", tt.minor, tt.code, tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatMoneyUnknownCurrency(t *testing.T) {
+	if _, err := FormatMoney(100, "XYZ", "en"); err == nil {
+		t.Error("formatted an unknown currency")
+	}
+}
+
+func TestMoneyFromJSON(t *testing.T) {
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	tmpl := &Template{Name: "amount", Text: "{{money .amount .currency}}"}
+
+	tests := []struct {
+		name    string
+		body    string
+		useNum  bool
+		want    string
+		wantErr string
+	}{
+		{name: "float64", body: `{"amount": 123450, "currency": "NGN"}`, want: "₦1,234.50"},
+		{name: "float64 in exponent form", body: `{"amount": 1.5e6, "currency": "XOF"}`, want: "F CFA1,500,000"},
+		{name: "negative float64", body: `{"amount": -250, "currency": "KWD"}`, want: "-KD0.250"},
+		{name: "json.Number", body: `{"amount": 9007199254740993, "currency": "NGN"}`, useNum: true, want: "₦90,071,992,547,409.93"},
+		{name: "string", body: `{"amount": "123450", "currency": "NGN"}`, want: "₦1,234.50"},
+		{name: "fractional minor units", body: `{"amount": 1234.5, "currency": "NGN"}`, wantErr: "whole number"},
+		{name: "beyond float64 precision", body: `{"amount": 9007199254740993, "currency": "NGN"}`, wantErr: "whole number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]interface{}
+			dec := json.NewDecoder(strings.NewReader(tt.body))
+			if tt.useNum {
+				dec.UseNumber()
+			}
+			if err := dec.Decode(&data); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			got, err := e.RenderTemplate(tmpl, "en", data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplate: %v", err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("text = %q, want %q", got.Text, tt.want)
+			}
+		})
+	}
+}
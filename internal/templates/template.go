@@ -0,0 +1,159 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template is the source of a named notification template
+type Template struct {
+	Name   string
+	Locale string
+	// Version is the managed template version, or 0 for built-in templates
+	Version  int
+	Subject  string
+	Text     string
+	HTML     string
+	Required []string
+}
+
+// cacheKey identifies the template content so parsed forms can be reused
+func (t *Template) cacheKey() string {
+	h := sha256.New()
+	for _, part := range []string{t.Name, t.Locale, strconv.Itoa(t.Version), t.Subject, t.Text, t.HTML, strings.Join(t.Required, ",")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Rendered is the output of a template for one set of data
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+	// Version is the template version that produced the output, or 0 for built-in templates
+	Version int
+	// Locale is the template variant that was used after locale fallback
+	Locale string
+}
+
+// MissingVariablesError reports template variables that were not supplied
+type MissingVariablesError struct {
+	Template string
+	Missing  []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return fmt.Sprintf("template %s is missing required variables: %s", e.Template, strings.Join(e.Missing, ", "))
+}
+
+// compiled holds the parsed forms of a template. They are never executed
+// directly; each render clones them and binds the recipient's locale helpers.
+type compiled struct {
+	// key is the template's cacheKey while it is in an Engine's cache
+	key     string
+	source  *Template
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+func compile(t *Template, funcs map[string]interface{}) (*compiled, error) {
+	if t.Subject == "" && t.Text == "" {
+		return nil, fmt.Errorf("template %s has no subject or text body", t.Name)
+	}
+
+	c := &compiled{source: t}
+	var err error
+
+	c.subject, err = texttemplate.New(t.Name + ".subject").Funcs(funcs).Option("missingkey=error").Parse(t.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("parse subject of template %s: %w", t.Name, err)
+	}
+
+	c.text, err = texttemplate.New(t.Name + ".text").Funcs(funcs).Option("missingkey=error").Parse(t.Text)
+	if err != nil {
+		return nil, fmt.Errorf("parse text body of template %s: %w", t.Name, err)
+	}
+
+	if t.HTML != "" {
+		c.html, err = htmltemplate.New(t.Name + ".html").Funcs(funcs).Option("missingkey=error").Parse(t.HTML)
+		if err != nil {
+			return nil, fmt.Errorf("parse html body of template %s: %w", t.Name, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *compiled) render(data map[string]interface{}, locale string) (*Rendered, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	var missing []string
+	for _, key := range c.source.Required {
+		if v, ok := data[key]; !ok || v == nil || v == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, &MissingVariablesError{Template: c.source.Name, Missing: missing}
+	}
+
+	funcs := localeFuncs(locale)
+	out := Rendered{Version: c.source.Version, Locale: c.source.Locale}
+	var buf bytes.Buffer
+
+	subject, err := c.subject.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("clone subject of template %s: %w", c.source.Name, err)
+	}
+	if err := subject.Funcs(funcs).Execute(&buf, data); err != nil {
+		return nil, c.executeError("subject", err)
+	}
+	out.Subject = strings.TrimSpace(buf.String())
+
+	text, err := c.text.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("clone text body of template %s: %w", c.source.Name, err)
+	}
+	buf.Reset()
+	if err := text.Funcs(funcs).Execute(&buf, data); err != nil {
+		return nil, c.executeError("text body", err)
+	}
+	out.Text = buf.String()
+
+	if c.html != nil {
+		html, err := c.html.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("clone html body of template %s: %w", c.source.Name, err)
+		}
+		buf.Reset()
+		if err := html.Funcs(funcs).Execute(&buf, data); err != nil {
+			return nil, c.executeError("html body", err)
+		}
+		out.HTML = buf.String()
+	}
+
+	return &out, nil
+}
+
+// executeError turns missingkey failures for undeclared variables into a MissingVariablesError
+func (c *compiled) executeError(part string, err error) error {
+	msg := err.Error()
+	if i := strings.Index(msg, "map has no entry for key "); i >= 0 {
+		key := strings.Trim(msg[i+len("map has no entry for key "):], `"`)
+		return &MissingVariablesError{Template: c.source.Name, Missing: []string{key}}
+	}
+	return fmt.Errorf("render %s of template %s: %w", part, c.source.Name, err)
+}
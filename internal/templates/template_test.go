@@ -0,0 +1,159 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mapSource serves templates by name for every locale and version
+type mapSource map[string]*Template
+
+func (s mapSource) Get(_ context.Context, name, _ string, _ *int) (*Template, error) {
+	if t, ok := s[name]; ok {
+		return t, nil
+	}
+	return nil, ErrTemplateNotFound
+}
+
+func TestRenderNamedTemplate(t *testing.T) {
+	e, err := NewEngine(mapSource{
+		"welcome": {
+			Name:     "welcome",
+			Subject:  "Welcome, {{.name}}",
+			Text:     "Hi {{.name}}, your store {{upper .store}} is live.",
+			HTML:     `<p>Hi {{.name}}, your store <a href="{{.url}}">{{.store}}</a> is live.</p>`,
+			Required: []string{"name", "store"},
+		},
+		"text_only": {Name: "text_only", Text: "Code: {{.code}}"},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		data     map[string]interface{}
+		want     Rendered
+	}{
+		{
+			name:     "text and html",
+			template: "welcome",
+			data:     map[string]interface{}{"name": "Ada", "store": "kiosk", "url": "https://kiosk.example"},
+			want: Rendered{
+				Subject: "Welcome, Ada",
+				Text:    "Hi Ada, your store KIOSK is live.",
+				HTML:    `<p>Hi Ada, your store <a href="https://kiosk.example">kiosk</a> is live.</p>`,
+			},
+		},
+		{
+			name:     "text only",
+			template: "text_only",
+			data:     map[string]interface{}{"code": "482913"},
+			want:     Rendered{Text: "Code: 482913"},
+		},
+		{
+			name:     "html is escaped, text is not",
+			template: "welcome",
+			data: map[string]interface{}{
+				"name":  `<script>alert("x")</script>`,
+				"store": "Tom & Jerry's",
+				"url":   `javascript:alert(1)`,
+			},
+			want: Rendered{
+				Subject: `Welcome, <script>alert("x")</script>`,
+				Text:    `Hi <script>alert("x")</script>, your store TOM & JERRY'S is live.`,
+				HTML:    `<p>Hi &lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;, your store <a href="#ZgotmplZ">Tom &amp; Jerry&#39;s</a> is live.</p>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Render(context.Background(), tt.template, "", nil, tt.data)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("rendered = %+v\nwant       %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMissingVariables(t *testing.T) {
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	tmpl := &Template{
+		Name:     "receipt",
+		Subject:  "Receipt for {{.order}}",
+		Text:     "Paid by {{.payer}}",
+		HTML:     "<p>Thanks {{.customer}}</p>",
+		Required: []string{"order", "total"},
+	}
+
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want []string
+	}{
+		{name: "required variables", data: nil, want: []string{"order", "total"}},
+		{name: "empty required variable", data: map[string]interface{}{"order": "", "total": 5}, want: []string{"order"}},
+		{name: "undeclared text variable", data: map[string]interface{}{"order": "A1", "total": 5}, want: []string{"payer"}},
+		{name: "undeclared html variable", data: map[string]interface{}{"order": "A1", "total": 5, "payer": "Ada"}, want: []string{"customer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.RenderTemplate(tmpl, "en", tt.data)
+			var missingErr *MissingVariablesError
+			if !errors.As(err, &missingErr) {
+				t.Fatalf("err = %v, want a MissingVariablesError", err)
+			}
+			if missingErr.Template != "receipt" || !reflect.DeepEqual(missingErr.Missing, tt.want) {
+				t.Errorf("missing = %s %v, want receipt %v", missingErr.Template, missingErr.Missing, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateParseErrors(t *testing.T) {
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tmpl *Template
+		want string
+	}{
+		{name: "empty", tmpl: &Template{Name: "empty"}, want: "no subject or text body"},
+		{name: "subject", tmpl: &Template{Name: "bad", Subject: "{{.name"}, want: "parse subject"},
+		{name: "text", tmpl: &Template{Name: "bad", Text: "{{if .x}}"}, want: "parse text body"},
+		{name: "html", tmpl: &Template{Name: "bad", Text: "ok", HTML: "{{nosuchfunc .x}}"}, want: "parse html body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := e.Validate(tt.tmpl); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	if _, err := e.Render(context.Background(), "nope", "en", nil, nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("err = %v, want ErrTemplateNotFound", err)
+	}
+}
@@ -0,0 +1,18 @@
+-- Push notification device registry
+CREATE TABLE IF NOT EXISTS notification_devices (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      VARCHAR(255) NOT NULL,
+    merchant_id  VARCHAR(255),
+    device_id    VARCHAR(255) NOT NULL,
+    platform     VARCHAR(16)  NOT NULL CHECK (platform IN ('fcm', 'apns', 'web')),
+    token        TEXT         NOT NULL,
+    app_version  VARCHAR(64),
+    active       BOOLEAN      NOT NULL DEFAULT TRUE,
+    last_seen_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, device_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_devices_token ON notification_devices (token);
+CREATE INDEX IF NOT EXISTS idx_notification_devices_user_active ON notification_devices (user_id) WHERE active;
@@ -0,0 +1,6 @@
+-- Asynchronous dispatch: workers claim pending notifications with FOR UPDATE SKIP LOCKED
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_dispatch
+    ON notifications (status, created_at)
+    WHERE status IN ('pending', 'processing');
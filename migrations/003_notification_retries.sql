@@ -0,0 +1,12 @@
+-- Retry scheduling and dead-lettering for notification delivery
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_notifications_dispatch;
+CREATE INDEX IF NOT EXISTS idx_notifications_dispatch
+    ON notifications (status, next_attempt_at)
+    WHERE status IN ('pending', 'processing');
+
+CREATE INDEX IF NOT EXISTS idx_notifications_dead_lettered
+    ON notifications (dead_lettered_at DESC)
+    WHERE status = 'dead_lettered';
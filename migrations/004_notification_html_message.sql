@@ -0,0 +1,2 @@
+-- Rendered HTML body for templated email notifications
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS html_message TEXT;
@@ -0,0 +1,40 @@
+-- Managed notification templates with immutable published versions
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name                VARCHAR(100) NOT NULL UNIQUE,
+    description         TEXT,
+    draft_subject       TEXT   NOT NULL DEFAULT '',
+    draft_text          TEXT   NOT NULL DEFAULT '',
+    draft_html          TEXT   NOT NULL DEFAULT '',
+    draft_required_vars TEXT[] NOT NULL DEFAULT '{}',
+    published_version   INTEGER,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS notification_template_versions (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    template_id   UUID    NOT NULL REFERENCES notification_templates (id) ON DELETE CASCADE,
+    version       INTEGER NOT NULL,
+    subject       TEXT    NOT NULL,
+    text_body     TEXT    NOT NULL,
+    html_body     TEXT    NOT NULL DEFAULT '',
+    required_vars TEXT[]  NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (template_id, version)
+);
+
+-- Versions are immutable once created
+CREATE OR REPLACE FUNCTION reject_template_version_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'notification template versions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notification_template_versions_immutable ON notification_template_versions;
+CREATE TRIGGER notification_template_versions_immutable
+    BEFORE UPDATE ON notification_template_versions
+    FOR EACH ROW EXECUTE FUNCTION reject_template_version_update();
+
+-- Which template version produced each notification
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS template_version INTEGER;
@@ -0,0 +1,9 @@
+-- Per-locale template variants and recipient locale preferences
+ALTER TABLE notification_templates ADD COLUMN IF NOT EXISTS locale VARCHAR(16) NOT NULL DEFAULT 'en';
+ALTER TABLE notification_templates DROP CONSTRAINT IF EXISTS notification_templates_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_templates_name_locale ON notification_templates (name, locale);
+
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS locale VARCHAR(16);
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS locale VARCHAR(16);
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS locale VARCHAR(16);
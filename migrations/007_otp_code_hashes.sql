@@ -0,0 +1,5 @@
+-- OTP codes are stored as HMAC-SHA256 digests keyed by a server-side pepper.
+-- key_id names the pepper used; rows written before this migration keep a NULL
+-- key_id and their plaintext code until they expire and are cleaned up.
+ALTER TABLE otps ALTER COLUMN code TYPE VARCHAR(128);
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
@@ -0,0 +1,4 @@
+-- OTPs are verified by ID or reference rather than looked up by code, and are
+-- locked once their attempts are exhausted
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS locked_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_otps_merchant_purpose_reference ON otps (merchant_id, purpose, reference_id, created_at DESC);
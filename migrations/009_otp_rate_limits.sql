@@ -0,0 +1,17 @@
+-- Fixed-window counters for OTP issuance, shared by all replicas
+CREATE TABLE IF NOT EXISTS otp_rate_limits (
+    bucket       VARCHAR(512) NOT NULL,
+    window_start TIMESTAMPTZ  NOT NULL,
+    count        INTEGER      NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_otp_rate_limits_window_start ON otp_rate_limits (window_start);
+
+-- Failed verification tracking and temporary lockouts per merchant recipient
+CREATE TABLE IF NOT EXISTS otp_lockouts (
+    subject      VARCHAR(512) PRIMARY KEY,
+    failures     INTEGER      NOT NULL DEFAULT 0,
+    window_start TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ
+);
@@ -0,0 +1,26 @@
+-- Authenticator-app (RFC 6238 TOTP) enrollments, one per merchant user.
+-- The shared secret is AES-GCM encrypted; last_used_step rejects replayed codes.
+CREATE TABLE IF NOT EXISTS totp_enrollments (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id    VARCHAR(255) NOT NULL,
+    user_id        VARCHAR(255) NOT NULL,
+    account_name   VARCHAR(255) NOT NULL,
+    secret         BYTEA        NOT NULL,
+    key_id         VARCHAR(64)  NOT NULL,
+    last_used_step BIGINT       NOT NULL DEFAULT 0,
+    confirmed_at   TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    UNIQUE (merchant_id, user_id)
+);
+
+-- Single-use recovery codes, stored as peppered HMAC digests
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    enrollment_id UUID         NOT NULL REFERENCES totp_enrollments (id) ON DELETE CASCADE,
+    code_hash     VARCHAR(128) NOT NULL,
+    used_at       TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_totp_recovery_codes_unused ON totp_recovery_codes (enrollment_id, code_hash) WHERE used_at IS NULL;
@@ -0,0 +1,12 @@
+-- Latest run of each scheduled housekeeping job, shared by all replicas. Jobs are
+-- serialised with advisory locks; started_at decides when a job is next due.
+CREATE TABLE IF NOT EXISTS scheduled_job_runs (
+    name              VARCHAR(100) PRIMARY KEY,
+    status            VARCHAR(16)  NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    affected          BIGINT       NOT NULL DEFAULT 0,
+    error             TEXT,
+    runner            VARCHAR(255) NOT NULL,
+    started_at        TIMESTAMPTZ  NOT NULL,
+    finished_at       TIMESTAMPTZ  NOT NULL,
+    last_succeeded_at TIMESTAMPTZ
+);
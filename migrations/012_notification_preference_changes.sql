@@ -0,0 +1,13 @@
+-- Audit trail of changes to merchant notification preferences
+CREATE TABLE IF NOT EXISTS notification_preference_changes (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id VARCHAR(255) NOT NULL,
+    -- Field name to {"old": ..., "new": ...}
+    changes     JSONB        NOT NULL,
+    changed_by  VARCHAR(255),
+    request_id  VARCHAR(255),
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_preference_changes_merchant
+    ON notification_preference_changes (merchant_id, created_at DESC);
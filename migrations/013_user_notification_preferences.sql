@@ -0,0 +1,23 @@
+-- Per-user overrides of the merchant's notification preferences. NULL inherits
+-- the merchant's setting.
+CREATE TABLE IF NOT EXISTS user_notification_preferences (
+    id                        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id               VARCHAR(255) NOT NULL,
+    user_id                   VARCHAR(255) NOT NULL,
+    email_enabled             BOOLEAN,
+    sms_enabled               BOOLEAN,
+    push_enabled              BOOLEAN,
+    transaction_notifications BOOLEAN,
+    payout_notifications      BOOLEAN,
+    settlement_notifications  BOOLEAN,
+    marketing_notifications   BOOLEAN,
+    email_address             VARCHAR(255),
+    phone_number              VARCHAR(32),
+    locale                    VARCHAR(35),
+    created_at                TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at                TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    UNIQUE (merchant_id, user_id)
+);
+
+-- Changes to a user's overrides are audited alongside the merchant's
+ALTER TABLE notification_preference_changes ADD COLUMN IF NOT EXISTS user_id VARCHAR(255);
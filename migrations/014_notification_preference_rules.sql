@@ -0,0 +1,83 @@
+-- Notification preferences as a (category, delivery type) matrix stored as rows.
+-- user_id is '' for the merchant's own settings and a user's ID for their
+-- overrides. Cells without a row use the defaults in code, so new categories
+-- need no schema change.
+CREATE TABLE IF NOT EXISTS notification_preference_rules (
+    merchant_id VARCHAR(255) NOT NULL,
+    user_id     VARCHAR(255) NOT NULL DEFAULT '',
+    category    VARCHAR(64)  NOT NULL,
+    type        VARCHAR(16)  NOT NULL,
+    enabled     BOOLEAN      NOT NULL,
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (merchant_id, user_id, category, type)
+);
+
+-- Copy the old boolean columns, once; they are dropped afterwards
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'notification_preferences' AND column_name = 'email_enabled'
+    ) THEN
+        -- Merchant layer: a cell was enabled when both its category and type columns were.
+        -- Security and system are now mandatory, so they are not carried over. Voice
+        -- follows the SMS setting, as it did before.
+        INSERT INTO notification_preference_rules (merchant_id, user_id, category, type, enabled)
+        SELECT p.merchant_id, '', c.category, t.type, c.enabled AND t.enabled
+        FROM notification_preferences p
+        CROSS JOIN LATERAL (VALUES
+            ('transaction', p.transaction_notifications),
+            ('payout',      p.payout_notifications),
+            ('settlement',  p.settlement_notifications),
+            ('marketing',   p.marketing_notifications)
+        ) AS c (category, enabled)
+        CROSS JOIN LATERAL (VALUES
+            ('email', p.email_enabled),
+            ('sms',   p.sms_enabled),
+            ('voice', p.sms_enabled),
+            ('push',  p.push_enabled)
+        ) AS t (type, enabled)
+        ON CONFLICT DO NOTHING;
+
+        -- User layer: only the cells a user's overrides actually affected
+        INSERT INTO notification_preference_rules (merchant_id, user_id, category, type, enabled)
+        SELECT u.merchant_id, u.user_id, c.category, t.type,
+               COALESCE(c.user_enabled, c.merchant_enabled) AND COALESCE(t.user_enabled, t.merchant_enabled)
+        FROM user_notification_preferences u
+        JOIN notification_preferences p ON p.merchant_id = u.merchant_id
+        CROSS JOIN LATERAL (VALUES
+            ('transaction', u.transaction_notifications, p.transaction_notifications),
+            ('payout',      u.payout_notifications,      p.payout_notifications),
+            ('settlement',  u.settlement_notifications,  p.settlement_notifications),
+            ('marketing',   u.marketing_notifications,   p.marketing_notifications)
+        ) AS c (category, user_enabled, merchant_enabled)
+        CROSS JOIN LATERAL (VALUES
+            ('email', u.email_enabled, p.email_enabled),
+            ('sms',   u.sms_enabled,   p.sms_enabled),
+            ('voice', u.sms_enabled,   p.sms_enabled),
+            ('push',  u.push_enabled,  p.push_enabled)
+        ) AS t (type, user_enabled, merchant_enabled)
+        WHERE c.user_enabled IS NOT NULL OR t.user_enabled IS NOT NULL
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE notification_preferences
+            DROP COLUMN IF EXISTS email_enabled,
+            DROP COLUMN IF EXISTS sms_enabled,
+            DROP COLUMN IF EXISTS push_enabled,
+            DROP COLUMN IF EXISTS transaction_notifications,
+            DROP COLUMN IF EXISTS payout_notifications,
+            DROP COLUMN IF EXISTS settlement_notifications,
+            DROP COLUMN IF EXISTS security_notifications,
+            DROP COLUMN IF EXISTS marketing_notifications;
+
+        ALTER TABLE user_notification_preferences
+            DROP COLUMN IF EXISTS email_enabled,
+            DROP COLUMN IF EXISTS sms_enabled,
+            DROP COLUMN IF EXISTS push_enabled,
+            DROP COLUMN IF EXISTS transaction_notifications,
+            DROP COLUMN IF EXISTS payout_notifications,
+            DROP COLUMN IF EXISTS settlement_notifications,
+            DROP COLUMN IF EXISTS marketing_notifications;
+    END IF;
+END
+$$;
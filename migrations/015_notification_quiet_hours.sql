@@ -0,0 +1,10 @@
+-- Quiet hours during which non-critical notifications are held back, as local
+-- HH:MM times in an IANA timezone. A window whose end is not after its start
+-- spans midnight. NULL on a user's overrides inherits the merchant's setting.
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS quiet_hours_start VARCHAR(5);
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS quiet_hours_end VARCHAR(5);
+
+ALTER TABLE user_notification_preferences ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+ALTER TABLE user_notification_preferences ADD COLUMN IF NOT EXISTS quiet_hours_start VARCHAR(5);
+ALTER TABLE user_notification_preferences ADD COLUMN IF NOT EXISTS quiet_hours_end VARCHAR(5);
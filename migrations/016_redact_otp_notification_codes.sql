@@ -0,0 +1,12 @@
+-- OTP notifications now store their code sealed and are rendered at delivery,
+-- with any magic link signed afresh rather than stored. Scrub the plaintext
+-- codes and links, and the text rendered from them, from OTP notifications
+-- written before that which are no longer waiting to be sent.
+UPDATE notifications SET
+    template_data = template_data - 'code' - 'link',
+    subject = NULL,
+    message = '',
+    html_message = NULL
+WHERE metadata ? 'otp_purpose'
+  AND (template_data ? 'code' OR template_data ? 'link')
+  AND status NOT IN ('pending', 'processing');
@@ -0,0 +1,15 @@
+-- locked_at was first added without a time zone. Convert it on databases that
+-- already ran 008; existing values were written by NOW() in the session time
+-- zone, which the conversion assumes.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM information_schema.columns
+        WHERE table_name = 'otps'
+          AND column_name = 'locked_at'
+          AND data_type = 'timestamp without time zone'
+    ) THEN
+        ALTER TABLE otps ALTER COLUMN locked_at TYPE TIMESTAMPTZ;
+    END IF;
+END $$;